@@ -1,17 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"os"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"otlp-log-parser-assignment/config"
 	"otlp-log-parser-assignment/internal/logger"
 	"otlp-log-parser-assignment/internal/server"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
@@ -27,8 +32,6 @@ func main() {
 		}
 	}()
 
-	startMetricsServer(cfg, appLogger)
-
 	srv, err := server.NewServer(cfg, appLogger)
 	if err != nil {
 		appLogger.Fatalw("Failed to create server", "error", err)
@@ -39,16 +42,33 @@ func main() {
 	}
 }
 
-func startMetricsServer(cfg *config.Config, logger *logger.Logger) {
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+// configCommand runs "config print" or "config validate" with the remaining
+// arguments as the server's flags and returns the exit code
+func configCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		fmt.Fprintln(os.Stderr, "Usage: otlp-log-parser-assignment config print|validate [flags]")
+		return 2
+	}
 
-	addr := fmt.Sprintf(":%d", cfg.MetricsPort)
-	logger.Infow("Starting Prometheus metrics server", "address", addr)
+	cfg, err := config.Load(args[1:])
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
 
-	go func() {
-		if err := http.ListenAndServe(addr, mux); err != nil {
-			logger.Errorw("Metrics server failed", "error", err)
-		}
-	}()
+	if args[0] == "validate" {
+		fmt.Println("Configuration is valid")
+		return 0
+	}
+	if cfg.File != "" {
+		fmt.Printf("Config file: %s\n\n", cfg.File)
+	}
+	if err := cfg.WriteSettings(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+		return 1
+	}
+	return 0
 }
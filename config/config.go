@@ -1,12 +1,32 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"otlp-log-parser-assignment/internal/forward"
+	"otlp-log-parser-assignment/internal/report"
+	"otlp-log-parser-assignment/internal/sketch"
+	"otlp-log-parser-assignment/internal/tracing"
 )
 
 type Config struct {
+	// File is the YAML or JSON file settings were loaded from; environment
+	// variables and flags override it and it is read again on reload (empty
+	// when there is no file)
+	File string
+
+	// args are the command line arguments the configuration was loaded from
+	args []string
+	// sources are the layers settings were loaded from, keyed by flag name
+	sources map[string]Source
+
 	GRPCPort    int
 	MetricsPort int
 
@@ -16,25 +36,484 @@ type Config struct {
 	// WindowDuration is the time window for aggregating and reporting counts
 	WindowDuration time.Duration
 
+	// MaxValuesPerWindow caps the distinct attribute values tracked within a single window;
+	// values beyond the cap are counted in an overflow bucket (0 disables the cap)
+	MaxValuesPerWindow int
+
+	// MaxMetricLabels caps the distinct attribute value labels exported to Prometheus
+	// over the lifetime of the process (0 disables the cap)
+	MaxMetricLabels int
+
+	// MetricSeriesIdleWindows removes a value's metric label series after this many
+	// consecutive windows without records (0 keeps them forever)
+	MetricSeriesIdleWindows int
+
+	// DistinctAttributeKey is an optional second attribute whose distinct values are
+	// estimated per AttributeKey value (e.g. trace_id or user.id)
+	DistinctAttributeKey string
+
+	// DistinctPrecision is the HyperLogLog precision used for distinct estimates
+	DistinctPrecision int
+
+	// HistorySize is the number of completed windows kept in memory (0 disables history)
+	HistorySize int
+
+	// HistoryRollups are the coarser resolutions completed windows are rolled up into
+	HistoryRollups Rollups
+
+	// ReportMode selects whether window reports contain deltas, cumulative totals or both
+	ReportMode string
+
+	// AnomalyThreshold is the absolute z-score at which a value's window count is
+	// reported as a spike or drop (0 disables anomaly detection)
+	AnomalyThreshold float64
+
+	// AnomalyAlpha is the EWMA smoothing factor of the per-value baselines
+	AnomalyAlpha float64
+
+	// AnomalyWarmup is the number of windows a baseline needs before values are scored
+	AnomalyWarmup int
+
+	// AnomalySeasonality compares each window with the one a season earlier in
+	// history instead of the EWMA baseline (0 disables seasonal baselines)
+	AnomalySeasonality time.Duration
+
+	// CheckpointDir is the directory counter state is checkpointed to so it
+	// survives restarts (empty disables checkpointing)
+	CheckpointDir string
+
+	// CheckpointInterval is how often counter state is snapshotted within a window
+	CheckpointInterval time.Duration
+
+	// Reporters are the sinks every window report is published to
+	Reporters Reporters
+
+	// WebhookSecret signs webhook deliveries with HMAC-SHA256 (empty disables signing)
+	WebhookSecret string
+
+	// WebhookTimeout bounds a single webhook delivery attempt
+	WebhookTimeout time.Duration
+
+	// WebhookMaxRetries is the number of retries per delivery round before a report is parked
+	WebhookMaxRetries int
+
+	// WebhookBackoff is the first retry delay; it doubles up to WebhookMaxBackoff
+	WebhookBackoff    time.Duration
+	WebhookMaxBackoff time.Duration
+
+	// WebhookQueueDir persists undelivered webhook reports across restarts (empty keeps them in memory)
+	WebhookQueueDir string
+
+	// WebhookQueueSize caps the undelivered reports kept per webhook
+	WebhookQueueSize int
+
+	// OTLPMetricsInsecure disables TLS to otlp reporter endpoints
+	OTLPMetricsInsecure bool
+
+	// OTLPMetricsTimeout bounds a single OTLP metrics export
+	OTLPMetricsTimeout time.Duration
+
+	// OTLPMetricsServiceName is the service.name of the resource otlp reporters export; empty uses the default
+	OTLPMetricsServiceName string
+
+	// LineMetricTemplate names statsd and graphite metrics from {key}, {value} and {metric}
+	LineMetricTemplate string
+
+	// LineSanitizePattern matches the characters of keys and values replaced by
+	// LineSanitizeReplacement in statsd and graphite metric names
+	LineSanitizePattern     string
+	LineSanitizeReplacement string
+
+	// LineTimeout bounds connecting and writing to statsd and graphite receivers
+	LineTimeout time.Duration
+
+	// ForwardEndpoints are downstream OTLP gRPC endpoints every request is
+	// forwarded to after counting (empty disables forwarding)
+	ForwardEndpoints Endpoints
+
+	// ForwardMode is sync (acknowledge after forwarding) or async (acknowledge once queued)
+	ForwardMode string
+
+	// ForwardInsecure disables TLS to the forward endpoints
+	ForwardInsecure bool
+
+	// ForwardTimeout bounds a single forwarding attempt
+	ForwardTimeout time.Duration
+
+	// ForwardQueueSize caps the requests queued per endpoint in async mode
+	ForwardQueueSize int
+
+	// ForwardBatchSize and ForwardBatchTimeout bound async batches by log records and delay
+	ForwardBatchSize    int
+	ForwardBatchTimeout time.Duration
+
+	// ForwardMaxRetries is the number of retries of a retryable forwarding failure
+	ForwardMaxRetries int
+
+	// ForwardBackoff is the first retry delay; it doubles up to ForwardMaxBackoff
+	ForwardBackoff    time.Duration
+	ForwardMaxBackoff time.Duration
+
+	// RouteKey is the attribute whose value selects a record's route (empty uses AttributeKey)
+	RouteKey string
+
+	// Routes send records whose RouteKey value matches to a downstream endpoint
+	Routes Routes
+
+	// RouteDefault is the endpoint records matching no route are sent to
+	RouteDefault string
+
+	// RouteUnmatched is the policy for records matching no route: default, drop or reject
+	RouteUnmatched string
+
+	// FileSinkDir writes every record as OTLP-JSON to a file per value under this
+	// directory (empty disables the file sink)
+	FileSinkDir string
+
+	// FileSinkMaxFileMB rotates a value's file at this size in MiB (0 disables size rotation)
+	FileSinkMaxFileMB int
+
+	// FileSinkRotateInterval starts a new file per value every interval
+	FileSinkRotateInterval time.Duration
+
+	// FileSinkMaxTotalMB caps the file sink's disk usage in MiB, deleting the
+	// oldest rotated files first (0 disables the budget)
+	FileSinkMaxTotalMB int
+
+	// ShutdownDrain is how long health checks report NOT_SERVING on shutdown
+	// before the gRPC server stops accepting requests
+	ShutdownDrain time.Duration
+
+	// AdminReadTimeout and AdminWriteTimeout bound admin server requests and responses
+	AdminReadTimeout  time.Duration
+	AdminWriteTimeout time.Duration
+	// AdminUsername and AdminPassword protect the admin endpoints but the probes with basic auth
+	AdminUsername string
+	AdminPassword string
+	// AdminTLSCert and AdminTLSKey serve the admin endpoints over HTTPS when set
+	AdminTLSCert string
+	AdminTLSKey  string
+
+	// TraceExporter exports self-tracing spans: stdout, file:<path> or otlp:<host:port> (empty disables)
+	TraceExporter string
+	// TraceInsecure disables TLS to the OTLP trace endpoint
+	TraceInsecure bool
+	// TraceSampleRatio is the fraction of new traces sampled
+	TraceSampleRatio float64
+
+	// WatchBufferSize is the number of window events buffered per WatchWindows
+	// subscriber before windows are dropped
+	WatchBufferSize int
+
+	// DashboardWindows is the number of completed windows the dashboard's
+	// sparklines span (0 disables the dashboard)
+	DashboardWindows int
+
 	Debug bool
 }
 
-func LoadConfig() (*Config, error) {
-	cfg := &Config{}
+// Rollup is a history resolution together with the number of windows retained at it
+type Rollup struct {
+	Resolution time.Duration
+	Retention  int
+}
 
-	flag.IntVar(&cfg.GRPCPort, "port", 4317, "gRPC server port")
-	flag.IntVar(&cfg.MetricsPort, "metrics-port", 9090, "Port for Prometheus metrics")
-	flag.StringVar(&cfg.AttributeKey, "attribute-key", "service.name", "Attribute key to track")
-	flag.DurationVar(&cfg.WindowDuration, "window-duration", 10*time.Second, "Window duration for reporting counts")
-	flag.BoolVar(&cfg.Debug, "debug", false, "Enable debug logging")
+// Rollups implements flag.Value for a comma-separated list of resolution:retention pairs,
+// e.g. "1m:60,1h:24"
+type Rollups []Rollup
 
-	flag.Parse()
+func (r *Rollups) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, len(*r))
+	for i, rollup := range *r {
+		parts[i] = fmt.Sprintf("%s:%d", rollup.Resolution, rollup.Retention)
+	}
+	return strings.Join(parts, ",")
+}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, err
+func (r *Rollups) Set(value string) error {
+	rollups, err := ParseRollups(value)
+	if err != nil {
+		return err
 	}
+	*r = rollups
+	return nil
+}
 
-	return cfg, nil
+// DefaultHistoryRollups are the rollups used when -history-rollups is not set.
+// Those that do not fit the window duration are dropped, see FitWindow.
+var DefaultHistoryRollups = Rollups{
+	{Resolution: time.Minute, Retention: 60},
+	{Resolution: time.Hour, Retention: 24},
+}
+
+// FitWindow returns the rollups whose resolution is a larger multiple of the
+// window duration and of the rollup kept before it
+func (r Rollups) FitWindow(windowDuration time.Duration) Rollups {
+	fitted := Rollups{}
+	previous := windowDuration
+	for _, rollup := range r {
+		if previous > 0 && rollup.Resolution > previous && rollup.Resolution%previous == 0 {
+			fitted = append(fitted, rollup)
+			previous = rollup.Resolution
+		}
+	}
+	return fitted
+}
+
+// ParseRollups parses a comma-separated list of resolution:retention pairs
+func ParseRollups(value string) (Rollups, error) {
+	rollups := Rollups{}
+	if strings.TrimSpace(value) == "" {
+		return rollups, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		resolution, retention, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid rollup %q (expected resolution:retention)", part)
+		}
+
+		d, err := time.ParseDuration(resolution)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollup resolution %q: %w", resolution, err)
+		}
+
+		n, err := strconv.Atoi(retention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollup retention %q: %w", retention, err)
+		}
+
+		rollups = append(rollups, Rollup{Resolution: d, Retention: n})
+	}
+
+	return rollups, nil
+}
+
+// Reporter is a report sink kind together with its target, e.g. csv:/var/log/counts.csv
+type Reporter struct {
+	Kind   string
+	Target string
+}
+
+// Reporters implements flag.Value for a comma-separated list of kind[:target] reporters,
+// e.g. "log,ndjson:/var/log/reports.ndjson"
+type Reporters []Reporter
+
+func (r *Reporters) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, len(*r))
+	for i, reporter := range *r {
+		parts[i] = reporter.Kind
+		if reporter.Target != "" {
+			parts[i] += ":" + reporter.Target
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *Reporters) Set(value string) error {
+	reporters, err := ParseReporters(value)
+	if err != nil {
+		return err
+	}
+	*r = reporters
+	return nil
+}
+
+// ParseReporters parses a comma-separated list of kind[:target] reporters. The
+// target is everything after the first colon, so it may itself contain colons.
+func ParseReporters(value string) (Reporters, error) {
+	reporters := Reporters{}
+	if strings.TrimSpace(value) == "" {
+		return reporters, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		kind, target, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if kind == "" {
+			return nil, fmt.Errorf("invalid reporter %q (expected kind[:target])", part)
+		}
+		reporters = append(reporters, Reporter{Kind: kind, Target: target})
+	}
+
+	return reporters, nil
+}
+
+// Endpoints implements flag.Value for a comma-separated list of host:port endpoints
+type Endpoints []string
+
+func (e *Endpoints) String() string {
+	if e == nil {
+		return ""
+	}
+	return strings.Join(*e, ",")
+}
+
+func (e *Endpoints) Set(value string) error {
+	endpoints := Endpoints{}
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			endpoints = append(endpoints, part)
+		}
+	}
+	*e = endpoints
+	return nil
+}
+
+// Route sends the records whose routing value equals Value to Endpoint
+type Route struct {
+	Value    string
+	Endpoint string
+}
+
+// Routes implements flag.Value for a comma-separated list of value=endpoint routes,
+// e.g. "prod=prod-collector:4317,staging=staging-collector:4317"
+type Routes []Route
+
+func (r *Routes) String() string {
+	if r == nil {
+		return ""
+	}
+	parts := make([]string, len(*r))
+	for i, route := range *r {
+		parts[i] = route.Value + "=" + route.Endpoint
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *Routes) Set(value string) error {
+	routes, err := ParseRoutes(value)
+	if err != nil {
+		return err
+	}
+	*r = routes
+	return nil
+}
+
+// ParseRoutes parses a comma-separated list of value=endpoint routes
+func ParseRoutes(value string) (Routes, error) {
+	routes := Routes{}
+	if strings.TrimSpace(value) == "" {
+		return routes, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		routeValue, endpoint, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || routeValue == "" {
+			return nil, fmt.Errorf("invalid route %q (expected value=endpoint)", part)
+		}
+		routes = append(routes, Route{Value: routeValue, Endpoint: endpoint})
+	}
+
+	return routes, nil
+}
+
+// LineReporterConfig returns the statsd and graphite reporter settings
+func (c *Config) LineReporterConfig() report.LineConfig {
+	return report.LineConfig{
+		Template:        c.LineMetricTemplate,
+		SanitizePattern: c.LineSanitizePattern,
+		Replacement:     c.LineSanitizeReplacement,
+		AttributeKey:    c.AttributeKey,
+		Timeout:         c.LineTimeout,
+	}
+}
+
+// Tracing returns the self-tracing settings
+func (c *Config) Tracing() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.TraceExporter,
+		Insecure:    c.TraceInsecure,
+		SampleRatio: c.TraceSampleRatio,
+	}
+}
+
+// Forwarding reports whether requests are forwarded or routed downstream
+func (c *Config) Forwarding() bool {
+	return len(c.ForwardEndpoints) > 0 || len(c.Routes) > 0 || c.RouteDefault != ""
+}
+
+// LoadConfig loads the configuration from the command line, environment and
+// config file, exiting after -help
+func LoadConfig() (*Config, error) {
+	cfg, err := Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	return cfg, err
+}
+
+// registerFlags defines a flag per setting on fs, writing to c
+func (c *Config) registerFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.GRPCPort, "port", 4317, "gRPC server port")
+	fs.IntVar(&c.MetricsPort, "metrics-port", 9090, "Port for the admin server serving metrics, pprof, probes and build info")
+	fs.StringVar(&c.AttributeKey, "attribute-key", "service.name", "Attribute key to track")
+	fs.DurationVar(&c.WindowDuration, "window-duration", 10*time.Second, "Window duration for reporting counts")
+	fs.IntVar(&c.MaxValuesPerWindow, "max-values-per-window", 10000, "Maximum distinct attribute values tracked per window")
+	fs.IntVar(&c.MaxMetricLabels, "max-metric-labels", 1000, "Maximum distinct attribute value labels exported as metrics")
+	fs.IntVar(&c.MetricSeriesIdleWindows, "metric-series-idle-windows", 360, "Windows without records after which a value's metric series are removed (0 = never)")
+	fs.StringVar(&c.DistinctAttributeKey, "distinct-key", "", "Attribute key whose distinct values are estimated per tracked value (empty disables)")
+	fs.IntVar(&c.DistinctPrecision, "distinct-precision", 10, "HyperLogLog precision for distinct estimates (4-18)")
+	fs.IntVar(&c.HistorySize, "history-size", 360, "Number of completed windows kept in memory (0 disables history)")
+	fs.Var(&c.HistoryRollups, "history-rollups", "Comma-separated resolution:retention rollups of the window history")
+	fs.StringVar(&c.ReportMode, "report-mode", "delta", "Window report contents: delta, cumulative or both")
+	fs.Float64Var(&c.AnomalyThreshold, "anomaly-threshold", 3, "Absolute z-score at which a value's window count is an anomaly (0 disables)")
+	fs.Float64Var(&c.AnomalyAlpha, "anomaly-alpha", 0.3, "EWMA smoothing factor of anomaly baselines (0-1]")
+	fs.IntVar(&c.AnomalyWarmup, "anomaly-warmup", 5, "Windows a value's baseline needs before it is scored")
+	fs.DurationVar(&c.AnomalySeasonality, "anomaly-seasonality", 0, "Compare windows with the same window one season earlier in history (0 disables)")
+	fs.StringVar(&c.CheckpointDir, "checkpoint-dir", "", "Directory counter state is checkpointed to across restarts (empty disables)")
+	fs.DurationVar(&c.CheckpointInterval, "checkpoint-interval", 30*time.Second, "Interval between counter state snapshots")
+	fs.Var(&c.Reporters, "reporters", "Comma-separated kind[:target] window report sinks: log, table, csv, ndjson, template, webhook, otlp, statsd, graphite")
+	fs.StringVar(&c.WebhookSecret, "webhook-secret", "", "HMAC-SHA256 secret signing webhook deliveries (empty disables signing)")
+	fs.DurationVar(&c.WebhookTimeout, "webhook-timeout", 5*time.Second, "Timeout of a single webhook delivery attempt")
+	fs.IntVar(&c.WebhookMaxRetries, "webhook-max-retries", 5, "Retries per webhook delivery round before a report is parked")
+	fs.DurationVar(&c.WebhookBackoff, "webhook-backoff", 500*time.Millisecond, "Initial webhook retry backoff")
+	fs.DurationVar(&c.WebhookMaxBackoff, "webhook-max-backoff", 30*time.Second, "Maximum webhook retry backoff")
+	fs.StringVar(&c.WebhookQueueDir, "webhook-queue-dir", "", "Directory persisting undelivered webhook reports (empty keeps them in memory)")
+	fs.IntVar(&c.WebhookQueueSize, "webhook-queue-size", 1000, "Maximum undelivered reports kept per webhook")
+	fs.BoolVar(&c.OTLPMetricsInsecure, "otlp-metrics-insecure", false, "Disable TLS to otlp reporter endpoints")
+	fs.DurationVar(&c.OTLPMetricsTimeout, "otlp-metrics-timeout", 10*time.Second, "Timeout of a single OTLP metrics export")
+	fs.StringVar(&c.OTLPMetricsServiceName, "otlp-metrics-service-name", report.DefaultOTLPServiceName, "service.name of the resource otlp reporters export, kept apart from the services whose logs are counted")
+	fs.StringVar(&c.LineMetricTemplate, "line-metric-template", report.DefaultMetricTemplate, "StatsD/Graphite metric name template with {key}, {value} and {metric} placeholders")
+	fs.StringVar(&c.LineSanitizePattern, "line-sanitize-pattern", report.DefaultSanitizePattern, "Regular expression of key and value characters replaced in StatsD/Graphite metric names")
+	fs.StringVar(&c.LineSanitizeReplacement, "line-sanitize-replacement", "_", "Replacement of sanitized characters in StatsD/Graphite metric names")
+	fs.DurationVar(&c.LineTimeout, "line-timeout", 2*time.Second, "Timeout of StatsD/Graphite connections and writes")
+	fs.Var(&c.ForwardEndpoints, "forward-endpoints", "Comma-separated downstream OTLP gRPC host:port endpoints requests are forwarded to (empty disables)")
+	fs.StringVar(&c.ForwardMode, "forward-mode", forward.ModeAsync, "Forwarding acknowledgement: sync or async")
+	fs.BoolVar(&c.ForwardInsecure, "forward-insecure", false, "Disable TLS to forward endpoints")
+	fs.DurationVar(&c.ForwardTimeout, "forward-timeout", 5*time.Second, "Timeout of a single forwarding attempt")
+	fs.IntVar(&c.ForwardQueueSize, "forward-queue-size", 1000, "Maximum requests queued per forward endpoint in async mode")
+	fs.IntVar(&c.ForwardBatchSize, "forward-batch-size", 8192, "Log records at which an async forwarding batch is sent")
+	fs.DurationVar(&c.ForwardBatchTimeout, "forward-batch-timeout", 200*time.Millisecond, "Longest a request waits for its async forwarding batch to fill")
+	fs.IntVar(&c.ForwardMaxRetries, "forward-max-retries", 5, "Retries of a retryable forwarding failure")
+	fs.DurationVar(&c.ForwardBackoff, "forward-backoff", 100*time.Millisecond, "Initial forwarding retry backoff")
+	fs.DurationVar(&c.ForwardMaxBackoff, "forward-max-backoff", 5*time.Second, "Maximum forwarding retry backoff")
+	fs.StringVar(&c.RouteKey, "route-key", "", "Attribute key whose value selects a record's route (empty uses -attribute-key)")
+	fs.Var(&c.Routes, "routes", "Comma-separated value=host:port routes sending matching records to downstream OTLP endpoints")
+	fs.StringVar(&c.RouteDefault, "route-default", "", "Downstream OTLP endpoint of records matching no route")
+	fs.StringVar(&c.RouteUnmatched, "route-unmatched", forward.UnmatchedDefault, "Policy for records matching no route: default, drop or reject")
+	fs.StringVar(&c.FileSinkDir, "file-sink-dir", "", "Directory every record is written to as OTLP-JSON, one subdirectory per value (empty disables)")
+	fs.IntVar(&c.FileSinkMaxFileMB, "file-sink-max-file-mb", 64, "Size in MiB at which a value's file is rotated (0 disables size rotation)")
+	fs.DurationVar(&c.FileSinkRotateInterval, "file-sink-rotate-interval", time.Hour, "Interval at which every value starts a new file")
+	fs.IntVar(&c.FileSinkMaxTotalMB, "file-sink-max-total-mb", 1024, "Disk budget of the file sink in MiB; the oldest rotated files are deleted first (0 disables)")
+	fs.DurationVar(&c.ShutdownDrain, "shutdown-drain", 5*time.Second, "Time health checks report NOT_SERVING on shutdown before requests stop being accepted")
+	fs.DurationVar(&c.AdminReadTimeout, "admin-read-timeout", 10*time.Second, "Timeout for reading admin server requests (0 disables)")
+	fs.DurationVar(&c.AdminWriteTimeout, "admin-write-timeout", 60*time.Second, "Timeout for writing admin server responses, which also caps profile durations (0 disables)")
+	fs.StringVar(&c.AdminUsername, "admin-username", "", "Basic auth username for the admin endpoints except /healthz and /readyz (empty disables)")
+	fs.StringVar(&c.AdminPassword, "admin-password", "", "Basic auth password for the admin endpoints")
+	fs.StringVar(&c.AdminTLSCert, "admin-tls-cert", "", "TLS certificate file for the admin server (empty serves plain HTTP)")
+	fs.StringVar(&c.AdminTLSKey, "admin-tls-key", "", "TLS private key file for the admin server")
+	fs.StringVar(&c.TraceExporter, "trace-exporter", "", "Self-tracing span exporter: stdout, file:<path> or otlp:<host:port> (empty disables)")
+	fs.BoolVar(&c.TraceInsecure, "trace-insecure", false, "Disable TLS to the OTLP trace endpoint")
+	fs.Float64Var(&c.TraceSampleRatio, "trace-sample-ratio", 1, "Fraction of new traces sampled; traces sampled by the caller are always kept")
+	fs.IntVar(&c.WatchBufferSize, "watch-buffer", 16, "Window events buffered per WatchWindows subscriber before windows are dropped")
+	fs.IntVar(&c.DashboardWindows, "dashboard-windows", 30, "Completed windows shown in the admin dashboard's sparklines (0 disables the dashboard)")
+	fs.BoolVar(&c.Debug, "debug", false, "Enable debug logging")
 }
 
 func (c *Config) Validate() error {
@@ -58,5 +537,192 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("window-duration must be positive")
 	}
 
+	if c.MaxValuesPerWindow < 0 {
+		return fmt.Errorf("max-values-per-window cannot be negative")
+	}
+
+	if c.MaxMetricLabels < 0 {
+		return fmt.Errorf("max-metric-labels cannot be negative")
+	}
+
+	if c.MetricSeriesIdleWindows < 0 {
+		return fmt.Errorf("metric-series-idle-windows cannot be negative")
+	}
+
+	if c.DistinctAttributeKey != "" {
+		if c.DistinctAttributeKey == c.AttributeKey {
+			return fmt.Errorf("distinct-key must differ from attribute-key")
+		}
+		if c.DistinctPrecision < sketch.MinPrecision || c.DistinctPrecision > sketch.MaxPrecision {
+			return fmt.Errorf("invalid distinct-precision: %d (must be between %d and %d)", c.DistinctPrecision, sketch.MinPrecision, sketch.MaxPrecision)
+		}
+	}
+
+	if c.HistorySize < 0 {
+		return fmt.Errorf("history-size cannot be negative")
+	}
+
+	previous := c.WindowDuration
+	for _, rollup := range c.HistoryRollups {
+		if rollup.Retention <= 0 {
+			return fmt.Errorf("history rollup %s must retain at least one window", rollup.Resolution)
+		}
+		if rollup.Resolution <= previous || rollup.Resolution%previous != 0 {
+			return fmt.Errorf("history rollup %s must be a larger multiple of %s", rollup.Resolution, previous)
+		}
+		previous = rollup.Resolution
+	}
+
+	switch c.ReportMode {
+	case "", "delta", "cumulative", "both":
+	default:
+		return fmt.Errorf("invalid report-mode: %q (must be delta, cumulative or both)", c.ReportMode)
+	}
+
+	if c.AnomalyThreshold < 0 {
+		return fmt.Errorf("anomaly-threshold cannot be negative")
+	}
+
+	if c.AnomalyThreshold > 0 {
+		if c.AnomalyAlpha <= 0 || c.AnomalyAlpha > 1 {
+			return fmt.Errorf("invalid anomaly-alpha: %g (must be in (0, 1])", c.AnomalyAlpha)
+		}
+		if c.AnomalyWarmup < 0 {
+			return fmt.Errorf("anomaly-warmup cannot be negative")
+		}
+		if c.AnomalySeasonality < 0 {
+			return fmt.Errorf("anomaly-seasonality cannot be negative")
+		}
+		if c.AnomalySeasonality > 0 {
+			if c.AnomalySeasonality%c.WindowDuration != 0 {
+				return fmt.Errorf("anomaly-seasonality must be a multiple of window-duration")
+			}
+			if c.AnomalySeasonality > time.Duration(c.HistorySize)*c.WindowDuration {
+				return fmt.Errorf("anomaly-seasonality %s exceeds the retained history (history-size × window-duration)", c.AnomalySeasonality)
+			}
+		}
+	}
+
+	if c.CheckpointDir != "" && c.CheckpointInterval <= 0 {
+		return fmt.Errorf("checkpoint-interval must be positive")
+	}
+
+	webhooks, otlp, lines := false, false, false
+	for _, reporter := range c.Reporters {
+		if err := report.Validate(reporter.Kind, reporter.Target); err != nil {
+			return fmt.Errorf("invalid reporters: %w", err)
+		}
+		webhooks = webhooks || reporter.Kind == report.KindWebhook
+		otlp = otlp || reporter.Kind == report.KindOTLP
+		lines = lines || reporter.Kind == report.KindStatsD || reporter.Kind == report.KindGraphite
+	}
+
+	if webhooks {
+		if c.WebhookTimeout <= 0 {
+			return fmt.Errorf("webhook-timeout must be positive")
+		}
+		if c.WebhookMaxRetries < 0 {
+			return fmt.Errorf("webhook-max-retries cannot be negative")
+		}
+		if c.WebhookBackoff <= 0 || c.WebhookMaxBackoff < c.WebhookBackoff {
+			return fmt.Errorf("webhook-backoff must be positive and no larger than webhook-max-backoff")
+		}
+		if c.WebhookQueueSize <= 0 {
+			return fmt.Errorf("webhook-queue-size must be positive")
+		}
+	}
+
+	if otlp && c.OTLPMetricsTimeout <= 0 {
+		return fmt.Errorf("otlp-metrics-timeout must be positive")
+	}
+
+	if lines {
+		if c.LineTimeout <= 0 {
+			return fmt.Errorf("line-timeout must be positive")
+		}
+		if err := report.ValidateLineConfig(c.LineReporterConfig()); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Routes) > 0 || c.RouteDefault != "" {
+		seen := make(map[string]bool, len(c.Routes))
+		for _, route := range c.Routes {
+			if _, _, err := net.SplitHostPort(route.Endpoint); err != nil {
+				return fmt.Errorf("invalid endpoint %q of route %q (expected host:port)", route.Endpoint, route.Value)
+			}
+			if seen[route.Value] {
+				return fmt.Errorf("duplicate route %q", route.Value)
+			}
+			seen[route.Value] = true
+		}
+		switch c.RouteUnmatched {
+		case forward.UnmatchedDefault:
+			if _, _, err := net.SplitHostPort(c.RouteDefault); err != nil {
+				return fmt.Errorf("route-unmatched=default requires a host:port route-default, got %q", c.RouteDefault)
+			}
+		case forward.UnmatchedDrop, forward.UnmatchedReject:
+		default:
+			return fmt.Errorf("route-unmatched must be %s, %s or %s", forward.UnmatchedDefault, forward.UnmatchedDrop, forward.UnmatchedReject)
+		}
+	}
+
+	if c.Forwarding() {
+		for _, endpoint := range c.ForwardEndpoints {
+			if _, _, err := net.SplitHostPort(endpoint); err != nil {
+				return fmt.Errorf("invalid forward endpoint %q (expected host:port)", endpoint)
+			}
+		}
+		if c.ForwardMode != forward.ModeSync && c.ForwardMode != forward.ModeAsync {
+			return fmt.Errorf("forward-mode must be %s or %s", forward.ModeSync, forward.ModeAsync)
+		}
+		if c.ForwardTimeout <= 0 {
+			return fmt.Errorf("forward-timeout must be positive")
+		}
+		if c.ForwardMaxRetries < 0 {
+			return fmt.Errorf("forward-max-retries cannot be negative")
+		}
+		if c.ForwardBackoff <= 0 || c.ForwardMaxBackoff < c.ForwardBackoff {
+			return fmt.Errorf("forward-backoff must be positive and no larger than forward-max-backoff")
+		}
+		if c.ForwardMode == forward.ModeAsync && (c.ForwardQueueSize <= 0 || c.ForwardBatchSize <= 0 || c.ForwardBatchTimeout <= 0) {
+			return fmt.Errorf("forward-queue-size, forward-batch-size and forward-batch-timeout must be positive")
+		}
+	}
+
+	if c.FileSinkDir != "" {
+		if c.FileSinkMaxFileMB < 0 || c.FileSinkMaxTotalMB < 0 {
+			return fmt.Errorf("file-sink-max-file-mb and file-sink-max-total-mb cannot be negative")
+		}
+		if c.FileSinkRotateInterval < time.Second {
+			return fmt.Errorf("file-sink-rotate-interval must be at least 1s")
+		}
+	}
+
+	if c.ShutdownDrain < 0 {
+		return fmt.Errorf("shutdown-drain cannot be negative")
+	}
+
+	if c.AdminReadTimeout < 0 || c.AdminWriteTimeout < 0 {
+		return fmt.Errorf("admin-read-timeout and admin-write-timeout cannot be negative")
+	}
+	if (c.AdminUsername == "") != (c.AdminPassword == "") {
+		return fmt.Errorf("admin-username and admin-password must be set together")
+	}
+	if (c.AdminTLSCert == "") != (c.AdminTLSKey == "") {
+		return fmt.Errorf("admin-tls-cert and admin-tls-key must be set together")
+	}
+
+	if c.WatchBufferSize < 0 {
+		return fmt.Errorf("watch-buffer cannot be negative")
+	}
+	if c.DashboardWindows < 0 {
+		return fmt.Errorf("dashboard-windows cannot be negative")
+	}
+
+	if err := tracing.Validate(c.Tracing()); err != nil {
+		return fmt.Errorf("invalid tracing: %w", err)
+	}
+
 	return nil
 }
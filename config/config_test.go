@@ -81,6 +81,642 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid cardinality caps",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				MaxValuesPerWindow: 100,
+				MaxMetricLabels:    50,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid max values per window - negative",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				MaxValuesPerWindow: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid max metric labels - negative",
+			config: Config{
+				GRPCPort:        4317,
+				MetricsPort:     9090,
+				AttributeKey:    "service.name",
+				WindowDuration:  10 * time.Second,
+				MaxMetricLabels: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid metric series idle windows - negative",
+			config: Config{
+				GRPCPort:                4317,
+				MetricsPort:             9090,
+				AttributeKey:            "service.name",
+				WindowDuration:          10 * time.Second,
+				MetricSeriesIdleWindows: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid distinct key",
+			config: Config{
+				GRPCPort:             4317,
+				MetricsPort:          9090,
+				AttributeKey:         "service.name",
+				WindowDuration:       10 * time.Second,
+				DistinctAttributeKey: "trace_id",
+				DistinctPrecision:    10,
+			},
+			wantErr: false,
+		},
+		{
+			name: "distinct key same as attribute key",
+			config: Config{
+				GRPCPort:             4317,
+				MetricsPort:          9090,
+				AttributeKey:         "service.name",
+				WindowDuration:       10 * time.Second,
+				DistinctAttributeKey: "service.name",
+				DistinctPrecision:    10,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid distinct precision",
+			config: Config{
+				GRPCPort:             4317,
+				MetricsPort:          9090,
+				AttributeKey:         "service.name",
+				WindowDuration:       10 * time.Second,
+				DistinctAttributeKey: "trace_id",
+				DistinctPrecision:    30,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid history rollups",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				HistorySize:    360,
+				HistoryRollups: Rollups{{Resolution: time.Minute, Retention: 60}, {Resolution: time.Hour, Retention: 24}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "history rollup not a multiple of window duration",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 7 * time.Second,
+				HistoryRollups: Rollups{{Resolution: time.Minute, Retention: 60}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "history rollups out of order",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				HistoryRollups: Rollups{{Resolution: time.Hour, Retention: 24}, {Resolution: time.Minute, Retention: 60}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "history rollup without retention",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				HistoryRollups: Rollups{{Resolution: time.Minute, Retention: 0}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid report mode",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				ReportMode:     "both",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid report mode",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				ReportMode:     "sometimes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid anomaly detection with seasonality",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				AnomalyThreshold:   3,
+				AnomalyAlpha:       0.3,
+				AnomalyWarmup:      5,
+				HistorySize:        360,
+				AnomalySeasonality: time.Hour,
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative anomaly threshold",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				AnomalyThreshold: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid anomaly alpha",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				AnomalyThreshold: 3,
+				AnomalyAlpha:     1.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "anomaly seasonality not a multiple of window",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				AnomalyThreshold:   3,
+				AnomalyAlpha:       0.3,
+				HistorySize:        360,
+				AnomalySeasonality: 15 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "anomaly seasonality beyond history",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				AnomalyThreshold:   3,
+				AnomalyAlpha:       0.3,
+				HistorySize:        6,
+				AnomalySeasonality: time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid checkpointing",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				CheckpointDir:      "/var/lib/otlp",
+				CheckpointInterval: 30 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "checkpointing without interval",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				CheckpointDir:  "/var/lib/otlp",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid reporters",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				Reporters:      Reporters{{Kind: "log"}, {Kind: "ndjson", Target: "/var/log/reports.ndjson"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown reporter",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				Reporters:      Reporters{{Kind: "fax"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ndjson reporter without path",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				Reporters:      Reporters{{Kind: "ndjson"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid webhook",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				Reporters:         Reporters{{Kind: "webhook", Target: "https://bot.example.com/hook"}},
+				WebhookTimeout:    5 * time.Second,
+				WebhookMaxRetries: 5,
+				WebhookBackoff:    500 * time.Millisecond,
+				WebhookMaxBackoff: 30 * time.Second,
+				WebhookQueueSize:  1000,
+			},
+			wantErr: false,
+		},
+		{
+			name: "webhook without timeout",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				Reporters:         Reporters{{Kind: "webhook", Target: "https://bot.example.com/hook"}},
+				WebhookTimeout:    0,
+				WebhookMaxRetries: 5,
+				WebhookBackoff:    500 * time.Millisecond,
+				WebhookMaxBackoff: 30 * time.Second,
+				WebhookQueueSize:  1000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "webhook backoff above max",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				Reporters:         Reporters{{Kind: "webhook", Target: "https://bot.example.com/hook"}},
+				WebhookTimeout:    5 * time.Second,
+				WebhookMaxRetries: 5,
+				WebhookBackoff:    time.Minute,
+				WebhookMaxBackoff: 30 * time.Second,
+				WebhookQueueSize:  1000,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid otlp reporter",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				Reporters:          Reporters{{Kind: "otlp", Target: "collector:4317"}},
+				OTLPMetricsTimeout: 10 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "otlp reporter without port",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				Reporters:          Reporters{{Kind: "otlp", Target: "collector"}},
+				OTLPMetricsTimeout: 10 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid sync forwarding",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				ForwardEndpoints:  Endpoints{"collector:4317"},
+				ForwardMode:       "sync",
+				ForwardTimeout:    5 * time.Second,
+				ForwardBackoff:    100 * time.Millisecond,
+				ForwardMaxBackoff: 5 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "forwarding with unknown mode",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				ForwardEndpoints:  Endpoints{"collector:4317"},
+				ForwardMode:       "eventually",
+				ForwardTimeout:    5 * time.Second,
+				ForwardBackoff:    100 * time.Millisecond,
+				ForwardMaxBackoff: 5 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "async forwarding without queue",
+			config: Config{
+				GRPCPort:            4317,
+				MetricsPort:         9090,
+				AttributeKey:        "service.name",
+				WindowDuration:      10 * time.Second,
+				ForwardEndpoints:    Endpoints{"collector:4317"},
+				ForwardMode:         "async",
+				ForwardTimeout:      5 * time.Second,
+				ForwardBatchSize:    8192,
+				ForwardBatchTimeout: 200 * time.Millisecond,
+				ForwardBackoff:      100 * time.Millisecond,
+				ForwardMaxBackoff:   5 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "forward endpoint without port",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				ForwardEndpoints:  Endpoints{"collector"},
+				ForwardMode:       "sync",
+				ForwardTimeout:    5 * time.Second,
+				ForwardBackoff:    100 * time.Millisecond,
+				ForwardMaxBackoff: 5 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid routes",
+			config: Config{
+				GRPCPort:            4317,
+				MetricsPort:         9090,
+				AttributeKey:        "service.name",
+				WindowDuration:      10 * time.Second,
+				Routes:              Routes{{Value: "prod", Endpoint: "prod:4317"}, {Value: "staging", Endpoint: "staging:4317"}},
+				RouteDefault:        "fallback:4317",
+				RouteUnmatched:      "default",
+				ForwardMode:         "async",
+				ForwardTimeout:      5 * time.Second,
+				ForwardQueueSize:    1000,
+				ForwardBatchSize:    8192,
+				ForwardBatchTimeout: 200 * time.Millisecond,
+				ForwardBackoff:      100 * time.Millisecond,
+				ForwardMaxBackoff:   5 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "default route policy without default route",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				Routes:            Routes{{Value: "prod", Endpoint: "prod:4317"}},
+				RouteUnmatched:    "default",
+				ForwardMode:       "sync",
+				ForwardTimeout:    5 * time.Second,
+				ForwardBackoff:    100 * time.Millisecond,
+				ForwardMaxBackoff: 5 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate route",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				Routes:            Routes{{Value: "prod", Endpoint: "prod:4317"}, {Value: "prod", Endpoint: "other:4317"}},
+				RouteUnmatched:    "drop",
+				ForwardMode:       "sync",
+				ForwardTimeout:    5 * time.Second,
+				ForwardBackoff:    100 * time.Millisecond,
+				ForwardMaxBackoff: 5 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid file sink",
+			config: Config{
+				GRPCPort:               4317,
+				MetricsPort:            9090,
+				AttributeKey:           "service.name",
+				WindowDuration:         10 * time.Second,
+				FileSinkDir:            "/var/log/otlp",
+				FileSinkMaxFileMB:      64,
+				FileSinkRotateInterval: time.Hour,
+				FileSinkMaxTotalMB:     1024,
+			},
+			wantErr: false,
+		},
+		{
+			name: "file sink without rotate interval",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				FileSinkDir:    "/var/log/otlp",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid admin auth and TLS",
+			config: Config{
+				GRPCPort:          4317,
+				MetricsPort:       9090,
+				AttributeKey:      "service.name",
+				WindowDuration:    10 * time.Second,
+				AdminReadTimeout:  10 * time.Second,
+				AdminWriteTimeout: time.Minute,
+				AdminUsername:     "admin",
+				AdminPassword:     "secret",
+				AdminTLSCert:      "/etc/tls/tls.crt",
+				AdminTLSKey:       "/etc/tls/tls.key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "admin username without password",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				AdminUsername:  "admin",
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin TLS certificate without key",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				AdminTLSCert:   "/etc/tls/tls.crt",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative shutdown drain",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				ShutdownDrain:  -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative admin timeout",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				AdminReadTimeout: -time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative watch buffer",
+			config: Config{
+				GRPCPort:        4317,
+				MetricsPort:     9090,
+				AttributeKey:    "service.name",
+				WindowDuration:  10 * time.Second,
+				WatchBufferSize: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative dashboard windows",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				DashboardWindows: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid otlp tracing",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				TraceExporter:    "otlp:collector:4317",
+				TraceSampleRatio: 0.25,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid trace exporter",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				TraceExporter:    "zipkin",
+				TraceSampleRatio: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid trace sample ratio",
+			config: Config{
+				GRPCPort:         4317,
+				MetricsPort:      9090,
+				AttributeKey:     "service.name",
+				WindowDuration:   10 * time.Second,
+				TraceSampleRatio: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid graphite reporter",
+			config: Config{
+				GRPCPort:                4317,
+				MetricsPort:             9090,
+				AttributeKey:            "service.name",
+				WindowDuration:          10 * time.Second,
+				Reporters:               Reporters{{Kind: "graphite", Target: "graphite:2003"}},
+				LineMetricTemplate:      "logs.{value}.{metric}",
+				LineSanitizeReplacement: "_",
+				LineTimeout:             2 * time.Second,
+			},
+			wantErr: false,
+		},
+		{
+			name: "statsd template without value",
+			config: Config{
+				GRPCPort:           4317,
+				MetricsPort:        9090,
+				AttributeKey:       "service.name",
+				WindowDuration:     10 * time.Second,
+				Reporters:          Reporters{{Kind: "statsd", Target: "localhost:8125"}},
+				LineMetricTemplate: "logs.{metric}",
+				LineTimeout:        2 * time.Second,
+			},
+			wantErr: true,
+		},
+		{
+			name: "otlp reporter without timeout",
+			config: Config{
+				GRPCPort:       4317,
+				MetricsPort:    9090,
+				AttributeKey:   "service.name",
+				WindowDuration: 10 * time.Second,
+				Reporters:      Reporters{{Kind: "otlp", Target: "collector:4317"}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -92,3 +728,109 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRollups(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Rollups
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			value: "",
+			want:  Rollups{},
+		},
+		{
+			name:  "multiple rollups",
+			value: "1m:60, 1h:24",
+			want:  Rollups{{Resolution: time.Minute, Retention: 60}, {Resolution: time.Hour, Retention: 24}},
+		},
+		{
+			name:    "missing retention",
+			value:   "1m",
+			wantErr: true,
+		},
+		{
+			name:    "invalid resolution",
+			value:   "soon:60",
+			wantErr: true,
+		},
+		{
+			name:    "invalid retention",
+			value:   "1m:many",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRollups(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRollups() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want.String() {
+				t.Errorf("ParseRollups() = %v, want %v", got.String(), tt.want.String())
+			}
+		})
+	}
+}
+
+func TestParseReporters(t *testing.T) {
+	reporters, err := ParseReporters("log, csv, ndjson:/var/log/reports.ndjson,template:C:/report.tmpl")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := Reporters{
+		{Kind: "log"},
+		{Kind: "csv"},
+		{Kind: "ndjson", Target: "/var/log/reports.ndjson"},
+		{Kind: "template", Target: "C:/report.tmpl"},
+	}
+	if len(reporters) != len(want) {
+		t.Fatalf("Expected %d reporters, got %d", len(want), len(reporters))
+	}
+	for i := range want {
+		if reporters[i] != want[i] {
+			t.Errorf("Reporter %d = %+v, want %+v", i, reporters[i], want[i])
+		}
+	}
+	if got := reporters.String(); got != "log,csv,ndjson:/var/log/reports.ndjson,template:C:/report.tmpl" {
+		t.Errorf("Unexpected String() %q", got)
+	}
+
+	if _, err := ParseReporters("log,,csv"); err == nil {
+		t.Errorf("Expected an error for an empty reporter")
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("prod=prod-collector:4317, staging=staging-collector:4317")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := Routes{
+		{Value: "prod", Endpoint: "prod-collector:4317"},
+		{Value: "staging", Endpoint: "staging-collector:4317"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("Expected %d routes, got %d", len(want), len(routes))
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("Route %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+	if got := routes.String(); got != "prod=prod-collector:4317,staging=staging-collector:4317" {
+		t.Errorf("Unexpected String() %q", got)
+	}
+
+	if _, err := ParseRoutes("prod-collector:4317"); err == nil {
+		t.Errorf("Expected an error for a route without a value")
+	}
+}
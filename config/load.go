@@ -0,0 +1,389 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"otlp-log-parser-assignment/internal/report"
+)
+
+// ReloadableSettings are the settings that can change without a restart: the
+// group-by attribute and window length, and the routing rules
+var ReloadableSettings = []string{
+	"attribute-key",
+	"window-duration",
+	"route-key",
+	"routes",
+	"route-default",
+	"route-unmatched",
+}
+
+var (
+	// ErrInvalidSettings is returned by Patch for unknown or invalid settings
+	ErrInvalidSettings = errors.New("invalid settings")
+	// ErrRestartRequired is returned when settings outside ReloadableSettings change
+	ErrRestartRequired = errors.New("settings can only change on restart")
+)
+
+// EnvPrefix prefixes the environment variable of every setting, e.g.
+// OTLP_PARSER_ATTRIBUTE_KEY for -attribute-key
+const EnvPrefix = "OTLP_PARSER_"
+
+// Source is the configuration layer a setting's effective value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	// SourceRuntime marks settings changed at runtime by Patch
+	SourceRuntime Source = "runtime"
+)
+
+// SecretSettings are the settings whose values are redacted when printed
+var SecretSettings = []string{"admin-password", "webhook-secret"}
+
+// EnvName returns the environment variable of the setting name
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load parses args with a flag set of its own, layered over the process
+// environment and a config file: defaults < the YAML or JSON file named by
+// -config < OTLP_PARSER_* environment variables < flags. Unknown settings in
+// the file or environment are rejected, and the result is validated.
+func Load(args []string) (*Config, error) {
+	cfg := &Config{
+		HistoryRollups: append(Rollups(nil), DefaultHistoryRollups...),
+		Reporters:      Reporters{{Kind: report.KindLog}},
+		args:           append([]string(nil), args...),
+		sources:        make(map[string]Source),
+	}
+
+	fs := flag.NewFlagSet("otlp-log-parser", flag.ContinueOnError)
+	cfg.registerFlags(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		cfg.sources[f.Name] = SourceDefault
+	})
+	fs.StringVar(&cfg.File, "config", "", "YAML or JSON file of settings keyed by flag name; environment variables and flags override it and it is reread on SIGHUP")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	env, err := environment(fs)
+	if err != nil {
+		return nil, err
+	}
+	if file, ok := env["config"]; ok && !explicit["config"] {
+		cfg.File = file
+	}
+	delete(env, "config")
+
+	if cfg.File != "" {
+		settings, err := readSettings(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		if err := cfg.apply(fs, settings, SourceFile, explicit); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", cfg.File, err)
+		}
+	}
+	if err := cfg.apply(fs, env, SourceEnv, explicit); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+	for name := range explicit {
+		cfg.sources[name] = SourceFlag
+	}
+	delete(cfg.sources, "config")
+	cfg.fitDefaultRollups()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// apply sets the settings not given as flags on fs and records their source
+func (c *Config) apply(fs *flag.FlagSet, settings map[string]string, source Source, explicit map[string]bool) error {
+	for name := range settings {
+		if explicit[name] {
+			delete(settings, name)
+		}
+	}
+	if err := setAll(fs, settings); err != nil {
+		return err
+	}
+	for name := range settings {
+		c.sources[name] = source
+	}
+	return nil
+}
+
+// environment returns the settings of the OTLP_PARSER_* environment
+// variables keyed by flag name, rejecting variables that are no flag of fs
+func environment(fs *flag.FlagSet) (map[string]string, error) {
+	settings := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, EnvPrefix)
+		if !ok {
+			continue
+		}
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+		if EnvName(name) != key || fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown environment variable %s", key)
+		}
+		settings[name] = value
+	}
+	return settings, nil
+}
+
+// readSettings reads a config file, parsed as YAML when its extension is
+// .yaml or .yml and as JSON otherwise
+func readSettings(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var settings map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		settings, err = ParseYAMLSettings(data)
+	default:
+		settings, err = ParseSettings(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// fitDefaultRollups drops the default history rollups that do not fit the
+// window duration, so only rollups set explicitly can fail validation
+func (c *Config) fitDefaultRollups() {
+	if c.sources["history-rollups"] == SourceDefault {
+		c.HistoryRollups = DefaultHistoryRollups.FitWindow(c.WindowDuration)
+	}
+}
+
+// Reload loads the configuration again from the original command line and
+// the current contents of its config file
+func (c *Config) Reload() (*Config, error) {
+	return Load(c.args)
+}
+
+// Patch returns a copy of c with settings applied, validated. Errors wrap
+// ErrInvalidSettings.
+func (c *Config) Patch(settings map[string]string) (*Config, error) {
+	next, fs := c.bind()
+	if err := setAll(fs, settings); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSettings, err)
+	}
+
+	next.sources = make(map[string]Source, len(c.sources))
+	for name, source := range c.sources {
+		next.sources[name] = source
+	}
+	for name := range settings {
+		next.sources[name] = SourceRuntime
+	}
+	next.fitDefaultRollups()
+
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSettings, err)
+	}
+	return next, nil
+}
+
+// Settings returns every setting as its flag value, keyed by flag name
+func (c *Config) Settings() map[string]string {
+	_, fs := c.bind()
+
+	settings := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		settings[f.Name] = f.Value.String()
+	})
+	return settings
+}
+
+// Sources returns the source of every setting, keyed by flag name
+func (c *Config) Sources() map[string]Source {
+	sources := make(map[string]Source)
+	for name := range c.Settings() {
+		sources[name] = SourceDefault
+		if source, ok := c.sources[name]; ok {
+			sources[name] = source
+		}
+	}
+	return sources
+}
+
+// WriteSettings writes every setting with its value and source, one per line
+// sorted by name. Secret values are redacted.
+func (c *Config) WriteSettings(w io.Writer) error {
+	settings, sources := c.Settings(), c.Sources()
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "SETTING\tVALUE\tSOURCE\n")
+	for _, name := range names {
+		value := strconv.Quote(settings[name])
+		if settings[name] != "" && slices.Contains(SecretSettings, name) {
+			value = "<redacted>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, value, sources[name])
+	}
+	return tw.Flush()
+}
+
+// Diff returns the sorted names of the settings whose values differ in next
+func (c *Config) Diff(next *Config) []string {
+	current, updated := c.Settings(), next.Settings()
+
+	var changed []string
+	for name, value := range current {
+		if updated[name] != value {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// ReloadChanges returns the settings that differ in next, or an error
+// wrapping ErrRestartRequired when any of them is not reloadable
+func (c *Config) ReloadChanges(next *Config) ([]string, error) {
+	changed := c.Diff(next)
+
+	var restart []string
+	for _, name := range changed {
+		if !slices.Contains(ReloadableSettings, name) {
+			restart = append(restart, name)
+		}
+	}
+	if len(restart) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRestartRequired, strings.Join(restart, ", "))
+	}
+	return changed, nil
+}
+
+// bind returns a copy of c together with a flag set writing to the copy
+func (c *Config) bind() (*Config, *flag.FlagSet) {
+	next := &Config{}
+	fs := flag.NewFlagSet("otlp-log-parser", flag.ContinueOnError)
+	next.registerFlags(fs)
+	// Registering wrote the defaults; the flags keep pointing at next's fields
+	*next = *c
+	return next, fs
+}
+
+// setAll sets every setting on fs, rejecting names that are not flags
+func setAll(fs *flag.FlagSet, settings map[string]string) error {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if err := fs.Set(name, settings[name]); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ParseSettings parses a JSON object of settings keyed by flag name. Values
+// are strings, numbers, booleans or arrays of them; arrays are joined with
+// commas, e.g. "routes": ["prod=prod:4317", "dev=dev:4317"].
+func ParseSettings(data []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var raw map[string]interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of settings: %w", err)
+	}
+	return settingValues(raw)
+}
+
+// ParseYAMLSettings parses a YAML mapping of settings keyed by flag name, with
+// the values ParseSettings accepts
+func ParseYAMLSettings(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("expected a YAML mapping of settings: %w", err)
+	}
+	return settingValues(raw)
+}
+
+func settingValues(raw map[string]interface{}) (map[string]string, error) {
+	settings := make(map[string]string, len(raw))
+	for name, value := range raw {
+		s, err := settingValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		settings[name] = s
+	}
+	return settings, nil
+}
+
+func settingValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			if _, ok := item.([]interface{}); ok {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			s, err := settingValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
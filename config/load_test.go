@@ -0,0 +1,309 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	return writeNamedConfigFile(t, "config.json", content)
+}
+
+func writeNamedConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.GRPCPort != 4317 || cfg.AttributeKey != "service.name" || cfg.WindowDuration != 10*time.Second {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+	if len(cfg.HistoryRollups) != 2 || len(cfg.Reporters) != 1 {
+		t.Errorf("Expected default rollups and reporters, got %v and %v", cfg.HistoryRollups, cfg.Reporters)
+	}
+}
+
+func TestLoad_DefaultRollupsFitWindow(t *testing.T) {
+	tests := []struct {
+		window string
+		want   Rollups
+	}{
+		{window: "10s", want: DefaultHistoryRollups},
+		{window: "5m", want: Rollups{{Resolution: time.Hour, Retention: 24}}},
+		{window: "7s", want: Rollups{}},
+		{window: "2h", want: Rollups{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			cfg, err := Load([]string{"-window-duration", tt.window})
+			if err != nil {
+				t.Fatalf("Expected a %s window to load with default settings, got %v", tt.window, err)
+			}
+			if !reflect.DeepEqual(cfg.HistoryRollups, tt.want) {
+				t.Errorf("HistoryRollups = %v, want %v", cfg.HistoryRollups, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load([]string{"-window-duration", "5m", "-history-rollups", "1m:60"}); err == nil {
+		t.Error("Expected explicit rollups that do not fit the window to fail validation")
+	}
+}
+
+func TestLoad_FileAndFlags(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"attribute-key": "k8s.namespace",
+		"window-duration": "1m",
+		"max-values-per-window": 50,
+		"debug": true,
+		"routes": ["prod=prod:4317", "dev=dev:4317"],
+		"route-unmatched": "drop"
+	}`)
+
+	cfg, err := Load([]string{"-config", path, "-window-duration", "30s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.AttributeKey != "k8s.namespace" || cfg.MaxValuesPerWindow != 50 || !cfg.Debug {
+		t.Errorf("Expected settings from the file, got %+v", cfg)
+	}
+	if cfg.WindowDuration != 30*time.Second {
+		t.Errorf("Expected the flag to override the file, got %s", cfg.WindowDuration)
+	}
+	if len(cfg.Routes) != 2 || cfg.Routes[1] != (Route{Value: "dev", Endpoint: "dev:4317"}) {
+		t.Errorf("Expected routes from an array, got %v", cfg.Routes)
+	}
+	if cfg.File != path {
+		t.Errorf("Expected File %q, got %q", path, cfg.File)
+	}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeNamedConfigFile(t, "config.yaml", `
+attribute-key: k8s.namespace
+window-duration: 1m
+max-values-per-window: 50
+routes:
+  - prod=prod:4317
+route-unmatched: drop
+`)
+	t.Setenv("OTLP_PARSER_CONFIG", path)
+	t.Setenv("OTLP_PARSER_WINDOW_DURATION", "20s")
+	t.Setenv("OTLP_PARSER_MAX_VALUES_PER_WINDOW", "100")
+	t.Setenv("OTLP_PARSER_DEBUG", "true")
+
+	cfg, err := Load([]string{"-max-values-per-window", "200"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.File != path || cfg.AttributeKey != "k8s.namespace" || len(cfg.Routes) != 1 {
+		t.Errorf("Expected settings from the YAML file named by the environment, got %+v", cfg)
+	}
+	if cfg.WindowDuration != 20*time.Second || !cfg.Debug {
+		t.Errorf("Expected the environment to override the file, got %s and %v", cfg.WindowDuration, cfg.Debug)
+	}
+	if cfg.MaxValuesPerWindow != 200 {
+		t.Errorf("Expected the flag to override the environment, got %d", cfg.MaxValuesPerWindow)
+	}
+
+	sources := cfg.Sources()
+	want := map[string]Source{
+		"attribute-key":         SourceFile,
+		"window-duration":       SourceEnv,
+		"debug":                 SourceEnv,
+		"max-values-per-window": SourceFlag,
+		"port":                  SourceDefault,
+	}
+	for name, source := range want {
+		if sources[name] != source {
+			t.Errorf("Source of %s = %q, want %q", name, sources[name], source)
+		}
+	}
+	if _, ok := sources["config"]; ok {
+		t.Error("Expected no source for -config itself")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "unknown setting", content: `{"atribute-key": "host.name"}`},
+		{name: "invalid value", content: `{"window-duration": "soon"}`},
+		{name: "not an object", content: `["attribute-key"]`},
+		{name: "fails validation", content: `{"window-duration": "0s"}`},
+		{name: "unknown yaml setting", file: "config.yml", content: "atribute-key: host.name"},
+		{name: "yaml mapping value", file: "config.yaml", content: "routes:\n  prod: prod:4317"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tt.file
+			if file == "" {
+				file = "config.json"
+			}
+			if _, err := Load([]string{"-config", writeNamedConfigFile(t, file, tt.content)}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	t.Run("unknown environment variable", func(t *testing.T) {
+		t.Setenv("OTLP_PARSER_WINDOW", "1m")
+		if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "OTLP_PARSER_WINDOW") {
+			t.Errorf("Expected the unknown variable to be rejected, got %v", err)
+		}
+	})
+
+	if _, err := Load([]string{"-help"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp, got %v", err)
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	path := writeConfigFile(t, `{"attribute-key": "service.name"}`)
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"attribute-key": "host.name"}`), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	next, err := cfg.Reload()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next.AttributeKey != "host.name" {
+		t.Errorf("Expected the rewritten file to be read, got %q", next.AttributeKey)
+	}
+	if got := cfg.Diff(next); !reflect.DeepEqual(got, []string{"attribute-key"}) {
+		t.Errorf("Diff() = %v, want [attribute-key]", got)
+	}
+}
+
+func TestConfig_Patch(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next, err := cfg.Patch(map[string]string{"window-duration": "30s", "routes": "prod=prod:4317", "route-unmatched": "drop"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next.WindowDuration != 30*time.Second || cfg.WindowDuration != 10*time.Second {
+		t.Errorf("Expected a patched copy, got %s (original %s)", next.WindowDuration, cfg.WindowDuration)
+	}
+	want := []string{"route-unmatched", "routes", "window-duration"}
+	if got := cfg.Diff(next); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+
+	if next.Sources()["window-duration"] != SourceRuntime || cfg.Sources()["window-duration"] != SourceDefault {
+		t.Errorf("Expected patched settings to have a runtime source")
+	}
+
+	if _, err := cfg.Patch(map[string]string{"window-duration": "7s", "history-rollups": "1m:60"}); !errors.Is(err, ErrInvalidSettings) {
+		t.Error("Expected rollups that are no multiple of the window to fail validation")
+	}
+	next, err = cfg.Patch(map[string]string{"window-duration": "5m"})
+	if err != nil || !reflect.DeepEqual(next.HistoryRollups, Rollups{{Resolution: time.Hour, Retention: 24}}) {
+		t.Errorf("Expected the default rollups to be fitted to the new window, got %v, %v", next, err)
+	}
+	if _, err := cfg.Patch(map[string]string{"config": "other.json"}); !errors.Is(err, ErrInvalidSettings) {
+		t.Error("Expected an unknown setting to be rejected")
+	}
+}
+
+func TestParseSettings(t *testing.T) {
+	settings, err := ParseSettings([]byte(`{"port": 4318, "anomaly-alpha": 0.5, "debug": false, "reporters": ["log", "csv:/tmp/c.csv"]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{"port": "4318", "anomaly-alpha": "0.5", "debug": "false", "reporters": "log,csv:/tmp/c.csv"}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("ParseSettings() = %v, want %v", settings, want)
+	}
+
+	if _, err := ParseSettings([]byte(`{"routes": {"prod": "prod:4317"}}`)); err == nil {
+		t.Error("Expected an object value to be rejected")
+	}
+}
+
+func TestParseYAMLSettings(t *testing.T) {
+	settings, err := ParseYAMLSettings([]byte("port: 4318\nanomaly-alpha: 0.5\ndebug: false\nhistory-rollups: 1m:60\nreporters: [log, \"csv:/tmp/c.csv\"]\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{"port": "4318", "anomaly-alpha": "0.5", "debug": "false", "history-rollups": "1m:60", "reporters": "log,csv:/tmp/c.csv"}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("ParseYAMLSettings() = %v, want %v", settings, want)
+	}
+
+	if _, err := ParseYAMLSettings([]byte("- port")); err == nil {
+		t.Error("Expected a sequence document to be rejected")
+	}
+}
+
+func TestConfig_WriteSettings(t *testing.T) {
+	cfg, err := Load([]string{"-admin-username", "admin", "-admin-password", "hunter2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := cfg.WriteSettings(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "<redacted>") {
+		t.Errorf("Expected the password to be redacted:\n%s", out)
+	}
+	for _, line := range []string{`admin-username`, `"admin"`, `attribute-key`, `"service.name"`} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %s in output:\n%s", line, out)
+		}
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != len(cfg.Settings())+1 {
+		t.Errorf("Expected a header and a line per setting, got %d lines", len(lines))
+	}
+}
+
+func TestConfig_ReloadChanges(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next, _ := cfg.Patch(map[string]string{"attribute-key": "host.name", "route-key": "k8s.namespace"})
+	changed, err := cfg.ReloadChanges(next)
+	if err != nil || !reflect.DeepEqual(changed, []string{"attribute-key", "route-key"}) {
+		t.Errorf("ReloadChanges() = %v, %v, want [attribute-key route-key]", changed, err)
+	}
+
+	next, _ = cfg.Patch(map[string]string{"attribute-key": "host.name", "port": "4318", "debug": "true"})
+	_, err = cfg.ReloadChanges(next)
+	if !errors.Is(err, ErrRestartRequired) || !strings.Contains(err.Error(), "debug, port") {
+		t.Errorf("Expected a restart to be required for debug and port, got %v", err)
+	}
+}
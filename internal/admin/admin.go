@@ -0,0 +1,216 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// Config configures the admin HTTP server
+type Config struct {
+	// Address is the listen address, e.g. ":9090"
+	Address string
+	// ReadTimeout bounds reading a request, including its body
+	ReadTimeout time.Duration
+	// WriteTimeout bounds writing a response; it also caps CPU profile and trace durations
+	WriteTimeout time.Duration
+	// Username and Password protect every endpoint but the probes with basic
+	// auth; empty disables authentication
+	Username string
+	Password string
+	// TLSCertFile and TLSKeyFile serve HTTPS when set
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// Handlers are the endpoints backed by the owning server
+type Handlers struct {
+	// Metrics serves Prometheus metrics
+	Metrics http.Handler
+	// Ready returns nil when the server accepts traffic, or the reason it does not
+	Ready func() error
+	// API serves the query API under /api/; nil leaves it unmounted
+	API http.Handler
+	// Config serves /api/v1/config, which reads and changes reloadable
+	// settings; nil leaves it unmounted. Without credentials it only reads them.
+	Config http.Handler
+	// Dashboard serves the web dashboard under /dashboard/; nil leaves it unmounted
+	Dashboard http.Handler
+}
+
+// Server serves /metrics, /debug/pprof/*, /healthz, /readyz, /buildinfo, /api/* and /dashboard/*
+type Server struct {
+	config   Config
+	http     *http.Server
+	listener net.Listener
+	logger   *logger.Logger
+}
+
+// New binds the admin listener, so an unavailable address fails startup
+func New(config Config, handlers Handlers, logger *logger.Logger) (*Server, error) {
+	var tlsConfig *tls.Config
+	if config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load admin TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin listener: %w", err)
+	}
+
+	s := &Server{config: config, listener: listener, logger: logger}
+	s.http = &http.Server{
+		Handler:           s.routes(handlers),
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		TLSConfig:         tlsConfig,
+	}
+	return s, nil
+}
+
+func (s *Server) routes(handlers Handlers) http.Handler {
+	protected := http.NewServeMux()
+	protected.Handle("/metrics", handlers.Metrics)
+	protected.HandleFunc("/buildinfo", serveBuildInfo)
+	protected.HandleFunc("/debug/pprof/", pprof.Index)
+	protected.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	protected.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	protected.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	protected.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	if handlers.API != nil {
+		protected.Handle("/api/", handlers.API)
+	}
+	if handlers.Config != nil {
+		protected.Handle("/api/v1/config", s.readOnlyUnlessAuthenticated(handlers.Config))
+	}
+	if handlers.Dashboard != nil {
+		protected.Handle("/dashboard/", handlers.Dashboard)
+	}
+
+	// Probes stay open so orchestrators need no credentials
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := handlers.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	mux.Handle("/", s.authenticate(protected))
+	return mux
+}
+
+// authenticate requires the configured basic auth credentials, if any
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.config.Username == "" && s.config.Password == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(username), []byte(s.config.Username)) == 1
+		passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(s.config.Password)) == 1
+		if !ok || !userMatch || !passwordMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readOnlyUnlessAuthenticated refuses requests that change settings when no
+// credentials are configured, so an open admin port cannot reconfigure the server
+func (s *Server) readOnlyUnlessAuthenticated(next http.Handler) http.Handler {
+	if s.config.Username != "" || s.config.Password != "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "changing settings requires admin credentials", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// ReadBuildInfo returns the build information embedded by the Go toolchain
+func ReadBuildInfo() BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildInfo{Version: "unknown"}
+	}
+
+	b := BuildInfo{GoVersion: info.GoVersion, Path: info.Main.Path, Version: info.Main.Version}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			b.Revision = setting.Value
+		case "vcs.time":
+			b.Time = setting.Value
+		case "vcs.modified":
+			b.Modified = setting.Value == "true"
+		}
+	}
+	return b
+}
+
+func serveBuildInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReadBuildInfo())
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve serves requests until Shutdown is called, after which it returns nil
+func (s *Server) Serve() error {
+	s.logger.Infow("Admin server listening", "address", s.Addr().String(), "tls", s.http.TLSConfig != nil)
+
+	var err error
+	if s.http.TLSConfig != nil {
+		err = s.http.ServeTLS(s.listener, "", "")
+	} else {
+		err = s.http.Serve(s.listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for active requests until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// startTestServer serves an admin server on a loopback port until the test ends
+func startTestServer(t *testing.T, config Config, ready func() error) string {
+	t.Helper()
+
+	testLogger, _ := logger.New(false)
+	config.Address = "127.0.0.1:0"
+	config.ReadTimeout = 5 * time.Second
+	config.WriteTimeout = 5 * time.Second
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test_metric 1\n"))
+	})
+	s, err := New(config, Handlers{Metrics: metrics, Ready: ready}, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+	t.Cleanup(func() {
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected shutdown error: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Expected Serve to return nil after shutdown, got %v", err)
+		}
+	})
+	return "http://" + s.Addr().String()
+}
+
+func get(t *testing.T, url string, auth ...string) (int, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if len(auth) == 2 {
+		req.SetBasicAuth(auth[0], auth[1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestServer_Endpoints(t *testing.T) {
+	base := startTestServer(t, Config{}, func() error { return nil })
+
+	tests := []struct {
+		path     string
+		contains string
+	}{
+		{path: "/metrics", contains: "test_metric 1"},
+		{path: "/healthz", contains: "ok"},
+		{path: "/readyz", contains: "ok"},
+		{path: "/debug/pprof/", contains: "goroutine"},
+		{path: "/buildinfo", contains: "go_version"},
+	}
+
+	for _, tt := range tests {
+		code, body := get(t, base+tt.path)
+		if code != http.StatusOK || !strings.Contains(body, tt.contains) {
+			t.Errorf("GET %s = %d %q, want 200 containing %q", tt.path, code, body, tt.contains)
+		}
+	}
+
+	_, body := get(t, base+"/buildinfo")
+	var info BuildInfo
+	if err := json.Unmarshal([]byte(body), &info); err != nil || info.GoVersion == "" {
+		t.Errorf("Expected build info JSON, got %q (%v)", body, err)
+	}
+}
+
+func TestServer_NotReady(t *testing.T) {
+	base := startTestServer(t, Config{}, func() error { return errors.New("shutting down") })
+
+	code, body := get(t, base+"/readyz")
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "shutting down") {
+		t.Errorf("Expected 503 with the reason, got %d %q", code, body)
+	}
+	if code, _ := get(t, base+"/healthz"); code != http.StatusOK {
+		t.Errorf("Expected liveness to stay OK, got %d", code)
+	}
+}
+
+func TestServer_BasicAuth(t *testing.T) {
+	base := startTestServer(t, Config{Username: "admin", Password: "secret"}, func() error { return nil })
+
+	if code, _ := get(t, base+"/metrics"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", code)
+	}
+	if code, _ := get(t, base+"/debug/pprof/", "admin", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong password, got %d", code)
+	}
+	if code, _ := get(t, base+"/metrics", "admin", "secret"); code != http.StatusOK {
+		t.Errorf("Expected 200 with credentials, got %d", code)
+	}
+	for _, probe := range []string{"/healthz", "/readyz"} {
+		if code, _ := get(t, base+probe); code != http.StatusOK {
+			t.Errorf("Expected %s to need no credentials, got %d", probe, code)
+		}
+	}
+}
+
+func TestServer_API(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+	handlers := Handlers{Metrics: http.NotFoundHandler(), Ready: func() error { return nil }, API: api}
+	s, err := New(Config{Address: "127.0.0.1:0", Username: "admin", Password: "secret"}, handlers, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+	go s.Serve()
+	defer s.Shutdown(context.Background())
+	base := "http://" + s.Addr().String()
+
+	if code, _ := get(t, base+"/api/v1/windows"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the API to require credentials, got %d", code)
+	}
+	if code, body := get(t, base+"/api/v1/windows", "admin", "secret"); code != http.StatusOK || body != "/api/v1/windows" {
+		t.Errorf("Expected the API handler to serve /api/, got %d %q", code, body)
+	}
+}
+
+func TestServer_Config(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	echo := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.Method))
+		})
+	}
+	handlers := Handlers{Metrics: http.NotFoundHandler(), Ready: func() error { return nil }, API: echo("api"), Config: echo("config")}
+	s, err := New(Config{Address: "127.0.0.1:0", Username: "admin", Password: "secret"}, handlers, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+	go s.Serve()
+	defer s.Shutdown(context.Background())
+	url := "http://" + s.Addr().String() + "/api/v1/config"
+
+	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(`{}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected changing settings to require credentials, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, url, strings.NewReader(`{}`))
+	req.SetBasicAuth("admin", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "config PUT" {
+		t.Errorf("Expected the config handler to take precedence over the API, got %q", body)
+	}
+}
+
+func TestServer_ConfigWithoutCredentials(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("config " + r.Method))
+	})
+	handlers := Handlers{Metrics: http.NotFoundHandler(), Ready: func() error { return nil }, Config: echo}
+	s, err := New(Config{Address: "127.0.0.1:0"}, handlers, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+	go s.Serve()
+	defer s.Shutdown(context.Background())
+	url := "http://" + s.Addr().String() + "/api/v1/config"
+
+	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(`{"routes":"x=y:4317"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || strings.Contains(string(body), "config PUT") {
+		t.Errorf("Expected an unauthenticated PUT to be refused, got %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "config GET" {
+		t.Errorf("Expected settings to stay readable without credentials, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestServer_Dashboard(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	dashboard := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+	handlers := Handlers{Metrics: http.NotFoundHandler(), Ready: func() error { return nil }, Dashboard: dashboard}
+	s, err := New(Config{Address: "127.0.0.1:0"}, handlers, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+	go s.Serve()
+	defer s.Shutdown(context.Background())
+
+	if code, body := get(t, "http://"+s.Addr().String()+"/dashboard/"); code != http.StatusOK || body != "/dashboard/" {
+		t.Errorf("Expected the dashboard handler to serve /dashboard/, got %d %q", code, body)
+	}
+}
+
+func TestNew_AddressInUse(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	handlers := Handlers{Metrics: http.NotFoundHandler(), Ready: func() error { return nil }}
+	first, err := New(Config{Address: "127.0.0.1:0"}, handlers, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create admin server: %v", err)
+	}
+	defer first.listener.Close()
+
+	if _, err := New(Config{Address: first.Addr().String()}, handlers, testLogger); err == nil {
+		t.Error("Expected a bind failure to be returned")
+	}
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"otlp-log-parser-assignment/internal/counter"
+)
+
+// SchemaVersion is carried by every response; fields are only added within a version
+const SchemaVersion = "v1"
+
+// Sort orders accepted by the sort parameter
+const (
+	// SortCount orders values by descending record count
+	SortCount = "count"
+	// SortBytes orders values by descending serialized size
+	SortBytes = "bytes"
+	// SortValue orders values alphabetically
+	SortValue = "value"
+)
+
+// ValueCount is a single attribute value's share of a window
+type ValueCount struct {
+	Value      string           `json:"value"`
+	Count      int64            `json:"count"`
+	Bytes      int64            `json:"bytes"`
+	Percentage float64          `json:"percentage"`
+	Severity   map[string]int64 `json:"severity,omitempty"`
+	Distinct   uint64           `json:"distinct,omitempty"`
+}
+
+// Window is a window's totals and per-value counts
+type Window struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	TotalLogs  int64     `json:"total_logs"`
+	TotalBytes int64     `json:"total_bytes"`
+	// DistinctValues is the number of values in the window before top is applied
+	DistinctValues int          `json:"distinct_values"`
+	Values         []ValueCount `json:"values"`
+	// MissedWindows is set on a gap: windows that passed while the server was down
+	MissedWindows int64 `json:"missed_windows,omitempty"`
+}
+
+// CurrentResponse is returned by /api/v1/windows/current
+type CurrentResponse struct {
+	SchemaVersion string `json:"schema_version"`
+	AttributeKey  string `json:"attribute_key"`
+	Window        Window `json:"window"`
+}
+
+// WindowsResponse is returned by /api/v1/windows
+type WindowsResponse struct {
+	SchemaVersion string   `json:"schema_version"`
+	AttributeKey  string   `json:"attribute_key"`
+	Resolution    string   `json:"resolution"`
+	Windows       []Window `json:"windows"`
+}
+
+// Point is a single value's aggregate within one window
+type Point struct {
+	Start    time.Time        `json:"start"`
+	End      time.Time        `json:"end"`
+	Count    int64            `json:"count"`
+	Bytes    int64            `json:"bytes"`
+	Severity map[string]int64 `json:"severity,omitempty"`
+	Distinct uint64           `json:"distinct,omitempty"`
+}
+
+// SeriesResponse is returned by /api/v1/values/{value}/series
+type SeriesResponse struct {
+	SchemaVersion string  `json:"schema_version"`
+	AttributeKey  string  `json:"attribute_key"`
+	Value         string  `json:"value"`
+	Resolution    string  `json:"resolution"`
+	Points        []Point `json:"points"`
+}
+
+// ErrorResponse is returned with every non-2xx status
+type ErrorResponse struct {
+	SchemaVersion string `json:"schema_version"`
+	Error         string `json:"error"`
+}
+
+// errHistoryDisabled is returned by history queries when no history is kept
+var errHistoryDisabled = errors.New("history is disabled (set -history-size)")
+
+// Handler serves the query API over a counter's current window and history
+type Handler struct {
+	counter *counter.WindowCounter
+	mux     *http.ServeMux
+}
+
+// NewHandler creates the query API of wc; responses name the attribute key
+// wc currently counts by
+func NewHandler(wc *counter.WindowCounter) *Handler {
+	h := &Handler{counter: wc, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /api/v1/windows/current", h.current)
+	h.mux.HandleFunc("GET /api/v1/windows", h.windows)
+	h.mux.HandleFunc("GET /api/v1/values/{value}/series", h.series)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// current serves the live partial counts of the window still being filled.
+// Parameters: top, sort.
+func (h *Handler) current(w http.ResponseWriter, r *http.Request) {
+	top, order, err := parseValueParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CurrentResponse{
+		SchemaVersion: SchemaVersion,
+		AttributeKey:  h.counter.AttributeKey(),
+		Window:        NewWindow(h.counter.CurrentWindow(), top, order),
+	})
+}
+
+// windows serves the completed windows overlapping [from, to), oldest first.
+// Parameters: from, to, resolution, top, sort.
+func (h *Handler) windows(w http.ResponseWriter, r *http.Request) {
+	top, order, err := parseValueParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	history, resolution, from, to, ok := h.parseHistoryParams(w, r)
+	if !ok {
+		return
+	}
+
+	windows, err := history.Windows(resolution, from, to)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := WindowsResponse{
+		SchemaVersion: SchemaVersion,
+		AttributeKey:  h.counter.AttributeKey(),
+		Resolution:    resolution.String(),
+		Windows:       make([]Window, len(windows)),
+	}
+	for i, window := range windows {
+		resp.Windows[i] = NewWindow(window, top, order)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// series serves a single value's history over [from, to), oldest first.
+// Parameters: from, to, resolution.
+func (h *Handler) series(w http.ResponseWriter, r *http.Request) {
+	history, resolution, from, to, ok := h.parseHistoryParams(w, r)
+	if !ok {
+		return
+	}
+
+	value := r.PathValue("value")
+	points, err := history.Series(value, resolution, from, to)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := SeriesResponse{
+		SchemaVersion: SchemaVersion,
+		AttributeKey:  h.counter.AttributeKey(),
+		Value:         value,
+		Resolution:    resolution.String(),
+		Points:        make([]Point, len(points)),
+	}
+	for i, p := range points {
+		resp.Points[i] = Point(p)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseHistoryParams returns the history and the from, to and resolution
+// parameters, writing an error response and returning false when they are invalid
+func (h *Handler) parseHistoryParams(w http.ResponseWriter, r *http.Request) (*counter.History, time.Duration, time.Time, time.Time, bool) {
+	history := h.counter.History()
+	if history == nil {
+		writeError(w, http.StatusNotFound, errHistoryDisabled)
+		return nil, 0, time.Time{}, time.Time{}, false
+	}
+
+	query := r.URL.Query()
+	from, err := parseTime(query.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid from: %w", err))
+		return nil, 0, time.Time{}, time.Time{}, false
+	}
+	to, err := parseTime(query.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid to: %w", err))
+		return nil, 0, time.Time{}, time.Time{}, false
+	}
+
+	resolution := history.Resolutions()[0]
+	if s := query.Get("resolution"); s != "" {
+		if resolution, err = time.ParseDuration(s); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid resolution: %w", err))
+			return nil, 0, time.Time{}, time.Time{}, false
+		}
+	}
+
+	return history, resolution, from, to, true
+}
+
+// parseTime parses an RFC 3339 time or Unix seconds; empty is the zero time
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseValueParams parses the top and sort parameters
+func parseValueParams(r *http.Request) (int, string, error) {
+	query := r.URL.Query()
+
+	top := 0
+	if s := query.Get("top"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, "", fmt.Errorf("invalid top %q: must be a non-negative integer", s)
+		}
+		top = n
+	}
+
+	order := query.Get("sort")
+	switch order {
+	case "":
+		order = SortCount
+	case SortCount, SortBytes, SortValue:
+	default:
+		return 0, "", fmt.Errorf("invalid sort %q (expected %s, %s or %s)", order, SortCount, SortBytes, SortValue)
+	}
+
+	return top, order, nil
+}
+
+// NewWindow converts a counter window, keeping the first top values in order (0 keeps all)
+func NewWindow(window *counter.Window, top int, order string) Window {
+	total := window.Total()
+	values := make([]ValueCount, 0, len(window.Values))
+	for value, stats := range window.Values {
+		vc := ValueCount{
+			Value:    value,
+			Count:    stats.Count,
+			Bytes:    stats.Bytes,
+			Severity: stats.Severity.Map(),
+			Distinct: stats.DistinctEstimate(),
+		}
+		if total > 0 {
+			vc.Percentage = float64(stats.Count) / float64(total) * 100
+		}
+		values = append(values, vc)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		a, b := values[i], values[j]
+		switch {
+		case order == SortCount && a.Count != b.Count:
+			return a.Count > b.Count
+		case order == SortBytes && a.Bytes != b.Bytes:
+			return a.Bytes > b.Bytes
+		}
+		return a.Value < b.Value
+	})
+
+	distinct := len(values)
+	if top > 0 && len(values) > top {
+		values = values[:top]
+	}
+
+	return Window{
+		Start:          window.Start,
+		End:            window.End,
+		TotalLogs:      total,
+		TotalBytes:     window.TotalBytes(),
+		DistinctValues: distinct,
+		Values:         values,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{SchemaVersion: SchemaVersion, Error: err.Error()})
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+func newTestCounter(t *testing.T, history bool) *counter.WindowCounter {
+	t.Helper()
+
+	testLogger, _ := logger.New(false)
+	opts := []counter.Option{counter.WithAttributeKey("service.name")}
+	if history {
+		opts = append(opts, counter.WithHistory(counter.NewHistory(time.Minute, 10, nil, 0)))
+	}
+	return counter.NewWindowCounter(time.Minute, testLogger, false, opts...)
+}
+
+func observe(wc *counter.WindowCounter) {
+	wc.ObserveBatch([]counter.Observation{
+		{Value: "checkout", Bytes: 10},
+		{Value: "checkout", Bytes: 10},
+		{Value: "checkout", Bytes: 10},
+		{Value: "cart", Bytes: 500},
+		{Value: "auth", Bytes: 1},
+		{Value: "auth", Bytes: 1},
+	})
+}
+
+func get(t *testing.T, h http.Handler, url string, v interface{}) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected a JSON content type for %s, got %q", url, ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to decode %s response %q: %v", url, rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func values(window Window) string {
+	names := make([]string, len(window.Values))
+	for i, v := range window.Values {
+		names[i] = v.Value
+	}
+	return strings.Join(names, ",")
+}
+
+func TestHandler_Current(t *testing.T) {
+	wc := newTestCounter(t, false)
+	observe(wc)
+	h := NewHandler(wc)
+
+	var resp CurrentResponse
+	if code := get(t, h, "/api/v1/windows/current", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.SchemaVersion != SchemaVersion || resp.AttributeKey != "service.name" {
+		t.Errorf("Unexpected envelope: %+v", resp)
+	}
+	if resp.Window.TotalLogs != 6 || resp.Window.TotalBytes != 532 || resp.Window.DistinctValues != 3 {
+		t.Errorf("Unexpected totals: %+v", resp.Window)
+	}
+	if got := values(resp.Window); got != "checkout,auth,cart" {
+		t.Errorf("Expected values by descending count, got %s", got)
+	}
+	if got := resp.Window.Values[0].Percentage; got != 50 {
+		t.Errorf("Expected checkout to be 50%% of records, got %v", got)
+	}
+}
+
+func TestHandler_CurrentTopAndSort(t *testing.T) {
+	wc := newTestCounter(t, false)
+	observe(wc)
+	h := NewHandler(wc)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "?sort=bytes", want: "cart,checkout,auth"},
+		{query: "?sort=value", want: "auth,cart,checkout"},
+		{query: "?sort=count&top=1", want: "checkout"},
+		{query: "?top=0", want: "checkout,auth,cart"},
+	}
+
+	for _, tt := range tests {
+		var resp CurrentResponse
+		get(t, h, "/api/v1/windows/current"+tt.query, &resp)
+		if got := values(resp.Window); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.query, tt.want, got)
+		}
+		if resp.Window.DistinctValues != 3 {
+			t.Errorf("%s: expected distinct values before top, got %d", tt.query, resp.Window.DistinctValues)
+		}
+	}
+}
+
+func TestHandler_Windows(t *testing.T) {
+	wc := newTestCounter(t, true)
+	observe(wc)
+	wc.Stop()
+	h := NewHandler(wc)
+
+	var resp WindowsResponse
+	if code := get(t, h, "/api/v1/windows?top=2", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.SchemaVersion != SchemaVersion || resp.Resolution != "1m0s" || len(resp.Windows) != 1 {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if got := values(resp.Windows[0]); got != "checkout,auth" {
+		t.Errorf("Expected the top 2 values, got %s", got)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	get(t, h, "/api/v1/windows?from="+future, &resp)
+	if len(resp.Windows) != 0 {
+		t.Errorf("Expected no windows after %s, got %d", future, len(resp.Windows))
+	}
+}
+
+func TestHandler_Series(t *testing.T) {
+	wc := newTestCounter(t, true)
+	observe(wc)
+	wc.Stop()
+	h := NewHandler(wc)
+
+	var resp SeriesResponse
+	if code := get(t, h, "/api/v1/values/checkout/series", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.Value != "checkout" || len(resp.Points) != 1 || resp.Points[0].Count != 3 || resp.Points[0].Bytes != 30 {
+		t.Errorf("Unexpected series: %+v", resp)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := NewHandler(newTestCounter(t, true))
+	disabled := NewHandler(newTestCounter(t, false))
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		url      string
+		code     int
+		contains string
+	}{
+		{name: "bad top", handler: h, url: "/api/v1/windows/current?top=-1", code: http.StatusBadRequest, contains: "invalid top"},
+		{name: "bad sort", handler: h, url: "/api/v1/windows?sort=size", code: http.StatusBadRequest, contains: "invalid sort"},
+		{name: "bad from", handler: h, url: "/api/v1/windows?from=yesterday", code: http.StatusBadRequest, contains: "invalid from"},
+		{name: "bad resolution", handler: h, url: "/api/v1/values/cart/series?resolution=fast", code: http.StatusBadRequest, contains: "invalid resolution"},
+		{name: "unknown resolution", handler: h, url: "/api/v1/windows?resolution=1h", code: http.StatusBadRequest, contains: "resolution"},
+		{name: "history disabled", handler: disabled, url: "/api/v1/windows", code: http.StatusNotFound, contains: "history is disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp ErrorResponse
+			if code := get(t, tt.handler, tt.url, &resp); code != tt.code {
+				t.Errorf("Expected %d, got %d", tt.code, code)
+			}
+			if resp.SchemaVersion != SchemaVersion || !strings.Contains(resp.Error, tt.contains) {
+				t.Errorf("Expected an error containing %q, got %+v", tt.contains, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"otlp-log-parser-assignment/config"
+)
+
+// maxConfigBytes bounds the size of a PUT /api/v1/config document
+const maxConfigBytes = 1 << 20
+
+// Reconfigurer reads and changes the settings that can change at runtime
+type Reconfigurer interface {
+	// ReloadableSettings returns the current values of config.ReloadableSettings
+	ReloadableSettings() map[string]string
+	// ApplySettings applies settings keyed by flag name over the current
+	// configuration and returns the names of the settings that changed
+	ApplySettings(settings map[string]string) ([]string, error)
+}
+
+// ConfigResponse is returned by /api/v1/config
+type ConfigResponse struct {
+	SchemaVersion string            `json:"schema_version"`
+	Settings      map[string]string `json:"settings"`
+	// Changed names the settings a PUT changed
+	Changed []string `json:"changed,omitempty"`
+}
+
+// ConfigHandler serves the reloadable settings and applies changes to them
+type ConfigHandler struct {
+	reconfigurer Reconfigurer
+	mux          *http.ServeMux
+}
+
+// NewConfigHandler creates the /api/v1/config endpoint of r
+func NewConfigHandler(r Reconfigurer) *ConfigHandler {
+	h := &ConfigHandler{reconfigurer: r, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /api/v1/config", h.get)
+	h.mux.HandleFunc("PUT /api/v1/config", h.put)
+	return h
+}
+
+func (h *ConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *ConfigHandler) get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ConfigResponse{
+		SchemaVersion: SchemaVersion,
+		Settings:      h.reconfigurer.ReloadableSettings(),
+	})
+}
+
+// put applies a JSON object of settings in the config file format. Invalid
+// settings are a 400 and settings that need a restart a 409; nothing is
+// applied in either case.
+func (h *ConfigHandler) put(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read settings: %w", err))
+		return
+	}
+	settings, err := config.ParseSettings(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	changed, err := h.reconfigurer.ApplySettings(settings)
+	switch {
+	case errors.Is(err, config.ErrInvalidSettings):
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case errors.Is(err, config.ErrRestartRequired):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ConfigResponse{
+		SchemaVersion: SchemaVersion,
+		Settings:      h.reconfigurer.ReloadableSettings(),
+		Changed:       changed,
+	})
+}
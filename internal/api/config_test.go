@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otlp-log-parser-assignment/config"
+)
+
+// fakeReconfigurer applies settings to a configuration the way the server does
+type fakeReconfigurer struct {
+	config *config.Config
+}
+
+func (f *fakeReconfigurer) ReloadableSettings() map[string]string {
+	all := f.config.Settings()
+	settings := make(map[string]string, len(config.ReloadableSettings))
+	for _, name := range config.ReloadableSettings {
+		settings[name] = all[name]
+	}
+	return settings
+}
+
+func (f *fakeReconfigurer) ApplySettings(settings map[string]string) ([]string, error) {
+	next, err := f.config.Patch(settings)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := f.config.ReloadChanges(next)
+	if err != nil {
+		return nil, err
+	}
+	f.config = next
+	return changed, nil
+}
+
+func put(t *testing.T, h http.Handler, body string, v interface{}) int {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/config", strings.NewReader(body)))
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code
+}
+
+func TestConfigHandler_Get(t *testing.T) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	h := NewConfigHandler(&fakeReconfigurer{config: cfg})
+
+	var resp ConfigResponse
+	if code := get(t, h, "/api/v1/config", &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.Settings["attribute-key"] != "service.name" || resp.Settings["window-duration"] != "10s" {
+		t.Errorf("Unexpected settings: %v", resp.Settings)
+	}
+	if _, ok := resp.Settings["admin-password"]; ok || len(resp.Settings) != len(config.ReloadableSettings) {
+		t.Errorf("Expected only the reloadable settings, got %v", resp.Settings)
+	}
+}
+
+func TestConfigHandler_Put(t *testing.T) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	r := &fakeReconfigurer{config: cfg}
+	h := NewConfigHandler(r)
+
+	var resp ConfigResponse
+	if code := put(t, h, `{"attribute-key": "k8s.namespace", "window-duration": "30s"}`, &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if strings.Join(resp.Changed, ",") != "attribute-key,window-duration" || resp.Settings["attribute-key"] != "k8s.namespace" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		contains string
+	}{
+		{name: "malformed", body: `attribute-key=host.name`, code: http.StatusBadRequest, contains: "JSON object"},
+		{name: "unknown setting", body: `{"window": "1m"}`, code: http.StatusBadRequest, contains: "unknown setting"},
+		{name: "invalid", body: `{"attribute-key": ""}`, code: http.StatusBadRequest, contains: "attribute-key cannot be empty"},
+		{name: "restart required", body: `{"attribute-key": "host.name", "port": 4318}`, code: http.StatusConflict, contains: "port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp ErrorResponse
+			if code := put(t, h, tt.body, &resp); code != tt.code || !strings.Contains(resp.Error, tt.contains) {
+				t.Errorf("Expected %d containing %q, got %d %q", tt.code, tt.contains, code, resp.Error)
+			}
+		})
+	}
+	if r.config.AttributeKey != "k8s.namespace" {
+		t.Errorf("Expected rejected documents to change nothing, got %q", r.config.AttributeKey)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"fmt"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 )
 
 const (
 	UnknownValue = "unknown"
+
+	// OverflowValue is the bucket that collects attribute values beyond a cardinality cap
+	OverflowValue = "__overflow__"
 )
 
 // Extractor extracts attribute values from OTLP data structures
@@ -33,6 +38,40 @@ func (e *Extractor) ExtractValue(attributes []*commonpb.KeyValue) string {
 	return UnknownValue
 }
 
+// ResourceValue extracts the attribute value of a resource, or UnknownValue.
+// A nil Extractor returns UnknownValue.
+func (e *Extractor) ResourceValue(resource *resourcepb.Resource) string {
+	if e == nil || resource == nil {
+		return UnknownValue
+	}
+	return e.ExtractValue(resource.Attributes)
+}
+
+// ScopeValue extracts the attribute value of an instrumentation scope, or UnknownValue.
+// A nil Extractor returns UnknownValue.
+func (e *Extractor) ScopeValue(scope *commonpb.InstrumentationScope) string {
+	if e == nil || scope == nil {
+		return UnknownValue
+	}
+	return e.ExtractValue(scope.Attributes)
+}
+
+// RecordValue resolves the attribute value of a log record with priority
+// Log > Scope > Resource, given the values of its scope and resource
+func (e *Extractor) RecordValue(record *logspb.LogRecord, scopeValue, resourceValue string) string {
+	value := UnknownValue
+	if e != nil && record != nil {
+		value = e.ExtractValue(record.Attributes)
+	}
+	if value != UnknownValue {
+		return value
+	}
+	if scopeValue != UnknownValue {
+		return scopeValue
+	}
+	return resourceValue
+}
+
 // arrayToSlice converts ArrayValue to Go slice
 func (e *Extractor) arrayToSlice(arr *commonpb.ArrayValue) []interface{} {
 	if arr == nil {
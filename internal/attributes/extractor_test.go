@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 )
 
 func TestExtractor_ExtractValue(t *testing.T) {
@@ -200,3 +202,44 @@ func TestExtractor_ComplexTypes(t *testing.T) {
 		t.Errorf("Expected %s, got %s", expected, result)
 	}
 }
+
+func TestExtractor_RecordValue(t *testing.T) {
+	attrs := func(value string) []*commonpb.KeyValue {
+		return []*commonpb.KeyValue{{
+			Key:   "service.name",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+		}}
+	}
+	e := NewExtractor("service.name")
+
+	resourceValue := e.ResourceValue(&resourcepb.Resource{Attributes: attrs("resource")})
+	scopeValue := e.ScopeValue(&commonpb.InstrumentationScope{Attributes: attrs("scope")})
+	if resourceValue != "resource" || scopeValue != "scope" {
+		t.Fatalf("Expected resource and scope values, got %q and %q", resourceValue, scopeValue)
+	}
+
+	tests := []struct {
+		name          string
+		record        *logspb.LogRecord
+		scopeValue    string
+		resourceValue string
+		want          string
+	}{
+		{name: "log wins", record: &logspb.LogRecord{Attributes: attrs("log")}, scopeValue: scopeValue, resourceValue: resourceValue, want: "log"},
+		{name: "scope over resource", record: &logspb.LogRecord{}, scopeValue: scopeValue, resourceValue: resourceValue, want: "scope"},
+		{name: "resource", record: &logspb.LogRecord{}, scopeValue: UnknownValue, resourceValue: resourceValue, want: "resource"},
+		{name: "unknown", record: nil, scopeValue: UnknownValue, resourceValue: UnknownValue, want: UnknownValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.RecordValue(tt.record, tt.scopeValue, tt.resourceValue); got != tt.want {
+				t.Errorf("RecordValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var none *Extractor
+	if got := none.RecordValue(&logspb.LogRecord{Attributes: attrs("log")}, UnknownValue, none.ResourceValue(nil)); got != UnknownValue {
+		t.Errorf("Expected a nil extractor to resolve to UnknownValue, got %q", got)
+	}
+}
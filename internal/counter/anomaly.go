@@ -0,0 +1,184 @@
+package counter
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"otlp-log-parser-assignment/internal/report"
+)
+
+const (
+	// AnomalySpike marks a window whose count is far above its baseline
+	AnomalySpike = "spike"
+	// AnomalyDrop marks a window whose count is far below its baseline
+	AnomalyDrop = "drop"
+
+	baselineEWMA     = "ewma"
+	baselineSeasonal = "seasonal"
+
+	// forgetBelow is the baseline mean under which a value that is no longer
+	// seen stops being tracked
+	forgetBelow = 0.5
+)
+
+// AnomalyConfig configures per-value spike and drop detection
+type AnomalyConfig struct {
+	// Threshold is the absolute z-score at or above which a window is anomalous
+	Threshold float64
+	// Alpha is the EWMA smoothing factor in (0, 1]; higher reacts faster
+	Alpha float64
+	// WarmupWindows is the number of windows a value must be seen in before it is scored
+	WarmupWindows int
+	// Seasonality, when positive, compares each window with the one a season
+	// earlier in history instead of the EWMA mean, if history still holds it
+	Seasonality time.Duration
+}
+
+// Anomaly describes a value whose window count deviates from its baseline
+type Anomaly = report.Anomaly
+
+// baseline is the running EWMA mean and variance of a value's window counts
+type baseline struct {
+	mean     float64
+	variance float64
+	windows  int
+}
+
+// update folds a new observation into the baseline
+func (b *baseline) update(x, alpha float64) {
+	if b.windows == 0 {
+		b.mean = x
+		b.variance = 0
+	} else {
+		diff := x - b.mean
+		incr := alpha * diff
+		b.mean += incr
+		b.variance = (1 - alpha) * (b.variance + diff*incr)
+	}
+	b.windows++
+}
+
+// anomalyDetector scores each completed window against per-value baselines
+type anomalyDetector struct {
+	mu             sync.Mutex
+	config         AnomalyConfig
+	baselines      map[string]*baseline
+	history        *History
+	windowDuration time.Duration
+}
+
+func newAnomalyDetector(config AnomalyConfig, history *History, windowDuration time.Duration) *anomalyDetector {
+	return &anomalyDetector{
+		config:         config,
+		baselines:      make(map[string]*baseline),
+		history:        history,
+		windowDuration: windowDuration,
+	}
+}
+
+// setWindowDuration changes the window length seasonal baselines look up in history
+func (d *anomalyDetector) setWindowDuration(windowDuration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.windowDuration = windowDuration
+}
+
+// reset forgets every baseline, so values warm up again
+func (d *anomalyDetector) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.baselines = make(map[string]*baseline)
+}
+
+// score returns the z-score of every warmed-up value in w, plus the anomalies
+// among them, then folds w into the baselines. Values with a baseline that are
+// missing from w are scored as zero so drops are detected. Must be called
+// before w is added to history.
+func (d *anomalyDetector) score(w *Window) (map[string]float64, []Anomaly) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	scores := make(map[string]float64)
+	var anomalies []Anomaly
+
+	values := make(map[string]struct{}, len(w.Values)+len(d.baselines))
+	for value := range w.Values {
+		values[value] = struct{}{}
+	}
+	for value := range d.baselines {
+		values[value] = struct{}{}
+	}
+
+	for value := range values {
+		count := int64(0)
+		if stats, ok := w.Values[value]; ok {
+			count = stats.Count
+		}
+		x := float64(count)
+
+		b, ok := d.baselines[value]
+		if !ok {
+			b = &baseline{}
+			d.baselines[value] = b
+		}
+
+		if b.windows >= d.config.WarmupWindows && b.windows > 0 {
+			expected, kind := b.mean, baselineEWMA
+			if seasonal, ok := d.seasonalExpectation(value, w.Start); ok {
+				expected, kind = seasonal, baselineSeasonal
+			}
+
+			// Poisson noise floors the deviation so quiet, steady values are not flagged for tiny changes
+			stddev := math.Max(math.Sqrt(b.variance), math.Sqrt(math.Max(expected, 1)))
+			z := (x - expected) / stddev
+			scores[value] = z
+
+			if math.Abs(z) >= d.config.Threshold {
+				direction := AnomalySpike
+				if z < 0 {
+					direction = AnomalyDrop
+				}
+				anomalies = append(anomalies, Anomaly{
+					Value:     value,
+					Count:     count,
+					Expected:  expected,
+					ZScore:    z,
+					Direction: direction,
+					Baseline:  kind,
+				})
+			}
+		}
+
+		b.update(x, d.config.Alpha)
+		if count == 0 && b.mean < forgetBelow {
+			delete(d.baselines, value)
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].ZScore) > math.Abs(anomalies[j].ZScore)
+	})
+
+	return scores, anomalies
+}
+
+// seasonalExpectation returns the value's count in the base window that
+// started one season before start, if history still retains it
+func (d *anomalyDetector) seasonalExpectation(value string, start time.Time) (float64, bool) {
+	if d.config.Seasonality <= 0 || d.history == nil {
+		return 0, false
+	}
+
+	// Windows drift slightly from the ticker, so accept any window starting within half a window
+	at := start.Add(-d.config.Seasonality)
+	points, err := d.history.Series(value, d.windowDuration, at.Add(-d.windowDuration/2), at.Add(d.windowDuration/2))
+	if err != nil || len(points) == 0 {
+		return 0, false
+	}
+
+	return float64(points[len(points)-1].Count), true
+}
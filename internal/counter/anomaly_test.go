@@ -0,0 +1,183 @@
+package counter
+
+import (
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+var testAnomalyConfig = AnomalyConfig{Threshold: 3, Alpha: 0.3, WarmupWindows: 3}
+
+// feed scores a sequence of windows of duration d starting at base and returns the last result
+func feed(d *anomalyDetector, base time.Time, duration time.Duration, counts []map[string]int64) (map[string]float64, []Anomaly) {
+	var scores map[string]float64
+	var anomalies []Anomaly
+	for i, c := range counts {
+		scores, anomalies = d.score(testWindow(base.Add(time.Duration(i)*duration), duration, c))
+	}
+	return scores, anomalies
+}
+
+func TestAnomalyDetector_Spike(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	d := newAnomalyDetector(testAnomalyConfig, nil, 10*time.Second)
+
+	_, anomalies := feed(d, base, 10*time.Second, []map[string]int64{
+		{"a": 100, "b": 50}, {"a": 104, "b": 48}, {"a": 98, "b": 52}, {"a": 101, "b": 50},
+		{"a": 500, "b": 51},
+	})
+
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %+v", anomalies)
+	}
+	got := anomalies[0]
+	if got.Value != "a" || got.Direction != AnomalySpike || got.Count != 500 || got.Baseline != baselineEWMA {
+		t.Errorf("Unexpected anomaly %+v", got)
+	}
+	if got.ZScore < testAnomalyConfig.Threshold {
+		t.Errorf("Expected z-score of at least %v, got %v", testAnomalyConfig.Threshold, got.ZScore)
+	}
+}
+
+func TestAnomalyDetector_DropToZero(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	d := newAnomalyDetector(testAnomalyConfig, nil, 10*time.Second)
+
+	scores, anomalies := feed(d, base, 10*time.Second, []map[string]int64{
+		{"a": 100, "b": 50}, {"a": 100, "b": 50}, {"a": 100, "b": 50},
+		{"b": 50},
+	})
+
+	if len(anomalies) != 1 || anomalies[0].Value != "a" || anomalies[0].Direction != AnomalyDrop || anomalies[0].Count != 0 {
+		t.Fatalf("Expected 'a' to be reported as dropping to zero, got %+v", anomalies)
+	}
+	if scores["a"] >= 0 {
+		t.Errorf("Expected a negative score for 'a', got %v", scores["a"])
+	}
+}
+
+func TestAnomalyDetector_Warmup(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	d := newAnomalyDetector(testAnomalyConfig, nil, 10*time.Second)
+
+	scores, anomalies := feed(d, base, 10*time.Second, []map[string]int64{
+		{"a": 100}, {"a": 100}, {"a": 900},
+	})
+
+	if len(scores) != 0 || len(anomalies) != 0 {
+		t.Errorf("Expected no scores during warm-up, got %v and %+v", scores, anomalies)
+	}
+}
+
+func TestAnomalyDetector_ForgetsGoneValues(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	d := newAnomalyDetector(AnomalyConfig{Threshold: 3, Alpha: 0.5, WarmupWindows: 1}, nil, 10*time.Second)
+
+	counts := []map[string]int64{{"a": 4}}
+	for i := 0; i < 10; i++ {
+		counts = append(counts, map[string]int64{})
+	}
+	feed(d, base, 10*time.Second, counts)
+
+	if _, ok := d.baselines["a"]; ok {
+		t.Errorf("Expected the baseline of a value gone for good to be dropped")
+	}
+}
+
+func TestAnomalyDetector_SeasonalBaseline(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 10, nil, 0)
+	d := newAnomalyDetector(AnomalyConfig{Threshold: 3, Alpha: 0.3, WarmupWindows: 3, Seasonality: 40 * time.Second}, h, 10*time.Second)
+
+	// A burst in the first window repeats exactly one season later
+	counts := []map[string]int64{{"a": 500}, {"a": 100}, {"a": 100}, {"a": 100}, {"a": 500}}
+	var anomalies []Anomaly
+	var scores map[string]float64
+	for i, c := range counts {
+		w := testWindow(base.Add(time.Duration(i)*10*time.Second), 10*time.Second, c)
+		scores, anomalies = d.score(w)
+		h.Add(w)
+	}
+
+	if len(anomalies) != 0 {
+		t.Errorf("Expected the seasonal burst not to be an anomaly, got %+v", anomalies)
+	}
+	if scores["a"] != 0 {
+		t.Errorf("Expected a zero score against the seasonal baseline, got %v", scores["a"])
+	}
+}
+
+func TestWindowCounter_ReportsAnomalies(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, true, WithAnomalyDetection(AnomalyConfig{Threshold: 3, Alpha: 0.3, WarmupWindows: 1}))
+
+	wc.IncrementBatch([]string{"a", "a", "a", "a", "a", "a", "a", "a", "a"})
+	wc.reportAndReset()
+	// 'a' vanishes entirely; the otherwise empty window must still be scored
+	wc.reportAndReset()
+
+	if _, ok := wc.anomalies.baselines["a"]; !ok {
+		t.Fatalf("Expected 'a' to keep its baseline after a single drop")
+	}
+	if got := wc.anomalies.baselines["a"].windows; got != 2 {
+		t.Errorf("Expected the baseline to cover 2 windows, got %d", got)
+	}
+}
+
+func TestWindowCounter_ReconfiguredWindowNotScored(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	rec := &recordingReporter{}
+	wc := NewWindowCounter(time.Second, testLogger, false,
+		WithAttributeKey("service.name"),
+		WithAnomalyDetection(AnomalyConfig{Threshold: 3, Alpha: 0.3, WarmupWindows: 1}),
+		WithReporters(rec),
+	)
+	for i := 0; i < 3; i++ {
+		wc.IncrementBatch([]string{"a", "a", "a", "a", "a", "a", "a", "a", "a"})
+		wc.reportAndReset()
+	}
+
+	// The window cut short by the reconfiguration holds a single record
+	wc.Increment("a")
+	wc.Reconfigure("service.name", time.Second)
+
+	r := rec.reports[len(rec.reports)-1]
+	if !r.Reconfigured || len(r.Anomalies) != 0 {
+		t.Errorf("Expected the reconfigured window to be reported without anomalies, got %+v", r)
+	}
+	if got := wc.anomalies.baselines["a"].windows; got != 3 {
+		t.Errorf("Expected the reconfigured window kept out of the baseline, got %d windows", got)
+	}
+}
+
+func TestWindowCounter_ReconfigureResetsBaselines(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		duration time.Duration
+		reset    bool
+	}{
+		{name: "same settings", key: "service.name", duration: time.Second, reset: false},
+		{name: "attribute key", key: "k8s.namespace", duration: time.Second, reset: true},
+		{name: "window duration", key: "service.name", duration: 2 * time.Second, reset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testLogger, _ := logger.New(false)
+			wc := NewWindowCounter(time.Second, testLogger, false,
+				WithAttributeKey("service.name"),
+				WithAnomalyDetection(AnomalyConfig{Threshold: 3, Alpha: 0.3, WarmupWindows: 1}),
+			)
+			wc.IncrementBatch([]string{"a", "a", "a"})
+			wc.reportAndReset()
+
+			wc.Reconfigure(tt.key, tt.duration)
+
+			if _, kept := wc.anomalies.baselines["a"]; kept == tt.reset {
+				t.Errorf("Expected baselines reset=%v, got %d baselines", tt.reset, len(wc.anomalies.baselines))
+			}
+		})
+	}
+}
@@ -0,0 +1,686 @@
+package counter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"otlp-log-parser-assignment/internal/report"
+	"otlp-log-parser-assignment/internal/severity"
+	"otlp-log-parser-assignment/internal/sketch"
+)
+
+const (
+	checkpointVersion = 1
+	snapshotFile      = "snapshot.json"
+	walPrefix         = "wal-"
+	walSuffix         = ".log"
+)
+
+// walRecord is a single write-ahead log entry: a batch of observations, a
+// window close or a reset of the since-reset totals
+type walRecord struct {
+	Time         time.Time        `json:"t"`
+	Observations []walObservation `json:"obs,omitempty"`
+	Close        bool             `json:"close,omitempty"`
+	Reset        bool             `json:"reset,omitempty"`
+}
+
+// walObservation is an Observation as written to the write-ahead log
+type walObservation struct {
+	Value    string          `json:"v"`
+	Distinct string          `json:"d,omitempty"`
+	Bytes    int64           `json:"b,omitempty"`
+	Severity severity.Bucket `json:"s,omitempty"`
+}
+
+func toWALObservations(observations []Observation) []walObservation {
+	records := make([]walObservation, len(observations))
+	for i, obs := range observations {
+		records[i] = walObservation{Value: obs.Value, Distinct: obs.Distinct, Bytes: obs.Bytes, Severity: obs.Severity}
+	}
+	return records
+}
+
+func fromWALObservations(records []walObservation) []Observation {
+	observations := make([]Observation, len(records))
+	for i, rec := range records {
+		observations[i] = Observation{Value: rec.Value, Distinct: rec.Distinct, Bytes: rec.Bytes, Severity: rec.Severity}
+	}
+	return observations
+}
+
+// snapshot is the persisted counter state. WALSeq is the first write-ahead log
+// segment holding increments made after the snapshot was taken.
+type snapshot struct {
+	Version        int                          `json:"version"`
+	TakenAt        time.Time                    `json:"taken_at"`
+	WALSeq         uint64                       `json:"wal_seq"`
+	AttributeKey   string                       `json:"attribute_key"`
+	WindowDuration time.Duration                `json:"window_duration"`
+	WindowStart    time.Time                    `json:"window_start"`
+	TotalWindows   int64                        `json:"total_windows"`
+	Current        map[string]persistedStats    `json:"current"`
+	Overflowed     []byte                       `json:"overflowed,omitempty"`
+	Lifetime       persistedWindow              `json:"lifetime"`
+	SinceReset     persistedWindow              `json:"since_reset"`
+	History        []persistedLevel             `json:"history,omitempty"`
+	Baselines      map[string]persistedBaseline `json:"baselines,omitempty"`
+}
+
+type persistedStats struct {
+	Count     int64           `json:"count"`
+	Bytes     int64           `json:"bytes,omitempty"`
+	Severity  severity.Counts `json:"severity"`
+	Distinct  []byte          `json:"distinct,omitempty"`
+	FirstSeen time.Time       `json:"first_seen"`
+	LastSeen  time.Time       `json:"last_seen"`
+}
+
+type persistedWindow struct {
+	Start  time.Time                 `json:"start"`
+	End    time.Time                 `json:"end"`
+	Values map[string]persistedStats `json:"values"`
+}
+
+type persistedLevel struct {
+	Resolution time.Duration     `json:"resolution"`
+	Windows    []persistedWindow `json:"windows"`
+	Pending    *persistedWindow  `json:"pending,omitempty"`
+}
+
+type persistedBaseline struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Windows  int     `json:"windows"`
+}
+
+func persistValues(values map[string]*ValueStats) map[string]persistedStats {
+	persisted := make(map[string]persistedStats, len(values))
+	for value, stats := range values {
+		p := persistedStats{
+			Count:     stats.Count,
+			Bytes:     stats.Bytes,
+			Severity:  stats.Severity,
+			FirstSeen: stats.FirstSeen,
+			LastSeen:  stats.LastSeen,
+		}
+		if stats.Distinct != nil {
+			p.Distinct, _ = stats.Distinct.MarshalBinary()
+		}
+		persisted[value] = p
+	}
+	return persisted
+}
+
+func restoreValues(persisted map[string]persistedStats) (map[string]*ValueStats, error) {
+	values := make(map[string]*ValueStats, len(persisted))
+	for value, p := range persisted {
+		stats := &ValueStats{
+			Count:     p.Count,
+			Bytes:     p.Bytes,
+			Severity:  p.Severity,
+			FirstSeen: p.FirstSeen,
+			LastSeen:  p.LastSeen,
+		}
+		if len(p.Distinct) > 0 {
+			stats.Distinct = &sketch.HyperLogLog{}
+			if err := stats.Distinct.UnmarshalBinary(p.Distinct); err != nil {
+				return nil, fmt.Errorf("value %q: %w", value, err)
+			}
+		}
+		values[value] = stats
+	}
+	return values, nil
+}
+
+func persistWindow(w *Window) persistedWindow {
+	return persistedWindow{Start: w.Start, End: w.End, Values: persistValues(w.Values)}
+}
+
+func restoreWindow(p persistedWindow) (*Window, error) {
+	values, err := restoreValues(p.Values)
+	if err != nil {
+		return nil, err
+	}
+	return &Window{Start: p.Start, End: p.End, Values: values}, nil
+}
+
+// persist returns the retained windows of every level
+func (h *History) persist() []persistedLevel {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	levels := make([]persistedLevel, 0, len(h.levels))
+	for _, level := range h.levels {
+		p := persistedLevel{Resolution: level.resolution}
+		level.windows.each(func(w *Window) {
+			p.Windows = append(p.Windows, persistWindow(w))
+		})
+		if level.pending != nil {
+			pending := persistWindow(level.pending)
+			p.Pending = &pending
+		}
+		levels = append(levels, p)
+	}
+	return levels
+}
+
+// restore refills the levels whose resolution is still configured. Windows
+// beyond a level's current retention are dropped, oldest first.
+func (h *History) restore(levels []persistedLevel) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, p := range levels {
+		level, err := h.level(p.Resolution)
+		if err != nil {
+			continue
+		}
+		for _, pw := range p.Windows {
+			w, err := restoreWindow(pw)
+			if err != nil {
+				return err
+			}
+			level.windows.push(w)
+		}
+		if p.Pending != nil && level != h.levels[0] {
+			if level.pending, err = restoreWindow(*p.Pending); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// persist returns the baselines of every tracked value
+func (d *anomalyDetector) persist() map[string]persistedBaseline {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	baselines := make(map[string]persistedBaseline, len(d.baselines))
+	for value, b := range d.baselines {
+		baselines[value] = persistedBaseline{Mean: b.mean, Variance: b.variance, Windows: b.windows}
+	}
+	return baselines
+}
+
+func (d *anomalyDetector) restore(baselines map[string]persistedBaseline) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for value, p := range baselines {
+		d.baselines[value] = &baseline{mean: p.Mean, variance: p.Variance, windows: p.Windows}
+	}
+}
+
+// checkpointer owns the checkpoint directory: the latest snapshot and the
+// write-ahead log segments written since
+type checkpointer struct {
+	dir string
+	seq uint64
+	wal *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newCheckpointer(dir string) (*checkpointer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	return &checkpointer{dir: dir}, nil
+}
+
+// load reads the latest snapshot, nil when there is none, and the
+// write-ahead log records written after it. A record torn by a crash ends
+// the log.
+func (c *checkpointer) load() (*snapshot, []walRecord, error) {
+	var snap *snapshot
+	data, err := os.ReadFile(filepath.Join(c.dir, snapshotFile))
+	switch {
+	case err == nil:
+		snap = &snapshot{}
+		if err := json.Unmarshal(data, snap); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		if snap.Version != checkpointVersion {
+			return nil, nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	segments, err := c.segments()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []walRecord
+	for _, seq := range segments {
+		if seq > c.seq {
+			c.seq = seq
+		}
+		if snap != nil && seq < snap.WALSeq {
+			continue
+		}
+		segment, torn, err := c.readSegment(seq)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, segment...)
+		if torn {
+			break
+		}
+	}
+
+	return snap, records, nil
+}
+
+// segments returns the sequence numbers of the write-ahead log segments on disk, in order
+func (c *checkpointer) segments() ([]uint64, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoint directory: %w", err)
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, walPrefix) || !strings.HasSuffix(name, walSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walPrefix), walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+// readSegment decodes a write-ahead log segment, reporting whether it ended in a torn record
+func (c *checkpointer) readSegment(seq uint64) ([]walRecord, bool, error) {
+	f, err := os.Open(c.segmentPath(seq))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	defer f.Close()
+
+	var records []walRecord
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var rec walRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return records, false, nil
+			}
+			return records, true, nil
+		}
+		records = append(records, rec)
+	}
+}
+
+func (c *checkpointer) segmentPath(seq uint64) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s%020d%s", walPrefix, seq, walSuffix))
+}
+
+// rotate closes the current write-ahead log segment and starts the next one,
+// returning its sequence number
+func (c *checkpointer) rotate() (uint64, error) {
+	if err := c.close(); err != nil {
+		return 0, err
+	}
+
+	c.seq++
+	f, err := os.OpenFile(c.segmentPath(c.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	c.wal = f
+	c.buf = bufio.NewWriter(f)
+	c.enc = json.NewEncoder(c.buf)
+	return c.seq, nil
+}
+
+// append writes a record to the current segment. Records are flushed to the
+// operating system immediately so they survive a process crash; they are
+// synced to disk with the next snapshot.
+func (c *checkpointer) append(rec walRecord) error {
+	if c.enc == nil {
+		return errors.New("write-ahead log is not open")
+	}
+	if err := c.enc.Encode(rec); err != nil {
+		return err
+	}
+	return c.buf.Flush()
+}
+
+// writeSnapshot atomically replaces the snapshot and removes the write-ahead
+// log segments it supersedes
+func (c *checkpointer) writeSnapshot(snap *snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, snapshotFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, snapshotFile)); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	if dir, err := os.Open(c.dir); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+
+	segments, err := c.segments()
+	if err != nil {
+		return err
+	}
+	for _, seq := range segments {
+		if seq < snap.WALSeq {
+			_ = os.Remove(c.segmentPath(seq))
+		}
+	}
+	return nil
+}
+
+// close flushes, syncs and closes the current write-ahead log segment
+func (c *checkpointer) close() error {
+	if c.wal == nil {
+		return nil
+	}
+	f := c.wal
+	c.wal, c.buf, c.enc = nil, nil, nil
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync write-ahead log: %w", err)
+	}
+	return f.Close()
+}
+
+// logWAL appends a record to the write-ahead log when checkpointing is
+// enabled. Observation and close records must be logged under wc.mu so they
+// are ordered with segment rotation.
+func (wc *WindowCounter) logWAL(rec walRecord) {
+	if wc.checkpoint == nil {
+		return
+	}
+	if err := wc.checkpoint.append(rec); err != nil {
+		wc.metrics.CheckpointErrorsTotal.Inc()
+		wc.logger.Errorw("Failed to append to checkpoint write-ahead log", "error", err)
+	}
+}
+
+// checkpointNow snapshots the counter state and starts a new write-ahead log segment
+func (wc *WindowCounter) checkpointNow() {
+	if wc.checkpoint == nil {
+		return
+	}
+
+	wc.closeMu.Lock()
+	defer wc.closeMu.Unlock()
+
+	snap := &snapshot{Version: checkpointVersion}
+
+	wc.mu.Lock()
+	seq, err := wc.checkpoint.rotate()
+	if err != nil {
+		wc.mu.Unlock()
+		wc.metrics.CheckpointErrorsTotal.Inc()
+		wc.logger.Errorw("Failed to rotate checkpoint write-ahead log", "error", err)
+		return
+	}
+	snap.TakenAt = time.Now()
+	snap.WALSeq = seq
+	snap.AttributeKey = wc.attributeKey
+	snap.WindowDuration = wc.windowDuration
+	snap.WindowStart = wc.windowStart
+	snap.TotalWindows = wc.totalWindows
+	snap.Current = persistValues(wc.current)
+	snap.Overflowed, _ = wc.overflowed.MarshalBinary()
+	wc.mu.Unlock()
+
+	wc.totalsMu.Lock()
+	snap.Lifetime = persistWindow(wc.lifetime)
+	snap.SinceReset = persistWindow(wc.sinceReset)
+	wc.totalsMu.Unlock()
+
+	if wc.history != nil {
+		snap.History = wc.history.persist()
+	}
+	if wc.anomalies != nil {
+		snap.Baselines = wc.anomalies.persist()
+	}
+
+	if err := wc.checkpoint.writeSnapshot(snap); err != nil {
+		wc.metrics.CheckpointErrorsTotal.Inc()
+		wc.logger.Errorw("Failed to write checkpoint snapshot", "error", err)
+		return
+	}
+	wc.metrics.CheckpointsTotal.Inc()
+}
+
+// restoreCheckpoint loads the persisted state, closes the restored window if
+// it ended while the process was down and reports the windows missed since as
+// a gap. It returns how long the current window has left to run. Checkpointing
+// is disabled if the directory cannot be used.
+func (wc *WindowCounter) restoreCheckpoint(now time.Time) time.Duration {
+	cp, err := newCheckpointer(wc.checkpointDir)
+	if err != nil {
+		wc.metrics.CheckpointErrorsTotal.Inc()
+		wc.logger.Errorw("Checkpointing disabled", "error", err)
+		return wc.windowDuration
+	}
+
+	snap, records, err := cp.load()
+	if err != nil {
+		// Keep the unreadable state for inspection and start afresh
+		wc.metrics.CheckpointErrorsTotal.Inc()
+		wc.logger.Errorw("Failed to restore checkpoint, starting with empty state", "dir", wc.checkpointDir, "error", err)
+		_ = os.Rename(filepath.Join(wc.checkpointDir, snapshotFile), filepath.Join(wc.checkpointDir, snapshotFile+".corrupt"))
+		snap, records = nil, nil
+	}
+	// Counts grouped by another key or window length cannot be merged
+	if snap != nil && (snap.AttributeKey != wc.attributeKey || snap.WindowDuration != wc.windowDuration) {
+		wc.logger.Warnw("Discarding checkpoint taken under different grouping settings",
+			"dir", wc.checkpointDir,
+			"attribute_key", snap.AttributeKey,
+			"window_duration", snap.WindowDuration,
+		)
+		snap, records = nil, nil
+	}
+
+	restored := snap != nil || len(records) > 0
+	if snap != nil {
+		if err := wc.applySnapshot(snap); err != nil {
+			wc.metrics.CheckpointErrorsTotal.Inc()
+			wc.logger.Errorw("Failed to apply checkpoint snapshot, starting with empty state", "error", err)
+			wc.resetState(now)
+			restored = false
+			records = nil
+		}
+	}
+	for _, rec := range records {
+		wc.replay(rec)
+	}
+
+	remaining := wc.windowDuration
+	if restored {
+		remaining = wc.closeMissedWindows(now)
+		wc.logger.Infow("Restored counter state from checkpoint",
+			"dir", wc.checkpointDir,
+			"snapshot_taken_at", snapTime(snap),
+			"replayed_records", len(records),
+			"window_start", wc.windowStart,
+		)
+	}
+
+	// Log from now on, then fold the replayed records into a fresh snapshot
+	wc.mu.Lock()
+	wc.checkpoint = cp
+	wc.mu.Unlock()
+	wc.checkpointNow()
+
+	return remaining
+}
+
+func snapTime(snap *snapshot) time.Time {
+	if snap == nil {
+		return time.Time{}
+	}
+	return snap.TakenAt
+}
+
+// applySnapshot replaces the counter state with a snapshot
+func (wc *WindowCounter) applySnapshot(snap *snapshot) error {
+	current, err := restoreValues(snap.Current)
+	if err != nil {
+		return err
+	}
+	lifetime, err := restoreWindow(snap.Lifetime)
+	if err != nil {
+		return err
+	}
+	sinceReset, err := restoreWindow(snap.SinceReset)
+	if err != nil {
+		return err
+	}
+	overflowed, _ := sketch.New(sketch.DefaultPrecision)
+	if len(snap.Overflowed) > 0 {
+		if err := overflowed.UnmarshalBinary(snap.Overflowed); err != nil {
+			return err
+		}
+	}
+	if wc.history != nil {
+		if err := wc.history.restore(snap.History); err != nil {
+			return err
+		}
+	}
+	if wc.anomalies != nil {
+		wc.anomalies.restore(snap.Baselines)
+	}
+
+	wc.mu.Lock()
+	wc.current = current
+	wc.overflowed = overflowed
+	wc.windowStart = snap.WindowStart
+	wc.totalWindows = snap.TotalWindows
+	wc.mu.Unlock()
+
+	wc.totalsMu.Lock()
+	wc.lifetime = lifetime
+	wc.sinceReset = sinceReset
+	wc.totalsMu.Unlock()
+
+	return nil
+}
+
+// resetState discards any partially restored state
+func (wc *WindowCounter) resetState(now time.Time) {
+	overflowed, _ := sketch.New(sketch.DefaultPrecision)
+
+	wc.mu.Lock()
+	wc.current = make(map[string]*ValueStats)
+	wc.overflowed = overflowed
+	wc.windowStart = now
+	wc.totalWindows = 0
+	wc.mu.Unlock()
+
+	wc.totalsMu.Lock()
+	wc.lifetime = &Window{Start: now, End: now, Values: make(map[string]*ValueStats)}
+	wc.sinceReset = &Window{Start: now, End: now, Values: make(map[string]*ValueStats)}
+	wc.totalsMu.Unlock()
+}
+
+// replay applies a write-ahead log record without reporting it again
+func (wc *WindowCounter) replay(rec walRecord) {
+	switch {
+	case rec.Close:
+		wc.closeMu.Lock()
+		window, _, _ := wc.swapWindow(rec.Time, nil)
+		wc.accountWindow(window, true)
+		wc.closeMu.Unlock()
+	case rec.Reset:
+		wc.totalsMu.Lock()
+		wc.sinceReset = &Window{Start: rec.Time, End: rec.Time, Values: make(map[string]*ValueStats)}
+		wc.totalsMu.Unlock()
+	default:
+		wc.mu.Lock()
+		wc.observeLocked(fromWALObservations(rec.Observations), rec.Time)
+		wc.mu.Unlock()
+	}
+}
+
+// closeMissedWindows reports the restored window if it ended while the process
+// was down, then reports every window that passed without the process as a
+// single gap report, marked by its missed window count, and starts a new
+// window at now. It returns how long the current window has left to run.
+func (wc *WindowCounter) closeMissedWindows(now time.Time) time.Duration {
+	wc.mu.RLock()
+	start := wc.windowStart
+	inFlight := len(wc.current) > 0
+	wc.mu.RUnlock()
+
+	end := start.Add(wc.windowDuration)
+	if end.After(now) {
+		return end.Sub(now)
+	}
+
+	gapStart := start
+	if inFlight {
+		wc.closeWindow(end, nil)
+		gapStart = end
+	}
+
+	if missed := int64(now.Sub(gapStart) / wc.windowDuration); missed > 0 {
+		gapEnd := gapStart.Add(time.Duration(missed) * wc.windowDuration)
+		wc.metrics.MissedWindowsTotal.Add(float64(missed))
+		wc.logger.Warnw("Windows missed while the counter was down",
+			"gap_start", gapStart,
+			"gap_end", gapEnd,
+			"missed_windows", missed,
+		)
+
+		wc.totalWindows++
+		wc.publish(report.Report{
+			WindowNumber:  wc.totalWindows,
+			Mode:          string(wc.reportMode),
+			Start:         gapStart,
+			End:           gapEnd,
+			AttributeKey:  wc.AttributeKey(),
+			DistinctKey:   wc.distinctKey,
+			MissedWindows: missed,
+		})
+	}
+
+	wc.mu.Lock()
+	wc.windowStart = now
+	wc.mu.Unlock()
+
+	return wc.windowDuration
+}
@@ -0,0 +1,197 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/severity"
+)
+
+// newCheckpointedCounter creates a counter checkpointing to dir and restores it as Start would
+func newCheckpointedCounter(t *testing.T, dir string, window time.Duration, now time.Time, opts ...Option) (*WindowCounter, time.Duration) {
+	t.Helper()
+	testLogger, _ := logger.New(false)
+	opts = append([]Option{
+		WithCheckpoint(dir, time.Hour),
+		WithHistory(NewHistory(window, 10, nil, 0)),
+		WithDistinct("trace_id", 10),
+	}, opts...)
+	wc := NewWindowCounter(window, testLogger, false, opts...)
+	remaining := wc.restoreCheckpoint(now)
+	t.Cleanup(func() { _ = wc.checkpoint.close() })
+	return wc, remaining
+}
+
+func TestCheckpoint_RestoresSnapshotAndWAL(t *testing.T) {
+	dir := t.TempDir()
+	wc, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	wc.ObserveBatch([]Observation{
+		{Value: "a", Distinct: "t1", Bytes: 10, Severity: severity.Error},
+		{Value: "a", Distinct: "t2", Bytes: 20, Severity: severity.Info},
+	})
+	wc.checkpointNow()
+	// Only in the write-ahead log when the process "crashes"
+	wc.IncrementBatch([]string{"b"})
+
+	restored, remaining := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	counts := restored.GetCurrentCounts()
+	if counts["a"] != 2 || counts["b"] != 1 {
+		t.Errorf("Expected a=2 and b=1 after restore, got %v", counts)
+	}
+	if got := restored.GetCurrentDistinctCounts()["a"]; got != 2 {
+		t.Errorf("Expected 2 distinct trace ids for 'a', got %d", got)
+	}
+	stats := restored.current["a"]
+	if stats.Bytes != 30 || stats.Severity[severity.Error] != 1 || stats.Severity[severity.Info] != 1 {
+		t.Errorf("Unexpected restored stats %+v", stats)
+	}
+	if !restored.windowStart.Equal(wc.windowStart) {
+		t.Errorf("Expected the window to keep its start %s, got %s", wc.windowStart, restored.windowStart)
+	}
+	if remaining <= 0 || remaining > time.Hour {
+		t.Errorf("Expected the rest of the hour window to remain, got %s", remaining)
+	}
+}
+
+func TestCheckpoint_ReplaysWindowClose(t *testing.T) {
+	dir := t.TempDir()
+	wc, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	wc.IncrementBatch([]string{"a", "a"})
+	wc.reportAndReset()
+	wc.Increment("b")
+
+	restored, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	if got := restored.LifetimeTotals().Values["a"]; got == nil || got.Count != 2 {
+		t.Errorf("Expected the closed window in the lifetime totals, got %v", restored.LifetimeTotals().Values)
+	}
+	if counts := restored.GetCurrentCounts(); len(counts) != 1 || counts["b"] != 1 {
+		t.Errorf("Expected only 'b' in the current window, got %v", counts)
+	}
+	windows, _ := restored.History().Windows(time.Hour, time.Time{}, time.Time{})
+	if len(windows) != 1 || windows[0].Values["a"].Count != 2 {
+		t.Errorf("Expected the closed window in history, got %d windows", len(windows))
+	}
+}
+
+func TestCheckpoint_ReportsMissedWindows(t *testing.T) {
+	dir := t.TempDir()
+	wc, _ := newCheckpointedCounter(t, dir, 10*time.Second, time.Now())
+	start := wc.windowStart
+
+	wc.Increment("a")
+	wc.checkpointNow()
+
+	rec := &recordingReporter{}
+	restartedAt := start.Add(45 * time.Second)
+	restored, remaining := newCheckpointedCounter(t, dir, 10*time.Second, restartedAt, WithReporters(rec))
+
+	// The in-flight window closes at start+10s; 10s-40s passed without the process
+	if got := testutil.ToFloat64(restored.metrics.MissedWindowsTotal); got != 3 {
+		t.Errorf("Expected 3 missed windows, got %v", got)
+	}
+	if got := restored.LifetimeTotals().Values["a"]; got == nil || got.Count != 1 {
+		t.Errorf("Expected the in-flight window to be closed into the totals")
+	}
+	windows, _ := restored.History().Windows(10*time.Second, time.Time{}, time.Time{})
+	if len(windows) != 1 || !windows[0].End.Equal(start.Add(10*time.Second)) {
+		t.Errorf("Expected the in-flight window to end on schedule, got %d windows", len(windows))
+	}
+	if len(restored.GetCurrentCounts()) != 0 || !restored.windowStart.Equal(restartedAt) {
+		t.Errorf("Expected a fresh window starting at restart")
+	}
+	if remaining != 10*time.Second {
+		t.Errorf("Expected a full window to remain, got %s", remaining)
+	}
+
+	if len(rec.reports) != 2 {
+		t.Fatalf("Expected the in-flight window and a gap to be reported, got %d reports", len(rec.reports))
+	}
+	if inFlight := rec.reports[0]; inFlight.MissedWindows != 0 || inFlight.Delta == nil || inFlight.Delta.TotalLogs != 1 {
+		t.Errorf("Expected the in-flight window reported with its counts, got %+v", inFlight)
+	}
+	gap := rec.reports[1]
+	if gap.MissedWindows != 3 || gap.Delta != nil || gap.WindowNumber != rec.reports[0].WindowNumber+1 {
+		t.Errorf("Expected a gap report of 3 missed windows without counts, got %+v", gap)
+	}
+	if !gap.Start.Equal(start.Add(10*time.Second)) || !gap.End.Equal(start.Add(40*time.Second)) {
+		t.Errorf("Expected the gap to span %s - %s, got %s - %s", start.Add(10*time.Second), start.Add(40*time.Second), gap.Start, gap.End)
+	}
+}
+
+func TestCheckpoint_DiscardsOtherGrouping(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		window time.Duration
+	}{
+		{name: "attribute key", key: "k8s.namespace", window: time.Hour},
+		{name: "window duration", key: "service.name", window: 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wc, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now(), WithAttributeKey("service.name"))
+			wc.Increment("checkout")
+			wc.checkpointNow()
+			wc.Increment("checkout")
+
+			restored, _ := newCheckpointedCounter(t, dir, tt.window, time.Now(), WithAttributeKey(tt.key))
+
+			if counts := restored.GetCurrentCounts(); len(counts) != 0 {
+				t.Errorf("Expected state from other grouping settings to be discarded, got %v", counts)
+			}
+			if restored.LifetimeTotals().Total() != 0 {
+				t.Errorf("Expected empty lifetime totals")
+			}
+		})
+	}
+}
+
+func TestCheckpoint_TornWALRecord(t *testing.T) {
+	dir := t.TempDir()
+	wc, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	wc.IncrementBatch([]string{"a", "b"})
+
+	f, err := os.OpenFile(wc.checkpoint.segmentPath(wc.checkpoint.seq), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open write-ahead log: %v", err)
+	}
+	f.WriteString(`{"t":"2026-10-16T10:00:00Z","obs":[{"v":"c"`)
+	f.Close()
+
+	restored, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	if counts := restored.GetCurrentCounts(); counts["a"] != 1 || counts["b"] != 1 || counts["c"] != 0 {
+		t.Errorf("Expected the records before the torn one to be restored, got %v", counts)
+	}
+}
+
+func TestCheckpoint_CorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, snapshotFile), []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	restored, _ := newCheckpointedCounter(t, dir, time.Hour, time.Now())
+
+	if len(restored.GetCurrentCounts()) != 0 {
+		t.Errorf("Expected empty state after a corrupt snapshot")
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile+".corrupt")); err != nil {
+		t.Errorf("Expected the corrupt snapshot to be kept aside: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Errorf("Expected a fresh snapshot to be written: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package counter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownResolution is returned when history is queried at a resolution it does not keep
+var ErrUnknownResolution = errors.New("unknown history resolution")
+
+// Rollup describes a coarser resolution kept in history and how many of its
+// windows are retained
+type Rollup struct {
+	Resolution time.Duration
+	Retention  int
+}
+
+// Point is a single value's aggregate within one history window
+type Point struct {
+	Start    time.Time        `json:"start"`
+	End      time.Time        `json:"end"`
+	Count    int64            `json:"count"`
+	Bytes    int64            `json:"bytes"`
+	Severity map[string]int64 `json:"severity,omitempty"`
+	Distinct uint64           `json:"distinct,omitempty"`
+}
+
+// History keeps completed windows in fixed-size ring buffers, both at the base
+// window resolution and rolled up into coarser resolutions
+type History struct {
+	mu        sync.RWMutex
+	levels    []*historyLevel
+	maxValues int
+}
+
+// historyLevel holds the retained windows of a single resolution
+type historyLevel struct {
+	resolution time.Duration
+	windows    *windowRing
+	// pending is the rollup bucket still being filled; always nil for the base level
+	pending *Window
+}
+
+// NewHistory creates a history retaining the last retention windows of duration
+// base, plus the given rollups. maxValues caps the distinct values per rollup
+// window in the same way as the counter (0 disables the cap).
+func NewHistory(base time.Duration, retention int, rollups []Rollup, maxValues int) *History {
+	levels := []*historyLevel{{
+		resolution: base,
+		windows:    newWindowRing(retention),
+	}}
+
+	sorted := append([]Rollup(nil), rollups...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Resolution < sorted[j].Resolution })
+	for _, rollup := range sorted {
+		levels = append(levels, &historyLevel{
+			resolution: rollup.Resolution,
+			windows:    newWindowRing(rollup.Retention),
+		})
+	}
+
+	return &History{
+		levels:    levels,
+		maxValues: maxValues,
+	}
+}
+
+// Add records a completed base window and folds it into every rollup. The
+// window must not be modified afterwards.
+func (h *History) Add(w *Window) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.levels[0].windows.push(w)
+
+	for _, level := range h.levels[1:] {
+		bucketStart := w.Start.Truncate(level.resolution)
+
+		if level.pending != nil && !level.pending.Start.Equal(bucketStart) {
+			level.pending.End = level.pending.Start.Add(level.resolution)
+			level.windows.push(level.pending)
+			level.pending = nil
+		}
+
+		if level.pending == nil {
+			level.pending = &Window{
+				Start:  bucketStart,
+				Values: make(map[string]*ValueStats),
+			}
+		}
+
+		level.pending.mergeValues(w.Values, h.maxValues)
+		level.pending.End = w.End
+	}
+}
+
+// Rebase relabels the base resolution after the window duration changed.
+// Retained windows keep their own bounds, so older base windows may be of
+// the previous duration.
+func (h *History) Rebase(base time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.levels[0].resolution = base
+}
+
+// Resolutions returns the resolutions kept, finest first
+func (h *History) Resolutions() []time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	resolutions := make([]time.Duration, len(h.levels))
+	for i, level := range h.levels {
+		resolutions[i] = level.resolution
+	}
+	return resolutions
+}
+
+// Windows returns the windows at resolution overlapping [from, to), oldest
+// first. A zero from or to leaves that side unbounded. Rollup buckets that are
+// still being filled are included with End set to the latest merged window.
+func (h *History) Windows(resolution time.Duration, from, to time.Time) ([]*Window, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	level, err := h.level(resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []*Window
+	level.windows.each(func(w *Window) {
+		if overlaps(w, from, to) {
+			windows = append(windows, w)
+		}
+	})
+	if level.pending != nil && overlaps(level.pending, from, to) {
+		windows = append(windows, level.pending.clone())
+	}
+
+	return windows, nil
+}
+
+// Series returns the history of a single value at resolution over [from, to).
+// Windows in which the value was not seen are reported with a zero count.
+func (h *History) Series(value string, resolution time.Duration, from, to time.Time) ([]Point, error) {
+	windows, err := h.Windows(resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]Point, 0, len(windows))
+	for _, w := range windows {
+		point := Point{Start: w.Start, End: w.End}
+		if stats, ok := w.Values[value]; ok {
+			point.Count = stats.Count
+			point.Bytes = stats.Bytes
+			point.Severity = stats.Severity.Map()
+			point.Distinct = stats.DistinctEstimate()
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// level returns the level kept at resolution. Callers must hold h.mu.
+func (h *History) level(resolution time.Duration) (*historyLevel, error) {
+	for _, level := range h.levels {
+		if level.resolution == resolution {
+			return level, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownResolution, resolution)
+}
+
+// overlaps reports whether w intersects [from, to), treating zero bounds as open
+func overlaps(w *Window, from, to time.Time) bool {
+	if !from.IsZero() && !w.End.After(from) {
+		return false
+	}
+	if !to.IsZero() && !w.Start.Before(to) {
+		return false
+	}
+	return true
+}
+
+// windowRing is a fixed-capacity ring buffer that overwrites its oldest window
+type windowRing struct {
+	buf  []*Window
+	next int
+	full bool
+}
+
+func newWindowRing(capacity int) *windowRing {
+	return &windowRing{buf: make([]*Window, capacity)}
+}
+
+func (r *windowRing) push(w *Window) {
+	if len(r.buf) == 0 {
+		return
+	}
+	r.buf[r.next] = w
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// each calls fn for every retained window, oldest first
+func (r *windowRing) each(fn func(*Window)) {
+	if r.full {
+		for _, w := range r.buf[r.next:] {
+			fn(w)
+		}
+	}
+	for _, w := range r.buf[:r.next] {
+		fn(w)
+	}
+}
@@ -0,0 +1,174 @@
+package counter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/attributes"
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/sketch"
+)
+
+// testWindow builds a base window starting at start with the given counts
+func testWindow(start time.Time, duration time.Duration, counts map[string]int64) *Window {
+	values := make(map[string]*ValueStats, len(counts))
+	for value, count := range counts {
+		values[value] = &ValueStats{Count: count}
+	}
+	return &Window{Start: start, End: start.Add(duration), Values: values}
+}
+
+func TestHistory_RetentionRing(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 3, nil, 0)
+
+	for i := 0; i < 5; i++ {
+		h.Add(testWindow(base.Add(time.Duration(i)*10*time.Second), 10*time.Second, map[string]int64{"a": int64(i)}))
+	}
+
+	windows, err := h.Windows(10*time.Second, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(windows) != 3 {
+		t.Fatalf("Expected 3 retained windows, got %d", len(windows))
+	}
+	for i, w := range windows {
+		want := int64(i + 2)
+		if w.Values["a"].Count != want {
+			t.Errorf("Window %d: expected count %d, got %d", i, want, w.Values["a"].Count)
+		}
+	}
+}
+
+func TestHistory_Rollups(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 100, []Rollup{{Resolution: time.Minute, Retention: 10}}, 0)
+
+	// Seven 10s windows: six fill the first minute, the seventh starts the second
+	for i := 0; i < 7; i++ {
+		h.Add(testWindow(base.Add(time.Duration(i)*10*time.Second), 10*time.Second, map[string]int64{"a": 1, "b": 2}))
+	}
+
+	windows, err := h.Windows(time.Minute, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 minute windows (one complete, one pending), got %d", len(windows))
+	}
+
+	complete := windows[0]
+	if !complete.Start.Equal(base) || !complete.End.Equal(base.Add(time.Minute)) {
+		t.Errorf("Unexpected complete window range %s - %s", complete.Start, complete.End)
+	}
+	if complete.Values["a"].Count != 6 || complete.Values["b"].Count != 12 {
+		t.Errorf("Unexpected complete window counts a=%d b=%d", complete.Values["a"].Count, complete.Values["b"].Count)
+	}
+
+	pending := windows[1]
+	if pending.Values["a"].Count != 1 {
+		t.Errorf("Expected pending window count 1, got %d", pending.Values["a"].Count)
+	}
+	if !pending.End.Equal(base.Add(70 * time.Second)) {
+		t.Errorf("Expected pending window to end at the last merged window, got %s", pending.End)
+	}
+}
+
+func TestHistory_RollupMergesDistinct(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 10, []Rollup{{Resolution: time.Minute, Retention: 10}}, 0)
+
+	for i, ids := range [][]string{{"t1", "t2"}, {"t2", "t3"}} {
+		distinct, _ := sketch.New(10)
+		for _, id := range ids {
+			distinct.Add(id)
+		}
+		w := testWindow(base.Add(time.Duration(i)*10*time.Second), 10*time.Second, map[string]int64{"a": 2})
+		w.Values["a"].Distinct = distinct
+		h.Add(w)
+	}
+
+	points, err := h.Series("a", time.Minute, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("Expected 1 point, got %d", len(points))
+	}
+	if points[0].Count != 4 || points[0].Distinct != 3 {
+		t.Errorf("Expected count 4 and 3 distinct, got count %d and %d distinct", points[0].Count, points[0].Distinct)
+	}
+
+	// Rolling up must not modify the base windows
+	raw, _ := h.Series("a", 10*time.Second, time.Time{}, time.Time{})
+	if raw[0].Distinct != 2 {
+		t.Errorf("Expected base window to keep 2 distinct, got %d", raw[0].Distinct)
+	}
+}
+
+func TestHistory_RollupMaxValues(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 10, []Rollup{{Resolution: time.Minute, Retention: 10}}, 2)
+
+	h.Add(testWindow(base, 10*time.Second, map[string]int64{"a": 1, "b": 1}))
+	h.Add(testWindow(base.Add(10*time.Second), 10*time.Second, map[string]int64{"c": 3}))
+
+	windows, _ := h.Windows(time.Minute, time.Time{}, time.Time{})
+	if got := windows[0].Values[attributes.OverflowValue]; got == nil || got.Count != 3 {
+		t.Errorf("Expected 'c' to be rolled up into the overflow bucket, got %v", windows[0].Values)
+	}
+}
+
+func TestHistory_SeriesRange(t *testing.T) {
+	base := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	h := NewHistory(10*time.Second, 10, nil, 0)
+
+	h.Add(testWindow(base, 10*time.Second, map[string]int64{"a": 1}))
+	h.Add(testWindow(base.Add(10*time.Second), 10*time.Second, map[string]int64{"b": 1}))
+	h.Add(testWindow(base.Add(20*time.Second), 10*time.Second, map[string]int64{"a": 3}))
+
+	points, err := h.Series("a", 10*time.Second, base.Add(10*time.Second), base.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 points in range, got %d", len(points))
+	}
+	if points[0].Count != 0 || points[1].Count != 3 {
+		t.Errorf("Unexpected series %+v", points)
+	}
+}
+
+func TestHistory_UnknownResolution(t *testing.T) {
+	h := NewHistory(10*time.Second, 10, nil, 0)
+
+	if _, err := h.Windows(time.Minute, time.Time{}, time.Time{}); !errors.Is(err, ErrUnknownResolution) {
+		t.Errorf("Expected ErrUnknownResolution, got %v", err)
+	}
+}
+
+func TestWindowCounter_RecordsHistory(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	h := NewHistory(1*time.Second, 10, nil, 0)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithHistory(h))
+
+	wc.IncrementBatch([]string{"a", "a", "b"})
+	wc.reportAndReset()
+	wc.reportAndReset()
+
+	windows, _ := wc.History().Windows(1*time.Second, time.Time{}, time.Time{})
+	if len(windows) != 2 {
+		t.Fatalf("Expected 2 windows including the empty one, got %d", len(windows))
+	}
+	if windows[0].Values["a"].Count != 2 || windows[0].Values["b"].Count != 1 {
+		t.Errorf("Unexpected first window %v", windows[0].Values)
+	}
+	if len(windows[1].Values) != 0 {
+		t.Errorf("Expected second window to be empty, got %v", windows[1].Values)
+	}
+}
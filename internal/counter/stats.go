@@ -0,0 +1,126 @@
+package counter
+
+import (
+	"time"
+
+	"otlp-log-parser-assignment/internal/attributes"
+	"otlp-log-parser-assignment/internal/severity"
+	"otlp-log-parser-assignment/internal/sketch"
+)
+
+// ValueStats holds everything tracked for a single attribute value within a window
+type ValueStats struct {
+	Count int64
+	// Bytes is the serialized size of the counted records
+	Bytes int64
+	// Severity breaks Count down by severity bucket
+	Severity severity.Counts
+	// Distinct estimates distinct-of values, nil when distinct tracking is disabled
+	Distinct *sketch.HyperLogLog
+	// FirstSeen and LastSeen are the ingestion times of the first and last counted records
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Merge adds other into s
+func (s *ValueStats) Merge(other *ValueStats) {
+	s.Count += other.Count
+	s.Bytes += other.Bytes
+	s.Severity.Add(other.Severity)
+	if !other.FirstSeen.IsZero() && (s.FirstSeen.IsZero() || other.FirstSeen.Before(s.FirstSeen)) {
+		s.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen.After(s.LastSeen) {
+		s.LastSeen = other.LastSeen
+	}
+	if other.Distinct != nil {
+		if s.Distinct == nil {
+			s.Distinct = other.Distinct.Clone()
+		} else {
+			// Sketches share the configured precision
+			_ = s.Distinct.Merge(other.Distinct)
+		}
+	}
+}
+
+// Clone returns an independent copy of s
+func (s *ValueStats) Clone() *ValueStats {
+	clone := &ValueStats{}
+	clone.Merge(s)
+	return clone
+}
+
+// DistinctEstimate returns the estimated distinct-of count, 0 when not tracked
+func (s *ValueStats) DistinctEstimate() uint64 {
+	if s.Distinct == nil {
+		return 0
+	}
+	return s.Distinct.Estimate()
+}
+
+// Window is the aggregate of a completed (or, for rollups, partially filled) window.
+// Windows handed out by the counter must be treated as read-only.
+type Window struct {
+	Start  time.Time
+	End    time.Time
+	Values map[string]*ValueStats
+}
+
+// Total returns the number of log records counted in the window
+func (w *Window) Total() int64 {
+	total := int64(0)
+	for _, stats := range w.Values {
+		total += stats.Count
+	}
+	return total
+}
+
+// TotalBytes returns the serialized size of the log records counted in the window
+func (w *Window) TotalBytes() int64 {
+	total := int64(0)
+	for _, stats := range w.Values {
+		total += stats.Bytes
+	}
+	return total
+}
+
+// TotalSeverity returns the severity breakdown of all log records in the window
+func (w *Window) TotalSeverity() severity.Counts {
+	var total severity.Counts
+	for _, stats := range w.Values {
+		total.Add(stats.Severity)
+	}
+	return total
+}
+
+// mergeValues folds values into w, diverting new values to the overflow bucket
+// once w holds maxValues distinct values (0 disables the cap)
+func (w *Window) mergeValues(values map[string]*ValueStats, maxValues int) {
+	for value, stats := range values {
+		target, ok := w.Values[value]
+		if !ok {
+			if maxValues > 0 && len(w.Values) >= maxValues {
+				value = attributes.OverflowValue
+				target = w.Values[value]
+			}
+			if target == nil {
+				target = &ValueStats{}
+				w.Values[value] = target
+			}
+		}
+		target.Merge(stats)
+	}
+}
+
+// clone returns a deep copy of w
+func (w *Window) clone() *Window {
+	values := make(map[string]*ValueStats, len(w.Values))
+	for value, stats := range w.Values {
+		values[value] = stats.Clone()
+	}
+	return &Window{
+		Start:  w.Start,
+		End:    w.End,
+		Values: values,
+	}
+}
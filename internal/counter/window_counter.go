@@ -1,46 +1,263 @@
 package counter
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sort"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"otlp-log-parser-assignment/internal/attributes"
 	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/report"
+	"otlp-log-parser-assignment/internal/severity"
+	"otlp-log-parser-assignment/internal/sketch"
 )
 
 // WindowCounter tracks counts of attribute values within time windows
 type WindowCounter struct {
 	mu             sync.RWMutex
-	currentCounts  map[string]int64
+	current        map[string]*ValueStats
 	windowDuration time.Duration
 	ticker         *time.Ticker
 	stopCh         chan struct{}
 	logger         *logger.Logger
 	windowStart    time.Time
 	totalWindows   int64
-	debug          bool
+
+	// attributeKey names the group-by attribute carried by reports; guarded by mu
+	attributeKey string
+	// reconfigure hands Reconfigure requests to the window loop
+	reconfigure chan reconfigureRequest
+
+	// maxValues caps the distinct values in current (0 disables the cap)
+	maxValues int
+	// overflowed estimates the distinct values diverted to the overflow bucket this window
+	overflowed *sketch.HyperLogLog
+
+	// distinctKey names the attribute whose distinct values are estimated per tracked value
+	distinctKey       string
+	distinctPrecision uint8
+
+	// history retains completed windows, nil when disabled
+	history *History
+
+	// reportMode selects whether window deltas, cumulative totals or both are reported
+	reportMode ReportMode
+	// totalsMu guards lifetime and sinceReset, which survive window rollover
+	totalsMu   sync.Mutex
+	lifetime   *Window
+	sinceReset *Window
+
+	// anomalyConfig enables anomaly detection when set; anomalies is built from it once options are applied
+	anomalyConfig *AnomalyConfig
+	anomalies     *anomalyDetector
+
+	// checkpointDir enables checkpointing to disk when set; checkpoint is opened by Start
+	checkpointDir      string
+	checkpointInterval time.Duration
+	checkpoint         *checkpointer
+	checkpointTicker   *time.Ticker
+	// closeMu serializes window closes with snapshots so a snapshot never
+	// sees a window that has left current but not yet reached the totals
+	closeMu sync.Mutex
+
+	// reporters publish every window report
+	reporters []report.Reporter
+
+	// metrics records window and checkpoint metrics
+	metrics *metrics.Metrics
+
+	// updates is signalled whenever the current window changes
+	updates chan struct{}
+}
+
+// ReportMode selects what each window report contains
+type ReportMode string
+
+const (
+	// ReportDelta reports the counts of the window that just closed
+	ReportDelta ReportMode = "delta"
+	// ReportCumulative reports running totals since start and since the last reset
+	ReportCumulative ReportMode = "cumulative"
+	// ReportBoth reports window deltas and cumulative totals
+	ReportBoth ReportMode = "both"
+)
+
+// ReportModes lists the supported report modes
+var ReportModes = []ReportMode{ReportDelta, ReportCumulative, ReportBoth}
+
+// Observation is a single log record as seen by the counter
+type Observation struct {
+	// Value is the group-by attribute value. ObserveBatch replaces it with
+	// attributes.OverflowValue when the per-window cap diverted it.
+	Value string
+	// Distinct is the distinct-of attribute value, empty when absent
+	Distinct string
+	// Bytes is the serialized size of the record including its share of resource and scope overhead
+	Bytes int64
+	// Severity is the record's severity bucket
+	Severity severity.Bucket
+}
+
+// Option configures optional WindowCounter behaviour
+type Option func(*WindowCounter)
+
+// reconfigureRequest asks the window loop to regroup windows; done is closed once applied
+type reconfigureRequest struct {
+	attributeKey   string
+	windowDuration time.Duration
+	done           chan struct{}
+}
+
+// WithMaxValues caps the distinct attribute values tracked per window. Values
+// beyond the cap are counted under attributes.OverflowValue.
+func WithMaxValues(maxValues int) Option {
+	return func(wc *WindowCounter) {
+		wc.maxValues = maxValues
+	}
+}
+
+// WithAttributeKey names the group-by attribute in every window report
+func WithAttributeKey(key string) Option {
+	return func(wc *WindowCounter) {
+		wc.attributeKey = key
+	}
+}
+
+// WithDistinct estimates, per tracked value, how many distinct values of key
+// appear in each window using HyperLogLog sketches of the given precision
+func WithDistinct(key string, precision uint8) Option {
+	return func(wc *WindowCounter) {
+		wc.distinctKey = key
+		wc.distinctPrecision = precision
+	}
+}
+
+// WithHistory records every completed window in h
+func WithHistory(h *History) Option {
+	return func(wc *WindowCounter) {
+		wc.history = h
+	}
+}
+
+// WithReportMode selects what each window report contains (ReportDelta by default)
+func WithReportMode(mode ReportMode) Option {
+	return func(wc *WindowCounter) {
+		wc.reportMode = mode
+	}
+}
+
+// WithAnomalyDetection scores every completed window against per-value
+// baselines and reports values whose counts spike or drop
+func WithAnomalyDetection(config AnomalyConfig) Option {
+	return func(wc *WindowCounter) {
+		wc.anomalyConfig = &config
+	}
 }
 
-func NewWindowCounter(windowDuration time.Duration, logger *logger.Logger, debug bool) *WindowCounter {
-	return &WindowCounter{
-		currentCounts:  make(map[string]int64),
+// WithCheckpoint persists counter state to dir: a snapshot every interval and
+// at every window close, plus a write-ahead log of increments in between.
+// Start restores the persisted state.
+func WithCheckpoint(dir string, interval time.Duration) Option {
+	return func(wc *WindowCounter) {
+		wc.checkpointDir = dir
+		wc.checkpointInterval = interval
+	}
+}
+
+// WithReporters publishes window reports to reporters instead of the default
+// JSON log (plus an ASCII table on stdout in debug mode)
+func WithReporters(reporters ...report.Reporter) Option {
+	return func(wc *WindowCounter) {
+		wc.reporters = reporters
+	}
+}
+
+// WithMetrics records window and checkpoint metrics in m instead of an
+// unregistered instance
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(wc *WindowCounter) {
+		wc.metrics = m
+	}
+}
+
+func NewWindowCounter(windowDuration time.Duration, logger *logger.Logger, debug bool, opts ...Option) *WindowCounter {
+	overflowed, _ := sketch.New(sketch.DefaultPrecision)
+	now := time.Now()
+
+	wc := &WindowCounter{
+		current:        make(map[string]*ValueStats),
 		windowDuration: windowDuration,
 		stopCh:         make(chan struct{}),
+		reconfigure:    make(chan reconfigureRequest),
 		logger:         logger.With("component", "counter"),
-		windowStart:    time.Now(),
-		debug:          debug,
+		windowStart:    now,
+		overflowed:     overflowed,
+		reportMode:     ReportDelta,
+		lifetime:       &Window{Start: now, End: now, Values: make(map[string]*ValueStats)},
+		sinceReset:     &Window{Start: now, End: now, Values: make(map[string]*ValueStats)},
+		updates:        make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(wc)
 	}
+
+	if wc.metrics == nil {
+		wc.metrics = metrics.New(nil)
+	}
+	if wc.reporters == nil {
+		wc.reporters = []report.Reporter{report.NewLogReporter(wc.logger)}
+		if debug {
+			wc.reporters = append(wc.reporters, report.NewTableReporter(os.Stdout))
+		}
+	}
+
+	if wc.anomalyConfig != nil {
+		wc.anomalies = newAnomalyDetector(*wc.anomalyConfig, wc.history, windowDuration)
+	}
+
+	return wc
 }
 
 func (wc *WindowCounter) Start() {
-	wc.ticker = time.NewTicker(wc.windowDuration)
+	// A restored window keeps its original start, so the first tick only waits out what is left of it
+	firstTick := wc.windowDuration
+	if wc.checkpointDir != "" {
+		firstTick = wc.restoreCheckpoint(time.Now())
+	}
+	wc.ticker = time.NewTicker(firstTick)
+
+	var checkpointC <-chan time.Time
+	if wc.checkpoint != nil {
+		wc.checkpointTicker = time.NewTicker(wc.checkpointInterval)
+		checkpointC = wc.checkpointTicker.C
+	}
 
 	go func() {
+		aligned := firstTick == wc.windowDuration
 		for {
 			select {
 			case <-wc.ticker.C:
 				wc.reportAndReset()
+				wc.checkpointNow()
+				if !aligned {
+					wc.ticker.Reset(wc.windowDuration)
+					aligned = true
+				}
+			case req := <-wc.reconfigure:
+				wc.applyReconfigure(req)
+				wc.ticker.Reset(wc.windowDuration)
+				aligned = true
+				close(req.done)
+			case <-checkpointC:
+				wc.checkpointNow()
 			case <-wc.stopCh:
 				return
 			}
@@ -54,19 +271,39 @@ func (wc *WindowCounter) Stop() {
 	if wc.ticker != nil {
 		wc.ticker.Stop()
 	}
+	if wc.checkpointTicker != nil {
+		wc.checkpointTicker.Stop()
+	}
 	close(wc.stopCh)
 
 	wc.reportAndReset()
 
+	if wc.checkpoint != nil {
+		wc.checkpointNow()
+		if err := wc.checkpoint.close(); err != nil {
+			wc.logger.Errorw("Failed to close checkpoint write-ahead log", "error", err)
+		}
+	}
+
+	for _, reporter := range wc.reporters {
+		if err := reporter.Close(); err != nil {
+			wc.logger.Errorw("Failed to close reporter", "reporter", fmt.Sprintf("%T", reporter), "error", err)
+		}
+	}
+
 	wc.logger.Infow("Window counter stopped")
 }
 
 // Increment increments the count for a given attribute value
 func (wc *WindowCounter) Increment(attributeValue string) {
+	now := time.Now()
+
 	wc.mu.Lock()
 	defer wc.mu.Unlock()
 
-	wc.currentCounts[attributeValue]++
+	wc.logWAL(walRecord{Time: now, Observations: []walObservation{{Value: attributeValue}}})
+	wc.incrementLocked(attributeValue, now)
+	wc.notifyUpdate()
 }
 
 func (wc *WindowCounter) IncrementBatch(attributeValues []string) {
@@ -74,99 +311,451 @@ func (wc *WindowCounter) IncrementBatch(attributeValues []string) {
 		return
 	}
 
+	now := time.Now()
+
 	wc.mu.Lock()
 	defer wc.mu.Unlock()
 
+	if wc.checkpoint != nil {
+		records := make([]walObservation, len(attributeValues))
+		for i, value := range attributeValues {
+			records[i] = walObservation{Value: value}
+		}
+		wc.logWAL(walRecord{Time: now, Observations: records})
+	}
+
 	for _, value := range attributeValues {
-		wc.currentCounts[value]++
+		wc.incrementLocked(value, now)
+	}
+	wc.notifyUpdate()
+}
+
+// ObserveBatch counts a batch of observations, including their distinct-of
+// values, and sets each Value to the value it was counted under
+func (wc *WindowCounter) ObserveBatch(observations []Observation) {
+	wc.ObserveBatchContext(context.Background(), observations)
+}
+
+// ObserveBatchContext is ObserveBatch recording the time spent waiting for the
+// counter lock as an event on the span in ctx
+func (wc *WindowCounter) ObserveBatchContext(ctx context.Context, observations []Observation) {
+	if len(observations) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.AddEvent("counter lock acquired", trace.WithAttributes(
+			attribute.Int64("counter.lock_wait_us", time.Since(now).Microseconds()),
+		))
+	}
+
+	if wc.checkpoint != nil {
+		wc.logWAL(walRecord{Time: now, Observations: toWALObservations(observations)})
+	}
+
+	wc.observeLocked(observations, now)
+	wc.notifyUpdate()
+}
+
+// Updates is signalled, without ever blocking the counter, after the current
+// window changes or is closed. Signals coalesce: a receiver sees at least one
+// signal after any number of changes. It is meant for a single receiver.
+func (wc *WindowCounter) Updates() <-chan struct{} {
+	return wc.updates
+}
+
+func (wc *WindowCounter) notifyUpdate() {
+	select {
+	case wc.updates <- struct{}{}:
+	default:
+	}
+}
+
+// observeLocked counts a batch of observations, setting each Value to the
+// value it was counted under. Callers must hold wc.mu.
+func (wc *WindowCounter) observeLocked(observations []Observation, now time.Time) {
+	for i := range observations {
+		obs := &observations[i]
+		value, stats := wc.incrementLocked(obs.Value, now)
+		obs.Value = value
+		stats.Bytes += obs.Bytes
+		stats.Severity[obs.Severity]++
+		if wc.distinctKey != "" && obs.Distinct != "" {
+			if stats.Distinct == nil {
+				// Precision is validated by configuration
+				stats.Distinct, _ = sketch.New(wc.distinctPrecision)
+			}
+			stats.Distinct.Add(obs.Distinct)
+		}
+	}
+}
+
+// incrementLocked counts a value, diverting it to the overflow bucket once the
+// window holds maxValues distinct values. It returns the value counted and the
+// stats that were incremented. Callers must hold wc.mu.
+func (wc *WindowCounter) incrementLocked(value string, now time.Time) (string, *ValueStats) {
+	stats, ok := wc.current[value]
+	if !ok {
+		if wc.maxValues > 0 && len(wc.current) >= wc.maxValues {
+			wc.overflowed.Add(value)
+			value = attributes.OverflowValue
+			stats = wc.current[value]
+		}
+		if stats == nil {
+			stats = &ValueStats{FirstSeen: now}
+			wc.current[value] = stats
+		}
 	}
+	stats.Count++
+	stats.LastSeen = now
+	return value, stats
 }
 
 // reportAndReset reports the current counts and resets the counter
 func (wc *WindowCounter) reportAndReset() {
-	wc.mu.Lock()
+	wc.closeWindow(time.Now(), nil)
+}
 
-	if len(wc.currentCounts) == 0 {
-		wc.logger.Infow("No data to report in this window")
+// Reconfigure closes the current window early, reporting it as reconfigured,
+// and counts the following windows by attributeKey over windowDuration. A new
+// key also resets the totals since the last reset so they never mix the
+// values of both keys. Once started, the change is applied by the window loop
+// between ticks; Reconfigure returns after it was applied or the counter stopped.
+func (wc *WindowCounter) Reconfigure(attributeKey string, windowDuration time.Duration) {
+	req := reconfigureRequest{attributeKey: attributeKey, windowDuration: windowDuration, done: make(chan struct{})}
+	if wc.ticker == nil {
+		wc.applyReconfigure(req)
 		return
 	}
 
-	counts := wc.currentCounts
-	wc.currentCounts = make(map[string]int64)
-	wc.windowStart = time.Now()
+	select {
+	case wc.reconfigure <- req:
+		<-req.done
+	case <-wc.stopCh:
+	}
+}
 
-	wc.mu.Unlock()
+// applyReconfigure closes the current window, swapping the grouping settings
+// as it does
+func (wc *WindowCounter) applyReconfigure(req reconfigureRequest) {
+	previousKey, previousDuration := wc.AttributeKey(), wc.WindowDuration()
+	wc.closeWindow(time.Now(), &req)
 
-	keys := make([]string, 0, len(counts))
-	for key := range counts {
-		keys = append(keys, key)
+	if previousKey != req.attributeKey {
+		wc.ResetTotals()
 	}
-	sort.Strings(keys)
+	// Baselines learned under another grouping or window length no longer apply
+	if wc.anomalies != nil && (previousKey != req.attributeKey || previousDuration != req.windowDuration) {
+		wc.anomalies.reset()
+		wc.metrics.SetAttributeValueAnomalyScores(nil)
+	}
+	// Snapshot under the new settings, which a restart checks the snapshot against
+	wc.checkpointNow()
 
-	now := time.Now()
-	windowEnd := now
-	windowDuration := windowEnd.Sub(wc.windowStart)
-	totalLogs := int64(0)
-	for _, count := range counts {
-		totalLogs += count
+	wc.logger.Infow("Window counter reconfigured",
+		"attribute_key", req.attributeKey,
+		"previous_attribute_key", previousKey,
+		"duration", req.windowDuration,
+		"previous_duration", previousDuration,
+	)
+}
+
+// AttributeKey returns the group-by attribute windows are currently counted by
+func (wc *WindowCounter) AttributeKey() string {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+
+	return wc.attributeKey
+}
+
+// WindowDuration returns the current window length
+func (wc *WindowCounter) WindowDuration() time.Duration {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+
+	return wc.windowDuration
+}
+
+// closeWindow ends the current window at end, folds it into history and the
+// cumulative totals, and reports it. With a reconfigure request the next
+// window uses the new settings; the closed one is marked as reconfigured and
+// reported even when empty.
+func (wc *WindowCounter) closeWindow(end time.Time, reconfigure *reconfigureRequest) {
+	reconfigured := reconfigure != nil
+
+	wc.closeMu.Lock()
+	window, overflowValues, attributeKey := wc.swapWindow(end, reconfigure)
+	// A window cut short by a reconfiguration would score as a drop everywhere
+	anomalies := wc.accountWindow(window, !reconfigured)
+	if reconfigured {
+		if wc.history != nil {
+			wc.history.Rebase(reconfigure.windowDuration)
+		}
+		if wc.anomalies != nil {
+			wc.anomalies.setWindowDuration(reconfigure.windowDuration)
+		}
+	}
+	wc.closeMu.Unlock()
+	wc.notifyUpdate()
+
+	// Idle windows count towards series expiry even when there is nothing to report
+	windowCounts := make(map[string]int64, len(window.Values))
+	for value, stats := range window.Values {
+		windowCounts[value] = stats.Count
+	}
+	wc.metrics.ObserveWindow(windowCounts)
+
+	var lifetime, sinceReset *Window
+	if wc.reportMode != ReportDelta {
+		wc.totalsMu.Lock()
+		lifetime = wc.lifetime.clone()
+		sinceReset = wc.sinceReset.clone()
+		wc.totalsMu.Unlock()
 	}
+
+	reportDelta := wc.reportMode != ReportCumulative && len(window.Values) > 0
+	reportCumulative := lifetime != nil && len(lifetime.Values) > 0
+	// A window in which every value dropped to zero is still worth reporting
+	if !reportDelta && !reportCumulative && len(anomalies) == 0 && !reconfigured {
+		wc.logger.Infow("No data to report in this window")
+		return
+	}
+
 	wc.totalWindows++
 
-	// Create detailed counts with percentages
-	type AttributeCount struct {
-		Count int64 `json:"count"`
+	r := report.Report{
+		WindowNumber: wc.totalWindows,
+		Mode:         string(wc.reportMode),
+		Start:        window.Start,
+		End:          window.End,
+		AttributeKey: attributeKey,
+		Reconfigured: reconfigured,
+		DistinctKey:  wc.distinctKey,
+	}
+	if reportDelta {
+		r.Delta = buildDelta(window, overflowValues)
+		if wc.distinctKey != "" {
+			distinctCounts := make(map[string]uint64, len(r.Delta.Values))
+			for _, v := range r.Delta.Values {
+				distinctCounts[v.Value] = v.Distinct
+			}
+			wc.metrics.SetAttributeValueDistinct(distinctCounts)
+		}
+	}
+	if reportCumulative {
+		r.Cumulative = buildCumulative(lifetime, sinceReset)
+	}
+	if wc.anomalies != nil {
+		r.Anomalies = append([]Anomaly{}, anomalies...)
 	}
 
-	detailedCounts := make(map[string]AttributeCount)
+	wc.publish(r)
 
-	wc.logger.Infow("Log attribute counts report",
-		"window_number", wc.totalWindows,
-		"time_range", fmt.Sprintf("%s - %s", wc.windowStart.Format("15:04:05"), windowEnd.Format("15:04:05")),
-		"duration", windowDuration.Round(time.Millisecond).String(),
-		"total_logs", totalLogs,
-		"unique_values", len(keys),
-		"attribute_counts", detailedCounts,
-	)
+	wc.metrics.WindowOverflowValues.Set(float64(overflowValues))
+	wc.metrics.WindowOverflowValuesTotal.Add(float64(overflowValues))
+}
 
-	// Show beautiful ASCII table in debug mode
-	if wc.debug {
-		wc.printASCIITable(windowEnd, windowDuration, totalLogs, keys)
+// publish hands r to every reporter
+func (wc *WindowCounter) publish(r report.Report) {
+	for _, reporter := range wc.reporters {
+		if err := reporter.Report(r); err != nil {
+			wc.logger.Errorw("Failed to publish window report", "reporter", fmt.Sprintf("%T", reporter), "error", err)
+		}
+	}
+}
+
+// buildDelta summarizes a closed window for reporting
+func buildDelta(window *Window, overflowValues uint64) *report.Delta {
+	total := window.Total()
+	delta := &report.Delta{
+		TotalLogs:      total,
+		TotalBytes:     window.TotalBytes(),
+		OverflowValues: overflowValues,
+		Severity:       window.TotalSeverity().Map(),
+		Values:         make([]report.ValueCount, 0, len(window.Values)),
+	}
+	if overflow, ok := window.Values[attributes.OverflowValue]; ok {
+		delta.OverflowLogs = overflow.Count
 	}
 
+	for _, value := range sortedKeys(window.Values) {
+		stats := window.Values[value]
+		percentage := 0.0
+		if total > 0 {
+			percentage = float64(stats.Count) / float64(total) * 100
+		}
+		delta.Values = append(delta.Values, report.ValueCount{
+			Value:      value,
+			Count:      stats.Count,
+			Percentage: percentage,
+			Bytes:      stats.Bytes,
+			Severity:   stats.Severity.Map(),
+			Distinct:   stats.DistinctEstimate(),
+		})
+	}
+
+	return delta
 }
 
-// printASCIITable prints a beautiful ASCII table for debug mode
-func (wc *WindowCounter) printASCIITable(windowEnd time.Time, windowDuration time.Duration, totalLogs int64, keys []string) {
-	fmt.Println("")
-	fmt.Println("╔═══════════════════════════════════════════════════════════╗")
-	fmt.Println("║          Log Attribute Counts Report                      ║")
-	fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-	fmt.Printf("║ Window #%-3d                                               ║\n", wc.totalWindows)
-	fmt.Printf("║ Time Range: %-45s ║\n", wc.windowStart.Format("15:04:05")+" - "+windowEnd.Format("15:04:05"))
-	fmt.Printf("║ Duration: %-47s ║\n", windowDuration.Round(time.Millisecond).String())
-	fmt.Printf("║ Total Logs: %-45d ║\n", totalLogs)
-	fmt.Printf("║ Unique Values: %-42d ║\n", len(keys))
-	fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-	fmt.Println("║ Attribute Value Counts:                                   ║")
-	fmt.Println("╠═══════════════════════════════════════════════════════════╣")
-	for _, key := range keys {
-		count := wc.currentCounts[key]
-		percentage := float64(count) / float64(totalLogs) * 100
-		fmt.Printf("║ %-40s %8d (%5.1f%%) ║\n", truncate(key, 40), count, percentage)
+// buildCumulative summarizes the running totals for reporting
+func buildCumulative(lifetime, sinceReset *Window) *report.Cumulative {
+	cumulative := &report.Cumulative{
+		Since:          lifetime.Start,
+		ResetAt:        sinceReset.Start,
+		TotalLogs:      lifetime.Total(),
+		TotalBytes:     lifetime.TotalBytes(),
+		SinceResetLogs: sinceReset.Total(),
+		Values:         make([]report.CumulativeCount, 0, len(lifetime.Values)),
+	}
+
+	for _, value := range sortedKeys(lifetime.Values) {
+		stats := lifetime.Values[value]
+		entry := report.CumulativeCount{
+			Value:     value,
+			Total:     stats.Count,
+			Bytes:     stats.Bytes,
+			FirstSeen: stats.FirstSeen,
+			LastSeen:  stats.LastSeen,
+		}
+		if recent, ok := sinceReset.Values[value]; ok {
+			entry.SinceReset = recent.Count
+		}
+		cumulative.Values = append(cumulative.Values, entry)
+	}
+
+	return cumulative
+}
+
+// swapWindow ends the current window at end, starts the next one, applying
+// reconfigure when set, and returns the closed window with its overflowed
+// distinct value estimate and attribute key. Callers must hold wc.closeMu.
+func (wc *WindowCounter) swapWindow(end time.Time, reconfigure *reconfigureRequest) (*Window, uint64, string) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	window := &Window{
+		Start:  wc.windowStart,
+		End:    end,
+		Values: wc.current,
+	}
+	overflowValues := wc.overflowed.Estimate()
+	wc.current = make(map[string]*ValueStats)
+	wc.overflowed.Reset()
+	wc.windowStart = end
+	attributeKey := wc.attributeKey
+	if reconfigure != nil {
+		wc.attributeKey = reconfigure.attributeKey
+		wc.windowDuration = reconfigure.windowDuration
 	}
-	fmt.Println("╚═══════════════════════════════════════════════════════════╝")
-	fmt.Println("")
+
+	wc.logWAL(walRecord{Time: end, Close: true})
+
+	return window, overflowValues, attributeKey
 }
 
-// truncate truncates a string to maxLen characters
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// accountWindow scores a closed window for anomalies, when score is set, and
+// folds it into history
+// and the cumulative totals. Callers must hold wc.closeMu.
+func (wc *WindowCounter) accountWindow(window *Window, score bool) []Anomaly {
+	// Score before the window enters history so a seasonal baseline never compares it with itself
+	var anomalies []Anomaly
+	if wc.anomalies != nil && score {
+		var scores map[string]float64
+		scores, anomalies = wc.anomalies.score(window)
+		wc.metrics.SetAttributeValueAnomalyScores(scores)
+		for _, anomaly := range anomalies {
+			wc.metrics.AttributeValueAnomaliesTotal.WithLabelValues(anomaly.Direction).Inc()
+		}
 	}
-	if maxLen <= 3 {
-		return s[:maxLen]
+
+	// Empty windows are kept in history so series have no holes
+	if wc.history != nil {
+		wc.history.Add(window)
+	}
+
+	// Cumulative totals survive window rollover
+	wc.totalsMu.Lock()
+	wc.lifetime.mergeValues(window.Values, wc.maxValues)
+	wc.lifetime.End = window.End
+	wc.sinceReset.mergeValues(window.Values, wc.maxValues)
+	wc.sinceReset.End = window.End
+	wc.totalsMu.Unlock()
+
+	return anomalies
+}
+
+// ResetTotals clears the totals since the last reset. Lifetime totals are kept.
+func (wc *WindowCounter) ResetTotals() {
+	now := time.Now()
+
+	wc.totalsMu.Lock()
+	defer wc.totalsMu.Unlock()
+
+	wc.logWAL(walRecord{Time: now, Reset: true})
+	wc.sinceReset = &Window{Start: now, End: now, Values: make(map[string]*ValueStats)}
+}
+
+// LifetimeTotals returns a copy of the totals of all completed windows since start
+func (wc *WindowCounter) LifetimeTotals() *Window {
+	wc.totalsMu.Lock()
+	defer wc.totalsMu.Unlock()
+
+	return wc.lifetime.clone()
+}
+
+// TotalsSinceReset returns a copy of the totals of all completed windows since the last reset
+func (wc *WindowCounter) TotalsSinceReset() *Window {
+	wc.totalsMu.Lock()
+	defer wc.totalsMu.Unlock()
+
+	return wc.sinceReset.clone()
+}
+
+// sortedKeys returns the values of a window in lexical order
+func sortedKeys(values map[string]*ValueStats) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetCurrentDistinctCounts returns the estimated distinct-of counts per value in the current window
+func (wc *WindowCounter) GetCurrentDistinctCounts() map[string]uint64 {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+
+	counts := make(map[string]uint64)
+	for k, stats := range wc.current {
+		if stats.Distinct != nil {
+			counts[k] = stats.Distinct.Estimate()
+		}
+	}
+	return counts
+}
+
+// History returns the window history, nil when disabled
+func (wc *WindowCounter) History() *History {
+	return wc.history
+}
+
+// CurrentWindow returns a snapshot of the window still being filled, ending now
+func (wc *WindowCounter) CurrentWindow() *Window {
+	wc.mu.RLock()
+	defer wc.mu.RUnlock()
+
+	w := &Window{Start: wc.windowStart, End: time.Now(), Values: make(map[string]*ValueStats, len(wc.current))}
+	for k, stats := range wc.current {
+		w.Values[k] = stats.Clone()
 	}
-	return s[:maxLen-3] + "..."
+	return w
 }
 
 // GetCurrentCounts returns a copy of the current counts (for testing)
@@ -174,9 +763,9 @@ func (wc *WindowCounter) GetCurrentCounts() map[string]int64 {
 	wc.mu.RLock()
 	defer wc.mu.RUnlock()
 
-	counts := make(map[string]int64, len(wc.currentCounts))
-	for k, v := range wc.currentCounts {
-		counts[k] = v
+	counts := make(map[string]int64, len(wc.current))
+	for k, stats := range wc.current {
+		counts[k] = stats.Count
 	}
 	return counts
 }
@@ -4,7 +4,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-log-parser-assignment/internal/attributes"
 	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/report"
+	"otlp-log-parser-assignment/internal/severity"
 )
 
 func TestWindowCounter_Increment(t *testing.T) {
@@ -101,3 +107,417 @@ func TestWindowCounter_ConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected count to be 1000, got %d", counts["concurrent"])
 	}
 }
+
+func TestWindowCounter_MaxValues(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithMaxValues(2))
+
+	wc.IncrementBatch([]string{"a", "b", "c", "a", "d", "c"})
+
+	counts := wc.GetCurrentCounts()
+
+	expected := map[string]int64{
+		"a":                      2,
+		"b":                      1,
+		attributes.OverflowValue: 3,
+	}
+
+	if len(counts) != len(expected) {
+		t.Errorf("Expected %d tracked values, got %v", len(expected), counts)
+	}
+	for key, expectedCount := range expected {
+		if counts[key] != expectedCount {
+			t.Errorf("Expected count for %s to be %d, got %d", key, expectedCount, counts[key])
+		}
+	}
+
+	if got := wc.overflowed.Estimate(); got != 2 {
+		t.Errorf("Expected 2 distinct overflowed values, got %d", got)
+	}
+}
+
+func TestWindowCounter_ObserveBatchSetsCountedValue(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithMaxValues(1))
+
+	observations := []Observation{{Value: "a"}, {Value: "b"}, {Value: "a"}}
+	wc.ObserveBatch(observations)
+
+	want := []string{"a", attributes.OverflowValue, "a"}
+	for i, obs := range observations {
+		if obs.Value != want[i] {
+			t.Errorf("Observation %d counted under %q, want %q", i, obs.Value, want[i])
+		}
+	}
+}
+
+func TestWindowCounter_MaxValuesResetsEachWindow(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithMaxValues(1))
+
+	wc.IncrementBatch([]string{"a", "b"})
+	wc.reportAndReset()
+	wc.Increment("b")
+
+	counts := wc.GetCurrentCounts()
+	if counts["b"] != 1 || counts[attributes.OverflowValue] != 0 {
+		t.Errorf("Expected 'b' to be tracked in the new window, got %v", counts)
+	}
+	if got := wc.overflowed.Estimate(); got != 0 {
+		t.Errorf("Expected overflow sketch to be reset, got %d", got)
+	}
+}
+
+func TestWindowCounter_ReportEmptyWindow(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false)
+
+	// Reporting an empty window must release the lock
+	wc.reportAndReset()
+	wc.Increment("a")
+
+	if counts := wc.GetCurrentCounts(); counts["a"] != 1 {
+		t.Errorf("Expected count for a to be 1, got %d", counts["a"])
+	}
+}
+
+func TestWindowCounter_WindowCountMetrics(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	m := metrics.New(nil)
+	m.SetSeriesIdleWindows(2)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithMetrics(m), WithReporters())
+
+	wc.IncrementBatch([]string{"a", "a", "b"})
+	wc.reportAndReset()
+	if got := testutil.ToFloat64(m.AttributeValueWindowCount.WithLabelValues("a")); got != 2 {
+		t.Errorf("Expected a window count of 2 for a, got %f", got)
+	}
+
+	// Empty windows still count as idle
+	wc.reportAndReset()
+	wc.reportAndReset()
+	if got := testutil.ToFloat64(m.AttributeValueExpiredSeriesTotal); got != 2 {
+		t.Errorf("Expected both series to expire after 2 idle windows, got %f", got)
+	}
+}
+
+func TestWindowCounter_ObserveBatch_Distinct(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithDistinct("trace_id", 10))
+
+	wc.ObserveBatch([]Observation{
+		{Value: "checkout", Distinct: "t1"},
+		{Value: "checkout", Distinct: "t2"},
+		{Value: "checkout", Distinct: "t1"},
+		{Value: "cart", Distinct: "t3"},
+		{Value: "cart"},
+	})
+
+	counts := wc.GetCurrentCounts()
+	if counts["checkout"] != 3 || counts["cart"] != 2 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+
+	distinct := wc.GetCurrentDistinctCounts()
+	if distinct["checkout"] != 2 {
+		t.Errorf("Expected 2 distinct trace ids for checkout, got %d", distinct["checkout"])
+	}
+	if distinct["cart"] != 1 {
+		t.Errorf("Expected 1 distinct trace id for cart, got %d", distinct["cart"])
+	}
+
+	wc.reportAndReset()
+	if distinct := wc.GetCurrentDistinctCounts(); len(distinct) != 0 {
+		t.Errorf("Expected distinct sketches to be reset, got %v", distinct)
+	}
+}
+
+func TestWindowCounter_ObserveBatch_DistinctDisabled(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false)
+
+	wc.ObserveBatch([]Observation{{Value: "checkout", Distinct: "t1"}})
+
+	if counts := wc.GetCurrentCounts(); counts["checkout"] != 1 {
+		t.Errorf("Expected count for checkout to be 1, got %d", counts["checkout"])
+	}
+	if distinct := wc.GetCurrentDistinctCounts(); len(distinct) != 0 {
+		t.Errorf("Expected no distinct tracking, got %v", distinct)
+	}
+}
+
+func TestWindowCounter_ObserveBatch_Bytes(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	h := NewHistory(1*time.Second, 10, nil, 0)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithHistory(h))
+
+	wc.ObserveBatch([]Observation{
+		{Value: "checkout", Bytes: 100},
+		{Value: "checkout", Bytes: 50},
+		{Value: "cart", Bytes: 10},
+	})
+	wc.reportAndReset()
+
+	points, _ := h.Series("checkout", 1*time.Second, time.Time{}, time.Time{})
+	if len(points) != 1 || points[0].Count != 2 || points[0].Bytes != 150 {
+		t.Errorf("Expected 2 records and 150 bytes for checkout, got %+v", points)
+	}
+}
+
+func TestWindowCounter_CurrentWindow(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false)
+
+	wc.ObserveBatch([]Observation{
+		{Value: "checkout", Bytes: 100},
+		{Value: "cart", Bytes: 10},
+	})
+
+	window := wc.CurrentWindow()
+	if window.Total() != 2 || window.TotalBytes() != 110 {
+		t.Errorf("Expected 2 records and 110 bytes, got %d and %d", window.Total(), window.TotalBytes())
+	}
+	if window.End.Before(window.Start) {
+		t.Errorf("Expected the window to end at or after its start, got %v to %v", window.Start, window.End)
+	}
+
+	// The snapshot must not change as the window keeps filling
+	wc.Increment("checkout")
+	if window.Values["checkout"].Count != 1 {
+		t.Errorf("Expected the snapshot to be isolated, got %d", window.Values["checkout"].Count)
+	}
+}
+
+func TestWindowCounter_Updates(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false)
+
+	select {
+	case <-wc.Updates():
+		t.Fatal("Expected no update before any change")
+	default:
+	}
+
+	// Changes coalesce into a single pending signal and never block
+	wc.Increment("checkout")
+	wc.IncrementBatch([]string{"cart"})
+	wc.ObserveBatch([]Observation{{Value: "checkout"}})
+	<-wc.Updates()
+	select {
+	case <-wc.Updates():
+		t.Fatal("Expected signals to coalesce")
+	default:
+	}
+
+	wc.reportAndReset()
+	select {
+	case <-wc.Updates():
+	default:
+		t.Error("Expected closing the window to signal an update")
+	}
+}
+
+func TestWindowCounter_ObserveBatch_Severity(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	h := NewHistory(1*time.Second, 10, nil, 0)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithHistory(h))
+
+	wc.ObserveBatch([]Observation{
+		{Value: "checkout", Severity: severity.Debug},
+		{Value: "checkout", Severity: severity.Debug},
+		{Value: "checkout", Severity: severity.Error},
+		{Value: "cart"},
+	})
+	wc.reportAndReset()
+
+	windows, _ := h.Windows(1*time.Second, time.Time{}, time.Time{})
+	checkout := windows[0].Values["checkout"].Severity
+	if checkout[severity.Debug] != 2 || checkout[severity.Error] != 1 {
+		t.Errorf("Unexpected checkout severity breakdown %v", checkout.Map())
+	}
+	if cart := windows[0].Values["cart"].Severity; cart[severity.Unspecified] != 1 {
+		t.Errorf("Expected cart record to be unspecified, got %v", cart.Map())
+	}
+
+	totals := windows[0].TotalSeverity()
+	if totals[severity.Debug] != 2 || totals[severity.Error] != 1 || totals[severity.Unspecified] != 1 {
+		t.Errorf("Unexpected window severity totals %v", totals.Map())
+	}
+}
+
+func TestWindowCounter_CumulativeTotals(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithReportMode(ReportBoth))
+
+	wc.IncrementBatch([]string{"a", "a", "b"})
+	wc.reportAndReset()
+	wc.IncrementBatch([]string{"a"})
+	wc.reportAndReset()
+
+	lifetime := wc.LifetimeTotals()
+	if lifetime.Values["a"].Count != 3 || lifetime.Values["b"].Count != 1 {
+		t.Errorf("Unexpected lifetime totals a=%d b=%d", lifetime.Values["a"].Count, lifetime.Values["b"].Count)
+	}
+
+	a := lifetime.Values["a"]
+	if a.FirstSeen.IsZero() || a.LastSeen.Before(a.FirstSeen) {
+		t.Errorf("Unexpected first/last seen %s - %s", a.FirstSeen, a.LastSeen)
+	}
+	if !lifetime.Values["b"].LastSeen.Before(a.LastSeen) {
+		t.Errorf("Expected b to be last seen before a")
+	}
+
+	wc.ResetTotals()
+	wc.Increment("b")
+	wc.reportAndReset()
+
+	sinceReset := wc.TotalsSinceReset()
+	if _, ok := sinceReset.Values["a"]; ok {
+		t.Errorf("Expected a to be absent since reset, got %v", sinceReset.Values["a"])
+	}
+	if sinceReset.Values["b"].Count != 1 {
+		t.Errorf("Expected 1 b since reset, got %d", sinceReset.Values["b"].Count)
+	}
+	if wc.LifetimeTotals().Values["b"].Count != 2 {
+		t.Errorf("Expected lifetime totals to survive the reset")
+	}
+}
+
+func TestBuildCumulative(t *testing.T) {
+	first := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	last := first.Add(time.Minute)
+	lifetime := &Window{Start: first, Values: map[string]*ValueStats{
+		"a": {Count: 5, Bytes: 50, FirstSeen: first, LastSeen: last},
+		"b": {Count: 1},
+	}}
+	sinceReset := &Window{Start: first, Values: map[string]*ValueStats{
+		"a": {Count: 2},
+	}}
+
+	cumulative := buildCumulative(lifetime, sinceReset)
+
+	if cumulative.TotalLogs != 6 || cumulative.SinceResetLogs != 2 || len(cumulative.Values) != 2 {
+		t.Fatalf("Unexpected cumulative totals %+v", cumulative)
+	}
+	want := report.CumulativeCount{Value: "a", Total: 5, SinceReset: 2, Bytes: 50, FirstSeen: first, LastSeen: last}
+	if cumulative.Values[0] != want {
+		t.Errorf("Unexpected cumulative entry %+v, want %+v", cumulative.Values[0], want)
+	}
+}
+
+func TestBuildDelta(t *testing.T) {
+	window := testWindow(time.Now(), time.Second, map[string]int64{"b": 1, "a": 3, attributes.OverflowValue: 4})
+	window.Values["a"].Bytes = 30
+
+	delta := buildDelta(window, 2)
+
+	if delta.TotalLogs != 8 || delta.TotalBytes != 30 || delta.OverflowLogs != 4 || delta.OverflowValues != 2 {
+		t.Errorf("Unexpected delta totals %+v", delta)
+	}
+	if len(delta.Values) != 3 || delta.Values[1].Value != "a" || delta.Values[2].Value != "b" {
+		t.Fatalf("Expected values sorted by value, got %+v", delta.Values)
+	}
+	if a := delta.Values[1]; a.Count != 3 || a.Percentage != 37.5 || a.Bytes != 30 {
+		t.Errorf("Unexpected entry for a: %+v", a)
+	}
+}
+
+// recordingReporter keeps every report it receives
+type recordingReporter struct {
+	reports []report.Report
+	closed  bool
+}
+
+func (r *recordingReporter) Report(rep report.Report) error {
+	r.reports = append(r.reports, rep)
+	return nil
+}
+
+func (r *recordingReporter) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestWindowCounter_Reporters(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	first, second := &recordingReporter{}, &recordingReporter{}
+	wc := NewWindowCounter(1*time.Second, testLogger, false, WithReporters(first, second))
+
+	wc.IncrementBatch([]string{"a", "a", "b"})
+	wc.Stop()
+
+	for _, rec := range []*recordingReporter{first, second} {
+		if len(rec.reports) != 1 {
+			t.Fatalf("Expected 1 report per reporter, got %d", len(rec.reports))
+		}
+		r := rec.reports[0]
+		if r.WindowNumber != 1 || r.Delta == nil || r.Delta.TotalLogs != 3 || r.Cumulative != nil || r.Anomalies != nil {
+			t.Errorf("Unexpected report %+v", r)
+		}
+		if !rec.closed {
+			t.Errorf("Expected reporter to be closed on stop")
+		}
+	}
+}
+
+func TestWindowCounter_Reconfigure(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	rec := &recordingReporter{}
+	history := NewHistory(time.Second, 10, nil, 0)
+	wc := NewWindowCounter(time.Second, testLogger, false,
+		WithAttributeKey("service.name"),
+		WithHistory(history),
+		WithReportMode(ReportBoth),
+		WithReporters(rec),
+	)
+
+	wc.IncrementBatch([]string{"a", "a", "b"})
+	wc.Reconfigure("k8s.namespace", 2*time.Second)
+
+	if len(rec.reports) != 1 {
+		t.Fatalf("Expected the open window to be reported, got %d reports", len(rec.reports))
+	}
+	r := rec.reports[0]
+	if !r.Reconfigured || r.AttributeKey != "service.name" || r.Delta.TotalLogs != 3 {
+		t.Errorf("Expected a reconfigured service.name window of 3 records, got %+v", r)
+	}
+	if len(wc.GetCurrentCounts()) != 0 {
+		t.Errorf("Expected a fresh window, got %v", wc.GetCurrentCounts())
+	}
+	if wc.AttributeKey() != "k8s.namespace" || wc.WindowDuration() != 2*time.Second {
+		t.Errorf("Expected k8s.namespace over 2s, got %s over %s", wc.AttributeKey(), wc.WindowDuration())
+	}
+	if got := history.Resolutions()[0]; got != 2*time.Second {
+		t.Errorf("Expected history rebased to 2s, got %s", got)
+	}
+	if len(wc.TotalsSinceReset().Values) != 0 || wc.LifetimeTotals().Total() != 3 {
+		t.Errorf("Expected a key change to reset only the totals since the last reset")
+	}
+
+	wc.Increment("prod")
+	wc.Stop()
+
+	if len(rec.reports) != 2 || rec.reports[1].Reconfigured || rec.reports[1].AttributeKey != "k8s.namespace" {
+		t.Errorf("Expected a regular k8s.namespace window after reconfiguring, got %+v", rec.reports[1:])
+	}
+}
+
+func TestWindowCounter_ReconfigureRunning(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	rec := &recordingReporter{}
+	wc := NewWindowCounter(time.Hour, testLogger, false, WithAttributeKey("service.name"), WithReporters(rec))
+	wc.Start()
+
+	// The marker is reported even for an empty window and the duration stays
+	wc.Reconfigure("service.name", time.Hour)
+	if len(rec.reports) != 1 || !rec.reports[0].Reconfigured || rec.reports[0].Delta != nil {
+		t.Fatalf("Expected an empty reconfigured window, got %+v", rec.reports)
+	}
+	if wc.TotalsSinceReset().Start.After(rec.reports[0].Start) {
+		t.Errorf("Expected totals to survive an unchanged key")
+	}
+
+	wc.Stop()
+	// Reconfiguring a stopped counter must not block
+	wc.Reconfigure("k8s.namespace", time.Hour)
+}
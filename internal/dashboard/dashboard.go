@@ -0,0 +1,290 @@
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"otlp-log-parser-assignment/internal/api"
+	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/watch"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
+)
+
+//go:embed static
+var static embed.FS
+
+// DefaultInterval is the minimum time between live updates of the current window
+const DefaultInterval = 500 * time.Millisecond
+
+// Config configures the dashboard
+type Config struct {
+	// Windows is the number of completed windows shown in the sparklines
+	Windows int
+	// Interval is the minimum time between current window updates (defaults to DefaultInterval)
+	Interval time.Duration
+}
+
+// Dashboard serves a single-page view of the counter under /dashboard/. The
+// page receives the current window, completed windows and dropped windows
+// as Server-Sent Events from /dashboard/events.
+type Dashboard struct {
+	config  Config
+	counter *counter.WindowCounter
+	hub     *watch.Hub
+	logger  *logger.Logger
+	mux     *http.ServeMux
+
+	// mu guards clients, which receive encoded current window events
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// snapshot is the first event of every stream
+type snapshot struct {
+	AttributeKey string       `json:"attribute_key"`
+	Windows      int          `json:"windows"`
+	Current      api.Window   `json:"current"`
+	History      []api.Window `json:"history"`
+}
+
+// dropped tells the page that windows were missed because it fell behind
+type dropped struct {
+	Count int64 `json:"count"`
+}
+
+// New creates a dashboard over wc's current window, receiving completed windows from hub
+func New(config Config, wc *counter.WindowCounter, hub *watch.Hub, logger *logger.Logger) *Dashboard {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+
+	d := &Dashboard{
+		config:  config,
+		counter: wc,
+		hub:     hub,
+		logger:  logger,
+		mux:     http.NewServeMux(),
+		clients: make(map[chan []byte]struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	assets, _ := fs.Sub(static, "static")
+	d.mux.Handle("GET /dashboard/", http.StripPrefix("/dashboard/", http.FileServerFS(assets)))
+	d.mux.HandleFunc("GET /dashboard/events", d.events)
+	return d
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mux.ServeHTTP(w, r)
+}
+
+// Start publishes current window updates, at most once per interval
+func (d *Dashboard) Start() {
+	d.startOnce.Do(func() {
+		go d.run()
+	})
+}
+
+// Close stops publishing updates and ends every open event stream
+func (d *Dashboard) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	d.startOnce.Do(func() {
+		close(d.done)
+	})
+	<-d.done
+}
+
+func (d *Dashboard) run() {
+	defer close(d.done)
+
+	for {
+		select {
+		case <-d.counter.Updates():
+		case <-d.stop:
+			return
+		}
+		d.publishCurrent()
+
+		select {
+		case <-time.After(d.config.Interval):
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// publishCurrent hands the current window to every client, replacing an
+// update the client has not picked up yet
+func (d *Dashboard) publishCurrent() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.clients) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(api.NewWindow(d.counter.CurrentWindow(), 0, api.SortCount))
+	if err != nil {
+		d.logger.Errorw("Failed to encode current window", "error", err)
+		return
+	}
+	for client := range d.clients {
+		select {
+		case <-client:
+		default:
+		}
+		client <- data
+	}
+}
+
+func (d *Dashboard) addClient() chan []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	client := make(chan []byte, 1)
+	d.clients[client] = struct{}{}
+	return client
+}
+
+func (d *Dashboard) removeClient(client chan []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.clients, client)
+}
+
+// events streams a snapshot, then current window, completed window and
+// dropped events until the client disconnects or the dashboard closes. A
+// reconfigured window is followed by a fresh snapshot.
+func (d *Dashboard) events(w http.ResponseWriter, r *http.Request) {
+	sub, err := d.hub.Subscribe(watch.Filter{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+	current := d.addClient()
+	defer d.removeClient(current)
+
+	// The stream outlives the admin server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		d.logger.Debugw("Failed to clear the event stream write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := d.writeEvent(rc, w, "snapshot", d.snapshot()); err != nil {
+		return
+	}
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-d.stop:
+			return
+		case data := <-current:
+			err = writeData(rc, w, "current", data)
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if window := event.GetWindow(); window != nil {
+				err = d.writeEvent(rc, w, "window", fromWindowReport(window))
+				// A reconfigured window ends the old grouping; the new snapshot names the new key
+				if err == nil && window.Reconfigured {
+					err = d.writeEvent(rc, w, "snapshot", d.snapshot())
+				}
+			} else if notice := event.GetDropped(); notice != nil {
+				err = d.writeEvent(rc, w, "dropped", dropped{Count: notice.Count})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// snapshot returns the current window and the last completed windows kept in history
+func (d *Dashboard) snapshot() snapshot {
+	s := snapshot{
+		AttributeKey: d.counter.AttributeKey(),
+		Windows:      d.config.Windows,
+		Current:      api.NewWindow(d.counter.CurrentWindow(), 0, api.SortCount),
+		History:      []api.Window{},
+	}
+
+	history := d.counter.History()
+	if history == nil {
+		return s
+	}
+	windows, err := history.Windows(history.Resolutions()[0], time.Time{}, time.Time{})
+	if err != nil {
+		return s
+	}
+	if len(windows) > d.config.Windows {
+		windows = windows[len(windows)-d.config.Windows:]
+	}
+	for _, window := range windows {
+		s.History = append(s.History, api.NewWindow(window, 0, api.SortCount))
+	}
+	return s
+}
+
+func (d *Dashboard) writeEvent(rc *http.ResponseController, w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		d.logger.Errorw("Failed to encode dashboard event", "event", event, "error", err)
+		return err
+	}
+	return writeData(rc, w, event, data)
+}
+
+func writeData(rc *http.ResponseController, w http.ResponseWriter, event string, data []byte) error {
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// fromWindowReport converts a published window into the API representation
+func fromWindowReport(window *countsv1.WindowReport) api.Window {
+	w := api.Window{
+		Start:          window.Start.AsTime(),
+		End:            window.End.AsTime(),
+		TotalLogs:      window.TotalLogs,
+		TotalBytes:     window.TotalBytes,
+		DistinctValues: len(window.Values),
+		Values:         make([]api.ValueCount, len(window.Values)),
+		MissedWindows:  window.MissedWindows,
+	}
+	for i, value := range window.Values {
+		w.Values[i] = api.ValueCount{
+			Value:      value.Value,
+			Count:      value.Count,
+			Bytes:      value.Bytes,
+			Percentage: value.Percentage,
+			Severity:   value.Severity,
+			Distinct:   value.Distinct,
+		}
+	}
+	return w
+}
@@ -0,0 +1,184 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/api"
+	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/report"
+	"otlp-log-parser-assignment/internal/watch"
+)
+
+type event struct {
+	name string
+	data string
+}
+
+// readEvents parses Server-Sent Events from body until it is closed
+func readEvents(body io.Reader) <-chan event {
+	events := make(chan event, 16)
+	go func() {
+		defer close(events)
+
+		var e event
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 1<<20), 1<<20)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				events <- e
+				e = event{}
+			}
+		}
+	}()
+	return events
+}
+
+func next(t *testing.T, events <-chan event, name string) string {
+	t.Helper()
+
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatalf("Expected a %s event, stream ended", name)
+		}
+		if e.name != name {
+			t.Fatalf("Expected a %s event, got %s: %s", name, e.name, e.data)
+		}
+		return e.data
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected a %s event in time", name)
+		return ""
+	}
+}
+
+func newTestDashboard(t *testing.T) (*Dashboard, *counter.WindowCounter, *watch.Hub) {
+	t.Helper()
+
+	testLogger, _ := logger.New(false)
+	history := counter.NewHistory(time.Minute, 10, nil, 0)
+	wc := counter.NewWindowCounter(time.Minute, testLogger, false, counter.WithAttributeKey("service.name"), counter.WithHistory(history))
+	wc.ObserveBatch([]counter.Observation{{Value: "checkout", Bytes: 10}})
+	wc.Stop()
+
+	hub := watch.NewHub(watch.Config{AttributeKey: "service.name", BufferSize: 4}, testLogger)
+	d := New(Config{Windows: 5, Interval: 10 * time.Millisecond}, wc, hub, testLogger)
+	return d, wc, hub
+}
+
+func TestDashboard_Assets(t *testing.T) {
+	d, _, _ := newTestDashboard(t)
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	for path, contains := range map[string]string{
+		"/dashboard/":              `<script src="dashboard.js">`,
+		"/dashboard/dashboard.js":  "EventSource",
+		"/dashboard/dashboard.css": "sparkline",
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), contains) {
+			t.Errorf("GET %s = %d, want 200 containing %q", path, resp.StatusCode, contains)
+		}
+	}
+}
+
+func TestDashboard_Events(t *testing.T) {
+	d, wc, hub := newTestDashboard(t)
+	d.Start()
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/dashboard/events")
+	if err != nil {
+		t.Fatalf("Failed to open the event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+	events := readEvents(resp.Body)
+
+	// The snapshot carries the completed windows kept in history
+	var s snapshot
+	if err := json.Unmarshal([]byte(next(t, events, "snapshot")), &s); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if s.AttributeKey != "service.name" || s.Windows != 5 || len(s.History) != 1 || s.History[0].TotalLogs != 1 {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+
+	// Counting pushes the current window
+	wc.ObserveBatch([]counter.Observation{{Value: "cart", Bytes: 5}, {Value: "cart", Bytes: 5}})
+	var current api.Window
+	if err := json.Unmarshal([]byte(next(t, events, "current")), &current); err != nil {
+		t.Fatalf("Failed to decode current window: %v", err)
+	}
+	if current.TotalLogs != 2 || len(current.Values) != 1 || current.Values[0].Value != "cart" {
+		t.Errorf("Unexpected current window: %+v", current)
+	}
+
+	// Completed windows arrive from the hub
+	hub.Report(report.Report{
+		WindowNumber: 2,
+		Start:        time.Now().Add(-time.Minute),
+		End:          time.Now(),
+		Delta:        &report.Delta{TotalLogs: 2, Values: []report.ValueCount{{Value: "cart", Count: 2, Percentage: 100}}},
+	})
+	var window api.Window
+	if err := json.Unmarshal([]byte(next(t, events, "window")), &window); err != nil {
+		t.Fatalf("Failed to decode window: %v", err)
+	}
+	if window.TotalLogs != 2 || len(window.Values) != 1 || window.Values[0].Count != 2 {
+		t.Errorf("Unexpected window: %+v", window)
+	}
+
+	// A reconfigured window is followed by a snapshot naming the new key
+	wc.Reconfigure("k8s.namespace", time.Minute)
+	next(t, events, "current")
+	hub.Report(report.Report{WindowNumber: 3, Start: time.Now(), End: time.Now(), Reconfigured: true})
+	next(t, events, "window")
+	if err := json.Unmarshal([]byte(next(t, events, "snapshot")), &s); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if s.AttributeKey != "k8s.namespace" {
+		t.Errorf("Expected the snapshot to name the new key, got %q", s.AttributeKey)
+	}
+
+	// Closing the dashboard ends open streams
+	d.Close()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no further events after close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the stream to end on close")
+	}
+}
+
+func TestDashboard_CloseWithoutStart(t *testing.T) {
+	d, _, _ := newTestDashboard(t)
+
+	// Closing a dashboard that never started must not block
+	d.Close()
+	d.Close()
+}
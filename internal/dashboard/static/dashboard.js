@@ -0,0 +1,145 @@
+// Renders the counter from the Server-Sent Events of /dashboard/events:
+// snapshot (once per connection), current, window and dropped.
+(function () {
+  "use strict";
+
+  const SVG = "http://www.w3.org/2000/svg";
+  const state = { windows: 30, current: null, history: [] };
+
+  const byId = (id) => document.getElementById(id);
+  const number = (n) => n.toLocaleString();
+
+  function bytes(n) {
+    const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+    let i = 0;
+    while (n >= 1024 && i < units.length - 1) {
+      n /= 1024;
+      i++;
+    }
+    return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+  }
+
+  function time(s) {
+    return new Date(s).toLocaleTimeString();
+  }
+
+  function sparkline(points) {
+    const svg = document.createElementNS(SVG, "svg");
+    svg.setAttribute("class", "sparkline");
+    svg.setAttribute("viewBox", "0 0 100 20");
+    svg.setAttribute("preserveAspectRatio", "none");
+    if (points.length < 2) {
+      return svg;
+    }
+    const max = Math.max(1, ...points);
+    const coords = points.map((p, i) =>
+      (i * 100 / (points.length - 1)).toFixed(2) + "," + (19 - p * 18 / max).toFixed(2));
+    const line = document.createElementNS(SVG, "polyline");
+    line.setAttribute("points", coords.join(" "));
+    svg.appendChild(line);
+    return svg;
+  }
+
+  function cell(text, className) {
+    const td = document.createElement("td");
+    td.textContent = text;
+    if (className) {
+      td.className = className;
+    }
+    return td;
+  }
+
+  function render() {
+    const current = state.current || { values: [], total_logs: 0, total_bytes: 0 };
+
+    if (state.current) {
+      byId("current-range").textContent = time(current.start) + " - " + time(current.end);
+    }
+    byId("current-logs").textContent = number(current.total_logs);
+    byId("current-bytes").textContent = bytes(current.total_bytes);
+    byId("current-values").textContent = number(current.distinct_values || 0);
+
+    byId("history-label").textContent = "Logs in last " + state.history.length + " windows";
+    byId("history-logs").textContent = number(state.history.reduce((sum, w) => sum + w.total_logs, 0));
+    byId("history-bytes").textContent = bytes(state.history.reduce((sum, w) => sum + w.total_bytes, 0));
+
+    // Values of the current window first, by count, then those only in history
+    const series = new Map();
+    state.history.forEach((window, i) => {
+      window.values.forEach((v) => {
+        if (!series.has(v.value)) {
+          series.set(v.value, new Array(state.history.length).fill(0));
+        }
+        series.get(v.value)[i] = v.count;
+      });
+    });
+    const rows = current.values.slice();
+    const seen = new Set(rows.map((v) => v.value));
+    series.forEach((_, value) => {
+      if (!seen.has(value)) {
+        rows.push({ value: value, count: 0, percentage: 0, bytes: 0 });
+      }
+    });
+
+    const tbody = byId("values");
+    tbody.replaceChildren(...rows.map((v) => {
+      const points = series.get(v.value) || [];
+      const tr = document.createElement("tr");
+      tr.appendChild(cell(v.value));
+      tr.appendChild(cell(number(v.count), "num"));
+      tr.appendChild(cell(v.percentage.toFixed(1), "num"));
+      tr.appendChild(cell(bytes(v.bytes), "num"));
+      tr.appendChild(cell(points.length ? number(points[points.length - 1]) : "-", "num"));
+      const td = document.createElement("td");
+      td.appendChild(sparkline(points));
+      tr.appendChild(td);
+      return tr;
+    }));
+  }
+
+  function setStatus(text, className) {
+    const status = byId("status");
+    status.textContent = text;
+    status.className = "status " + className;
+  }
+
+  const source = new EventSource("events");
+
+  source.addEventListener("open", () => setStatus("live", "live"));
+  source.addEventListener("error", () => setStatus("reconnecting", "down"));
+
+  source.addEventListener("snapshot", (e) => {
+    const s = JSON.parse(e.data);
+    state.windows = s.windows;
+    state.current = s.current;
+    state.history = s.history;
+    byId("key").textContent = s.attribute_key;
+    byId("notice").hidden = true;
+    render();
+  });
+
+  source.addEventListener("current", (e) => {
+    state.current = JSON.parse(e.data);
+    render();
+  });
+
+  source.addEventListener("window", (e) => {
+    const report = JSON.parse(e.data);
+    if (report.missed_windows) {
+      const notice = byId("notice");
+      notice.textContent = report.missed_windows + " window(s) were missed while the server was down.";
+      notice.hidden = false;
+    }
+    state.history.push(report);
+    if (state.history.length > state.windows) {
+      state.history.splice(0, state.history.length - state.windows);
+    }
+    render();
+  });
+
+  source.addEventListener("dropped", (e) => {
+    const notice = byId("notice");
+    notice.textContent = JSON.parse(e.data).count + " window(s) were missed because the page fell behind.";
+    notice.hidden = false;
+  });
+})();
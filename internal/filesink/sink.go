@@ -0,0 +1,577 @@
+package filesink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+const (
+	activeSuffix  = ".ndjson"
+	rotatedSuffix = ".ndjson.gz"
+
+	// maxSweepInterval bounds how long a value's file outlives its period when
+	// no more records arrive for the value
+	maxSweepInterval = time.Minute
+)
+
+// Config configures a Sink
+type Config struct {
+	// Dir is the root directory; each value gets its own subdirectory
+	Dir string
+	// MaxFileSize rotates a value's file once it would grow beyond this many bytes (0 disables)
+	MaxFileSize int64
+	// RotateInterval starts a new file per value every interval, which also names the files
+	RotateInterval time.Duration
+	// MaxTotalSize caps the bytes of all files; the oldest rotated files are deleted first (0 disables)
+	MaxTotalSize int64
+	// QueueSize caps the requests waiting to be written
+	QueueSize int
+	// Metrics records file sink metrics; nil uses an unregistered instance
+	Metrics *metrics.Metrics
+}
+
+// Sink writes every log record as an OTLP-JSON line to a file per extracted
+// value, e.g. <dir>/<value>/2026-10-16T10.ndjson. Rotated files are gzipped to
+// <period>.<n>.ndjson.gz. Records are encoded by the caller and written by a
+// background worker; requests are dropped when the worker falls behind. The
+// worker also rotates the files of values that went quiet once their period
+// has passed.
+type Sink struct {
+	config  Config
+	logger  *logger.Logger
+	marshal protojson.MarshalOptions
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []chunk
+	done   chan struct{}
+
+	// Owned by the worker
+	files   map[string]*activeFile
+	rotated []rotatedFile
+	total   int64
+	now     func() time.Time
+}
+
+// chunk is the encoded lines of a request's records for a single value
+type chunk struct {
+	dir     string
+	lines   []byte
+	records int
+}
+
+// activeFile is the file a value's records are currently appended to
+type activeFile struct {
+	dir    string
+	period string
+	size   int64
+}
+
+func (f *activeFile) path(root string) string {
+	return filepath.Join(root, f.dir, f.period+activeSuffix)
+}
+
+// rotatedFile is a gzipped file eligible for deletion under the disk budget
+type rotatedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// New creates the sink directory, gzips files left over from earlier periods
+// and starts the writer
+func New(config Config, logger *logger.Logger) (*Sink, error) {
+	return newWithClock(config, logger, time.Now)
+}
+
+func newWithClock(config Config, logger *logger.Logger, now func() time.Time) (*Sink, error) {
+	if config.Metrics == nil {
+		config.Metrics = metrics.New(nil)
+	}
+	s := &Sink{
+		config:  config,
+		logger:  logger,
+		marshal: protojson.MarshalOptions{UseEnumNumbers: true},
+		queue:   make(chan []chunk, config.QueueSize),
+		done:    make(chan struct{}),
+		files:   make(map[string]*activeFile),
+		now:     now,
+	}
+
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create file sink directory: %w", err)
+	}
+	if err := s.scan(); err != nil {
+		return nil, err
+	}
+	s.enforceBudget()
+
+	go s.run()
+	return s, nil
+}
+
+// Write encodes the records of req and queues them for writing. values holds
+// the extracted value of every non-nil log record, in request order.
+func (s *Sink) Write(req *collectorpb.ExportLogsServiceRequest, values []string) {
+	chunks := s.encode(req, values)
+	if len(chunks) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- chunks:
+	default:
+		records := 0
+		for _, c := range chunks {
+			records += c.records
+		}
+		s.config.Metrics.FileSinkRecordsTotal.WithLabelValues("dropped").Add(float64(records))
+		s.logger.Warnw("File sink queue full, dropping records", "log_records", records)
+	}
+}
+
+// Saturated returns an error when the write queue is full, so new requests would be dropped
+func (s *Sink) Saturated() error {
+	if len(s.queue) == cap(s.queue) {
+		return fmt.Errorf("file sink queue is full")
+	}
+	return nil
+}
+
+// Close writes the queued records and stops the writer. Active files are left
+// uncompressed and gzipped on the next start once their period has passed.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// encode renders every record as a single-record OTLP-JSON export request
+// line, grouped by value in first-seen order
+func (s *Sink) encode(req *collectorpb.ExportLogsServiceRequest, values []string) []chunk {
+	var chunks []chunk
+	index := make(map[string]int)
+	i := 0
+
+	// Walks the request like LogsService.extractObservations so values line up with records
+	for _, resourceLog := range req.GetResourceLogs() {
+		if resourceLog == nil {
+			continue
+		}
+		resource := s.object(resourceLog.Resource)
+
+		for _, scopeLog := range resourceLog.ScopeLogs {
+			if scopeLog == nil {
+				continue
+			}
+			scope := s.object(scopeLog.Scope)
+
+			for _, logRecord := range scopeLog.LogRecords {
+				if logRecord == nil {
+					continue
+				}
+				if i >= len(values) {
+					return chunks
+				}
+				dir := sanitize(values[i])
+				i++
+
+				n, ok := index[dir]
+				if !ok {
+					n = len(chunks)
+					index[dir] = n
+					chunks = append(chunks, chunk{dir: dir})
+				}
+
+				line := s.line(resource, resourceLog.SchemaUrl, scope, scopeLog.SchemaUrl, logRecord)
+				chunks[n].lines = append(chunks[n].lines, line...)
+				chunks[n].records++
+			}
+		}
+	}
+
+	return chunks
+}
+
+// object marshals m to OTLP-JSON, nil when m is unset
+func (s *Sink) object(m proto.Message) []byte {
+	if m == nil || !m.ProtoReflect().IsValid() {
+		return nil
+	}
+	b, err := s.marshal.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// line renders a record with its resource and scope as one export request.
+// OTLP-JSON encodes trace and span IDs as hex rather than protobuf JSON's base64.
+func (s *Sink) line(resource []byte, resourceSchema string, scope []byte, scopeSchema string, record *logspb.LogRecord) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"resourceLogs":[{`)
+	if resource != nil {
+		buf.WriteString(`"resource":`)
+		buf.Write(resource)
+		buf.WriteByte(',')
+	}
+	if resourceSchema != "" {
+		writeString(&buf, "schemaUrl", resourceSchema)
+	}
+	buf.WriteString(`"scopeLogs":[{`)
+	if scope != nil {
+		buf.WriteString(`"scope":`)
+		buf.Write(scope)
+		buf.WriteByte(',')
+	}
+	if scopeSchema != "" {
+		writeString(&buf, "schemaUrl", scopeSchema)
+	}
+	buf.WriteString(`"logRecords":[`)
+
+	ids := record
+	if len(record.TraceId) > 0 || len(record.SpanId) > 0 {
+		ids = proto.Clone(record).(*logspb.LogRecord)
+		ids.TraceId, ids.SpanId = nil, nil
+	}
+	body, _ := s.marshal.Marshal(ids)
+	body = bytes.TrimSpace(body)
+
+	buf.WriteByte('{')
+	if len(record.TraceId) > 0 {
+		writeString(&buf, "traceId", hex.EncodeToString(record.TraceId))
+	}
+	if len(record.SpanId) > 0 {
+		writeString(&buf, "spanId", hex.EncodeToString(record.SpanId))
+	}
+	if rest := bytes.TrimSpace(body[1:]); len(rest) > 0 && rest[0] == '}' {
+		// Drop the trailing comma of the IDs when the record has no other fields
+		if len(record.TraceId) > 0 || len(record.SpanId) > 0 {
+			buf.Truncate(buf.Len() - 1)
+		}
+		buf.WriteByte('}')
+	} else {
+		buf.Write(body[1:])
+	}
+
+	buf.WriteString("]}]}]}\n")
+	return buf.Bytes()
+}
+
+// writeString writes "key":"value", with a trailing comma
+func writeString(buf *bytes.Buffer, key, value string) {
+	quoted, _ := json.Marshal(value)
+	fmt.Fprintf(buf, "%q:%s,", key, quoted)
+}
+
+// sanitize maps a value to a safe directory name. Distinct values may share a directory.
+func sanitize(value string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, value)
+	if sanitized == "" || strings.Trim(sanitized, ".") == "" {
+		return "_" + sanitized
+	}
+	return sanitized
+}
+
+// periodName names the rotation period containing t, at the precision of the interval
+func periodName(t time.Time, interval time.Duration) string {
+	t = t.UTC().Truncate(interval)
+	switch {
+	case interval >= 24*time.Hour && interval%(24*time.Hour) == 0:
+		return t.Format("2006-01-02")
+	case interval >= time.Hour && interval%time.Hour == 0:
+		return t.Format("2006-01-02T15")
+	case interval >= time.Minute && interval%time.Minute == 0:
+		return t.Format("2006-01-02T15-04")
+	default:
+		return t.Format("2006-01-02T15-04-05")
+	}
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	sweepInterval := min(s.config.RotateInterval, maxSweepInterval)
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case chunks, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			now := s.now()
+			for _, c := range chunks {
+				if err := s.append(c, now); err != nil {
+					s.config.Metrics.FileSinkRecordsTotal.WithLabelValues("failed").Add(float64(c.records))
+					s.logger.Errorw("Failed to write log records to file sink", "dir", c.dir, "error", err)
+					continue
+				}
+				s.config.Metrics.FileSinkRecordsTotal.WithLabelValues("written").Add(float64(c.records))
+			}
+			s.enforceBudget()
+		case <-ticker.C:
+			s.sweep(s.now())
+		}
+	}
+}
+
+// sweep rotates the active files whose period has passed and forgets them,
+// so values that stopped receiving records neither keep an uncompressed file
+// nor an entry in files
+func (s *Sink) sweep(now time.Time) {
+	period := periodName(now, s.config.RotateInterval)
+	for dir, f := range s.files {
+		if f.period != period && s.rotate(f) {
+			delete(s.files, dir)
+		}
+	}
+	s.enforceBudget()
+}
+
+// append writes c to its value's active file, rotating it first when its
+// period has passed or c would take it beyond MaxFileSize
+func (s *Sink) append(c chunk, now time.Time) error {
+	period := periodName(now, s.config.RotateInterval)
+
+	f, ok := s.files[c.dir]
+	if !ok {
+		if err := os.MkdirAll(filepath.Join(s.config.Dir, c.dir), 0o755); err != nil {
+			return err
+		}
+		f = &activeFile{dir: c.dir, period: period}
+		s.files[c.dir] = f
+	}
+
+	if f.period != period {
+		s.rotate(f)
+		f.period, f.size = period, 0
+	}
+	if s.config.MaxFileSize > 0 && f.size > 0 && f.size+int64(len(c.lines)) > s.config.MaxFileSize {
+		s.rotate(f)
+		f.size = 0
+	}
+
+	file, err := os.OpenFile(f.path(s.config.Dir), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	n, err := file.Write(c.lines)
+	f.size += int64(n)
+	s.total += int64(n)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	s.config.Metrics.FileSinkBytes.Set(float64(s.total))
+	return err
+}
+
+// rotate gzips f's file to the next free <period>.<n>.ndjson.gz and reports
+// whether f's file is gone
+func (s *Sink) rotate(f *activeFile) bool {
+	src := f.path(s.config.Dir)
+	info, err := os.Stat(src)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	var dst string
+	for n := 1; ; n++ {
+		dst = filepath.Join(s.config.Dir, f.dir, fmt.Sprintf("%s.%d%s", f.period, n, rotatedSuffix))
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			break
+		}
+	}
+
+	size, err := compress(src, dst)
+	if err != nil {
+		s.logger.Errorw("Failed to compress rotated file, keeping it uncompressed", "file", src, "error", err)
+		return false
+	}
+	s.config.Metrics.FileSinkRotationsTotal.Inc()
+
+	s.total += size - info.Size()
+	s.rotated = append(s.rotated, rotatedFile{path: dst, size: size, modTime: s.now()})
+	s.config.Metrics.FileSinkBytes.Set(float64(s.total))
+	return true
+}
+
+// enforceBudget deletes the oldest rotated files until the sink fits
+// MaxTotalSize. Active files count toward the budget: when deleting every
+// rotated file is not enough, the largest active files are rotated so they
+// can be deleted too.
+func (s *Sink) enforceBudget() {
+	s.deleteOldest()
+
+	if s.overBudget() {
+		active := make([]*activeFile, 0, len(s.files))
+		for _, f := range s.files {
+			active = append(active, f)
+		}
+		sort.Slice(active, func(i, j int) bool { return active[i].size > active[j].size })
+
+		for _, f := range active {
+			if !s.overBudget() {
+				break
+			}
+			if s.rotate(f) {
+				delete(s.files, f.dir)
+			}
+			s.deleteOldest()
+		}
+	}
+	s.config.Metrics.FileSinkBytes.Set(float64(s.total))
+}
+
+func (s *Sink) overBudget() bool {
+	return s.config.MaxTotalSize > 0 && s.total > s.config.MaxTotalSize
+}
+
+// deleteOldest deletes the oldest rotated files while the sink exceeds
+// MaxTotalSize, and then their directory once it is empty and has no active file
+func (s *Sink) deleteOldest() {
+	for s.overBudget() && len(s.rotated) > 0 {
+		oldest := s.rotated[0]
+		s.rotated = s.rotated[1:]
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			s.logger.Errorw("Failed to delete rotated file", "file", oldest.path, "error", err)
+			continue
+		}
+		s.total -= oldest.size
+		s.config.Metrics.FileSinkDeletedFilesTotal.Inc()
+
+		dir := filepath.Dir(oldest.path)
+		if _, active := s.files[filepath.Base(dir)]; !active {
+			// Fails while the directory still holds files
+			_ = os.Remove(dir)
+		}
+	}
+}
+
+// scan accounts the files left by a previous run. Rotated files are ordered by
+// age and active files of the current period resume; older ones are rotated.
+func (s *Sink) scan() error {
+	dirs, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to list file sink directory: %w", err)
+	}
+
+	current := periodName(s.now(), s.config.RotateInterval)
+	var stale []*activeFile
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.config.Dir, dir.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to list file sink directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			name := entry.Name()
+			path := filepath.Join(s.config.Dir, dir.Name(), name)
+
+			switch {
+			case strings.HasSuffix(name, rotatedSuffix):
+				s.total += info.Size()
+				s.rotated = append(s.rotated, rotatedFile{path: path, size: info.Size(), modTime: info.ModTime()})
+			case strings.HasSuffix(name, activeSuffix):
+				s.total += info.Size()
+				f := &activeFile{dir: dir.Name(), period: strings.TrimSuffix(name, activeSuffix), size: info.Size()}
+				if f.period == current {
+					s.files[f.dir] = f
+				} else {
+					stale = append(stale, f)
+				}
+			}
+		}
+	}
+
+	sort.Slice(s.rotated, func(i, j int) bool { return s.rotated[i].modTime.Before(s.rotated[j].modTime) })
+	for _, f := range stale {
+		s.rotate(f)
+	}
+	s.config.Metrics.FileSinkBytes.Set(float64(s.total))
+
+	return nil
+}
+
+// compress gzips src to dst and removes src, returning the size of dst
+func compress(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if _, err := io.Copy(gz, in); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return 0, err
+	}
+
+	return info.Size(), os.Remove(src)
+}
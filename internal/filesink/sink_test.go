@@ -0,0 +1,272 @@
+package filesink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+var testStart = time.Date(2026, 10, 16, 10, 15, 0, 0, time.UTC)
+
+func newTestSink(t *testing.T, config Config, now time.Time) *Sink {
+	t.Helper()
+
+	testLogger, _ := logger.New(false)
+	if config.RotateInterval == 0 {
+		config.RotateInterval = time.Hour
+	}
+	config.QueueSize = 10
+	sink, err := newWithClock(config, testLogger, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	return sink
+}
+
+func testRequest() *collectorpb.ExportLogsServiceRequest {
+	return &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{
+				Key:   "service.name",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}},
+			}}},
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope: &commonpb.InstrumentationScope{Name: "api"},
+				LogRecords: []*logspb.LogRecord{
+					{
+						SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+						Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "payment failed"}},
+						TraceId:        []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+						SpanId:         []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+					},
+					{},
+					nil,
+					{Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "added item"}}},
+				},
+			}},
+		}},
+	}
+}
+
+// readLines returns the lines of a plain or gzipped file
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("Failed to read gzip %s: %v", path, err)
+		}
+		r = gz
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestSink_WritesOTLPJSONPerValue(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir}, testStart)
+
+	sink.Write(testRequest(), []string{"checkout", "../cart", "cart"})
+	sink.Close()
+
+	checkout := readLines(t, filepath.Join(dir, "checkout", "2026-10-16T10.ndjson"))
+	if len(checkout) != 1 {
+		t.Fatalf("Expected 1 checkout line, got %d", len(checkout))
+	}
+
+	var line struct {
+		ResourceLogs []struct {
+			Resource  map[string]interface{} `json:"resource"`
+			ScopeLogs []struct {
+				Scope      map[string]interface{}   `json:"scope"`
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal([]byte(checkout[0]), &line); err != nil {
+		t.Fatalf("Line is not valid JSON: %v\n%s", err, checkout[0])
+	}
+	record := line.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record["traceId"] != "5b8efff798038103d269b633813fc60c" || record["spanId"] != "eee19b7ec3c1b174" {
+		t.Errorf("Expected hex trace and span IDs, got %v and %v", record["traceId"], record["spanId"])
+	}
+	if record["severityNumber"] != float64(17) {
+		t.Errorf("Expected the severity as an enum number, got %v", record["severityNumber"])
+	}
+	if line.ResourceLogs[0].Resource == nil || line.ResourceLogs[0].ScopeLogs[0].Scope["name"] != "api" {
+		t.Errorf("Expected the resource and scope to be kept, got %s", checkout[0])
+	}
+
+	if lines := readLines(t, filepath.Join(dir, ".._cart", "2026-10-16T10.ndjson")); len(lines) != 1 || !strings.Contains(lines[0], `"logRecords":[{}]`) {
+		t.Errorf("Expected the empty record under a sanitized directory, got %v", lines)
+	}
+	if lines := readLines(t, filepath.Join(dir, "cart", "2026-10-16T10.ndjson")); len(lines) != 1 || !strings.Contains(lines[0], "added item") {
+		t.Errorf("Expected the last record in cart, got %v", lines)
+	}
+}
+
+func TestSink_RotatesBySizeAndTime(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir, MaxFileSize: 100}, testStart)
+	defer sink.Close()
+
+	line := []byte(strings.Repeat("x", 59) + "\n")
+	for _, now := range []time.Time{testStart, testStart.Add(time.Minute), testStart.Add(time.Hour)} {
+		if err := sink.append(chunk{dir: "checkout", lines: line, records: 1}, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// The second line exceeds the size limit, the third starts a new hour
+	for _, name := range []string{"2026-10-16T10.1.ndjson.gz", "2026-10-16T10.2.ndjson.gz", "2026-10-16T11.ndjson"} {
+		if lines := readLines(t, filepath.Join(dir, "checkout", name)); len(lines) != 1 {
+			t.Errorf("Expected 1 line in %s, got %d", name, len(lines))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkout", "2026-10-16T10.ndjson")); !os.IsNotExist(err) {
+		t.Errorf("Expected the rotated file to be removed, got %v", err)
+	}
+}
+
+func TestSink_DeletesOldestWithinBudget(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir, MaxFileSize: 1000, MaxTotalSize: 2000}, testStart)
+	defer sink.Close()
+
+	// Random data barely compresses, so every rotated file keeps most of its size
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 4; i++ {
+		lines := make([]byte, 900)
+		random.Read(lines)
+		_ = sink.append(chunk{dir: "checkout", lines: lines, records: 1}, testStart.Add(time.Duration(i)*time.Hour))
+		sink.enforceBudget()
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "checkout"))
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if sink.total > 2000 {
+		t.Errorf("Expected the sink to fit its budget, got %d bytes in %v", sink.total, names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkout", "2026-10-16T10.1.ndjson.gz")); !os.IsNotExist(err) {
+		t.Errorf("Expected the oldest rotated file to be deleted, got %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkout", "2026-10-16T13.ndjson")); err != nil {
+		t.Errorf("Expected the active file to be kept, got %v", names)
+	}
+}
+
+func TestSink_SweepRotatesQuietValues(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir}, testStart)
+	defer sink.Close()
+
+	line := []byte("{}\n")
+	for _, value := range []string{"checkout", "cart"} {
+		if err := sink.append(chunk{dir: value, lines: line, records: 1}, testStart); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	sink.sweep(testStart.Add(30 * time.Minute))
+	if len(sink.files) != 2 {
+		t.Fatalf("Expected files of the current period to stay active, got %d", len(sink.files))
+	}
+
+	next := testStart.Add(time.Hour)
+	if err := sink.append(chunk{dir: "cart", lines: line, records: 1}, next); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink.sweep(next.Add(time.Minute))
+
+	if _, ok := sink.files["checkout"]; ok || len(sink.files) != 1 {
+		t.Errorf("Expected the quiet value's file to be forgotten, got %v", sink.files)
+	}
+	if lines := readLines(t, filepath.Join(dir, "checkout", "2026-10-16T10.1.ndjson.gz")); len(lines) != 1 {
+		t.Errorf("Expected the quiet value's file to be compressed, got %d lines", len(lines))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "checkout", "2026-10-16T10.ndjson")); !os.IsNotExist(err) {
+		t.Errorf("Expected no uncompressed file left for the quiet value, got %v", err)
+	}
+}
+
+func TestSink_BudgetCountsActiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir, MaxTotalSize: 1500}, testStart)
+	defer sink.Close()
+
+	random := rand.New(rand.NewSource(1))
+	for _, value := range []string{"checkout", "cart"} {
+		lines := make([]byte, 900)
+		random.Read(lines)
+		_ = sink.append(chunk{dir: value, lines: lines, records: 1}, testStart)
+	}
+	sink.enforceBudget()
+
+	if sink.total > 1500 {
+		t.Errorf("Expected active files to be rotated and deleted to fit the budget, got %d bytes", sink.total)
+	}
+	if len(sink.files) != 1 {
+		t.Errorf("Expected one active file to be kept, got %d", len(sink.files))
+	}
+}
+
+func TestSink_RotatesStaleFilesOnStart(t *testing.T) {
+	dir := t.TempDir()
+	sink := newTestSink(t, Config{Dir: dir}, testStart)
+	sink.Write(testRequest(), []string{"checkout", "checkout", "checkout"})
+	sink.Close()
+
+	restarted := newTestSink(t, Config{Dir: dir}, testStart.Add(2*time.Hour))
+	defer restarted.Close()
+
+	if lines := readLines(t, filepath.Join(dir, "checkout", "2026-10-16T10.1.ndjson.gz")); len(lines) != 3 {
+		t.Errorf("Expected the previous hour's 3 lines to be compressed on start, got %d", len(lines))
+	}
+}
+
+func TestPeriodName(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		want     string
+	}{
+		{interval: 24 * time.Hour, want: "2026-10-16"},
+		{interval: time.Hour, want: "2026-10-16T10"},
+		{interval: 15 * time.Minute, want: "2026-10-16T10-15"},
+		{interval: 30 * time.Second, want: "2026-10-16T10-15-00"},
+	}
+
+	for _, tt := range tests {
+		if got := periodName(testStart.Add(20*time.Second), tt.interval); got != tt.want {
+			t.Errorf("periodName(%s) = %q, want %q", tt.interval, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,575 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+const (
+	// ModeSync forwards every request before acknowledging it and returns the
+	// downstream outcome to the client
+	ModeSync = "sync"
+	// ModeAsync acknowledges requests once they are queued and forwards them in batches
+	ModeAsync = "async"
+)
+
+// Config configures a Forwarder
+type Config struct {
+	// Endpoints are the host:port addresses of the downstream OTLP gRPC receivers
+	Endpoints []string
+	// Insecure disables TLS to the endpoints
+	Insecure bool
+	// Mode is ModeSync or ModeAsync
+	Mode string
+	// Timeout bounds a single export attempt
+	Timeout time.Duration
+	// QueueSize caps the requests queued per endpoint in async mode
+	QueueSize int
+	// BatchSize is the number of log records at which an async batch is sent
+	BatchSize int
+	// BatchTimeout is the longest a queued request waits for its batch to fill
+	BatchTimeout time.Duration
+	// MaxRetries is the number of retries after a retryable failure
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Routing routes records to endpoints by attribute value, in addition to Endpoints
+	Routing RouteConfig
+	// Metrics records forwarding metrics; nil uses an unregistered instance
+	Metrics *metrics.Metrics
+}
+
+// Forwarder tees OTLP log export requests to downstream OTLP gRPC endpoints
+// and, when routes are configured, routes their records by attribute value
+type Forwarder struct {
+	config Config
+	logger *logger.Logger
+	// tees receive every request unchanged
+	tees []*destination
+
+	// mu guards the routing; planned requests hold references to their
+	// destinations instead, so it is never held while sending
+	mu sync.RWMutex
+	// routed are the route endpoints, keyed by endpoint
+	routed map[string]*destination
+	router *router
+	// routing is the active routing, which starts as config.Routing
+	routing RouteConfig
+	// removing tracks the destinations SetRouting removed that are still
+	// waiting for their planned requests to be sent
+	removing sync.WaitGroup
+}
+
+// delivery is a request bound for a single destination
+type delivery struct {
+	destination *destination
+	req         *collectorpb.ExportLogsServiceRequest
+	records     int
+	// routed deliveries hold disjoint subsets of the request's records
+	routed bool
+}
+
+// New connects to every configured endpoint and, in async mode, starts their
+// batching workers. Connections are established lazily, so unavailable
+// endpoints do not prevent startup.
+func New(config Config, logger *logger.Logger) (*Forwarder, error) {
+	if config.Metrics == nil {
+		config.Metrics = metrics.New(nil)
+	}
+	f := &Forwarder{
+		config:  config,
+		logger:  logger,
+		routed:  make(map[string]*destination),
+		routing: config.Routing,
+	}
+
+	for _, endpoint := range config.Endpoints {
+		d, err := newDestination(endpoint, &f.config, logger)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tees = append(f.tees, d)
+	}
+
+	if config.Routing.Enabled() {
+		f.router = newRouter(config.Routing, config.Metrics)
+		for _, endpoint := range config.Routing.Endpoints() {
+			d, err := newDestination(endpoint, &f.config, logger)
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			f.routed[endpoint] = d
+		}
+	}
+
+	return f, nil
+}
+
+// Forward sends req to every tee endpoint and its records to their route
+// endpoints. In sync mode it waits for all of them and returns the largest
+// downstream rejection, or the first endpoint's error with its gRPC status
+// code. In async mode it only queues req; records that do not fit in a full
+// queue are dropped and reported as rejected. Records matching no route under
+// UnmatchedReject are reported as rejected in both modes.
+func (f *Forwarder) Forward(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsPartialSuccess, error) {
+	return f.Plan(req).Send(ctx)
+}
+
+// Planned is a request split under the routing active when it was planned.
+// It holds the endpoints it is bound for, so they stay open until Send returns
+// even if the routing changes meanwhile.
+type Planned struct {
+	forwarder  *Forwarder
+	deliveries []delivery
+	records    int
+	unmatched  int
+}
+
+// Plan splits req under the active routing, so a caller can plan while the
+// request is counted and send after. Send must be called exactly once to
+// release the planned endpoints.
+func (f *Forwarder) Plan(req *collectorpb.ExportLogsServiceRequest) *Planned {
+	records := countRecords(req.ResourceLogs)
+	if records == 0 {
+		return &Planned{}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	deliveries, unmatched := f.plan(req, records)
+	for _, dl := range deliveries {
+		dl.destination.acquire()
+	}
+	return &Planned{forwarder: f, deliveries: deliveries, records: records, unmatched: unmatched}
+}
+
+// Send delivers the planned request as described by Forward, then releases
+// its endpoints
+func (p *Planned) Send(ctx context.Context) (*collectorpb.ExportLogsPartialSuccess, error) {
+	partial := &collectorpb.ExportLogsPartialSuccess{}
+	if p.records == 0 {
+		return partial, nil
+	}
+	f := p.forwarder
+	defer func() {
+		for _, dl := range p.deliveries {
+			dl.destination.release()
+		}
+	}()
+
+	deliveries, unmatched, records := p.deliveries, p.unmatched, p.records
+	var messages []string
+	if unmatched > 0 {
+		messages = append(messages, fmt.Sprintf("%d log records matched no route", unmatched))
+	}
+
+	// Tees each see every record, so their rejections overlap; routed
+	// deliveries are disjoint, so theirs add up
+	teeRejected, routedRejected := int64(0), int64(unmatched)
+	reject := func(dl delivery, rejected int64) {
+		if dl.routed {
+			routedRejected += rejected
+		} else {
+			teeRejected = max(teeRejected, rejected)
+		}
+	}
+
+	if f.config.Mode == ModeAsync {
+		var full []string
+		for _, dl := range deliveries {
+			if !dl.destination.enqueue(dl.req, dl.records) {
+				full = append(full, dl.destination.endpoint)
+				reject(dl, int64(dl.records))
+			}
+		}
+		if len(full) > 0 {
+			messages = append(messages, fmt.Sprintf("forward queue full for %s", strings.Join(full, ", ")))
+		}
+	} else {
+		responses := make([]*collectorpb.ExportLogsServiceResponse, len(deliveries))
+		errs := make([]error, len(deliveries))
+		var wg sync.WaitGroup
+		for i, dl := range deliveries {
+			wg.Add(1)
+			go func(i int, dl delivery) {
+				defer wg.Done()
+				responses[i], errs[i] = dl.destination.export(ctx, dl.req, dl.records)
+			}(i, dl)
+		}
+		wg.Wait()
+
+		for i, dl := range deliveries {
+			if err := errs[i]; err != nil {
+				st := status.Convert(err)
+				return nil, status.Errorf(st.Code(), "forwarding to %s failed: %s", dl.destination.endpoint, st.Message())
+			}
+			ps := responses[i].GetPartialSuccess()
+			reject(dl, ps.GetRejectedLogRecords())
+			if ps.GetErrorMessage() != "" {
+				messages = append(messages, fmt.Sprintf("%s: %s", dl.destination.endpoint, ps.GetErrorMessage()))
+			}
+		}
+	}
+
+	partial.RejectedLogRecords = min(max(teeRejected, routedRejected), int64(records))
+	partial.ErrorMessage = strings.Join(messages, "; ")
+
+	return partial, nil
+}
+
+// plan returns the deliveries of req and the number of records rejected for
+// matching no route
+func (f *Forwarder) plan(req *collectorpb.ExportLogsServiceRequest, records int) ([]delivery, int) {
+	deliveries := make([]delivery, 0, len(f.tees)+len(f.routed))
+	for _, d := range f.tees {
+		deliveries = append(deliveries, delivery{destination: d, req: req, records: records})
+	}
+	if f.router == nil {
+		return deliveries, 0
+	}
+
+	split, unmatched := f.router.split(req)
+	for _, endpoint := range f.routing.Endpoints() {
+		if routedReq, ok := split[endpoint]; ok {
+			deliveries = append(deliveries, delivery{
+				destination: f.routed[endpoint],
+				req:         routedReq,
+				records:     countRecords(routedReq.ResourceLogs),
+				routed:      true,
+			})
+		}
+	}
+	return deliveries, unmatched
+}
+
+// SetRouting replaces the routes without waiting for the requests being
+// forwarded. Endpoints kept by the new routing keep their connections and
+// queues; endpoints it removes are closed in the background once the requests
+// planned for them are sent, sending what they have queued.
+func (f *Forwarder) SetRouting(routing RouteConfig) error {
+	f.mu.Lock()
+
+	routed := make(map[string]*destination)
+	var added []*destination
+	if routing.Enabled() {
+		for _, endpoint := range routing.Endpoints() {
+			if d, ok := f.routed[endpoint]; ok {
+				routed[endpoint] = d
+				continue
+			}
+			d, err := newDestination(endpoint, &f.config, f.logger)
+			if err != nil {
+				f.mu.Unlock()
+				for _, d := range added {
+					d.close()
+				}
+				return err
+			}
+			routed[endpoint] = d
+			added = append(added, d)
+		}
+	}
+
+	var removed []*destination
+	for endpoint, d := range f.routed {
+		if _, ok := routed[endpoint]; !ok {
+			removed = append(removed, d)
+		}
+	}
+
+	f.routed, f.routing, f.router = routed, routing, nil
+	if routing.Enabled() {
+		f.router = newRouter(routing, f.config.Metrics)
+	}
+	f.removing.Add(len(removed))
+	f.mu.Unlock()
+
+	for _, d := range removed {
+		go func(d *destination) {
+			defer f.removing.Done()
+			d.close()
+		}(d)
+	}
+	f.logger.Infow("Forward routing updated",
+		"routes", len(routing.Routes),
+		"added_endpoints", len(added),
+		"removed_endpoints", len(removed),
+	)
+	return nil
+}
+
+// Saturated returns an error naming the first endpoint whose async queue is
+// full, so new requests would be dropped
+func (f *Forwarder) Saturated() error {
+	for _, d := range f.tees {
+		if d.saturated() {
+			return fmt.Errorf("forward queue for %s is full", d.endpoint)
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, d := range f.routed {
+		if d.saturated() {
+			return fmt.Errorf("forward queue for %s is full", d.endpoint)
+		}
+	}
+	return nil
+}
+
+// Close sends the queued batches and closes the connections. Batches still
+// queued at shutdown get a single attempt each.
+func (f *Forwarder) Close() error {
+	for _, d := range f.tees {
+		d.close()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, d := range f.routed {
+		d.close()
+	}
+	f.removing.Wait()
+	return nil
+}
+
+// destination is a single downstream endpoint with its async queue
+type destination struct {
+	endpoint string
+	config   *Config
+	conn     *grpc.ClientConn
+	client   collectorpb.LogsServiceClient
+	logger   *logger.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	// refs counts the planned requests bound for the destination; close
+	// waits for released to signal that they are all sent
+	refs     int
+	released *sync.Cond
+	queue    chan *collectorpb.ExportLogsServiceRequest
+	closing  chan struct{}
+	done     chan struct{}
+}
+
+// saturated reports whether the async queue is full
+func (d *destination) saturated() bool {
+	return d.queue != nil && len(d.queue) == cap(d.queue)
+}
+
+func newDestination(endpoint string, config *Config, logger *logger.Logger) (*destination, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create forwarding client for %s: %w", endpoint, err)
+	}
+
+	d := &destination{
+		endpoint: endpoint,
+		config:   config,
+		conn:     conn,
+		client:   collectorpb.NewLogsServiceClient(conn),
+		logger:   logger.With("endpoint", endpoint),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	d.released = sync.NewCond(&d.mu)
+	if config.Mode == ModeAsync {
+		d.queue = make(chan *collectorpb.ExportLogsServiceRequest, config.QueueSize)
+		go d.run()
+	} else {
+		close(d.done)
+	}
+
+	return d, nil
+}
+
+// enqueue queues req without blocking, reporting whether there was room
+func (d *destination) enqueue(req *collectorpb.ExportLogsServiceRequest, records int) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "dropped").Add(float64(records))
+		return false
+	}
+	select {
+	case d.queue <- req:
+		d.config.Metrics.ForwardQueueLength.WithLabelValues(d.endpoint).Set(float64(len(d.queue)))
+		return true
+	default:
+		d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "dropped").Add(float64(records))
+		d.logger.Warnw("Forward queue full, dropping request", "log_records", records)
+		return false
+	}
+}
+
+// run merges queued requests into batches of up to BatchSize records and sends them
+func (d *destination) run() {
+	defer close(d.done)
+
+	var batch []*logspb.ResourceLogs
+	records := 0
+	timer := time.NewTimer(d.config.BatchTimeout)
+	timer.Stop()
+
+	flush := func() {
+		timer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		req := &collectorpb.ExportLogsServiceRequest{ResourceLogs: batch}
+		if _, err := d.export(context.Background(), req, records); err != nil {
+			d.logger.Errorw("Failed to forward batch, dropping it", "log_records", records, "error", err)
+		}
+		batch, records = nil, 0
+	}
+
+	for {
+		select {
+		case req, ok := <-d.queue:
+			if !ok {
+				flush()
+				return
+			}
+			d.config.Metrics.ForwardQueueLength.WithLabelValues(d.endpoint).Set(float64(len(d.queue)))
+
+			if len(batch) == 0 {
+				timer.Reset(d.config.BatchTimeout)
+			}
+			// Requests are merged by resource, so each keeps its resource and scope structure
+			batch = append(batch, req.ResourceLogs...)
+			records += countRecords(req.ResourceLogs)
+			if records >= d.config.BatchSize {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
+
+// export sends req, retrying retryable failures with exponential backoff until
+// ctx is done or the destination closes
+func (d *destination) export(ctx context.Context, req *collectorpb.ExportLogsServiceRequest, records int) (*collectorpb.ExportLogsServiceResponse, error) {
+	backoff := d.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+		resp, err := d.client.Export(attemptCtx, req)
+		cancel()
+
+		if err == nil {
+			rejected := resp.GetPartialSuccess().GetRejectedLogRecords()
+			d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "success").Add(float64(int64(records) - rejected))
+			if rejected > 0 {
+				d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "rejected").Add(float64(rejected))
+				d.logger.Warnw("Downstream partially rejected forwarded logs",
+					"rejected_log_records", rejected,
+					"message", resp.GetPartialSuccess().GetErrorMessage(),
+				)
+			}
+			return resp, nil
+		}
+
+		if !retryable(err) || attempt >= d.config.MaxRetries || ctx.Err() != nil {
+			d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "failed").Add(float64(records))
+			return nil, err
+		}
+
+		d.config.Metrics.ForwardRetriesTotal.WithLabelValues(d.endpoint).Inc()
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "failed").Add(float64(records))
+			return nil, err
+		case <-d.closing:
+			d.config.Metrics.ForwardedLogRecordsTotal.WithLabelValues(d.endpoint, "failed").Add(float64(records))
+			return nil, err
+		}
+		backoff = min(backoff*2, d.config.MaxBackoff)
+	}
+}
+
+// acquire keeps the destination open for a planned request until release
+func (d *destination) acquire() {
+	d.mu.Lock()
+	d.refs++
+	d.mu.Unlock()
+}
+
+func (d *destination) release() {
+	d.mu.Lock()
+	d.refs--
+	if d.refs == 0 {
+		d.released.Broadcast()
+	}
+	d.mu.Unlock()
+}
+
+// close waits for the planned requests to be sent, then sends what is queued
+// and closes the connection
+func (d *destination) close() {
+	d.mu.Lock()
+	for d.refs > 0 {
+		d.released.Wait()
+	}
+	if !d.closed {
+		d.closed = true
+		close(d.closing)
+		if d.queue != nil {
+			close(d.queue)
+		}
+	}
+	d.mu.Unlock()
+
+	<-d.done
+	_ = d.conn.Close()
+}
+
+// retryable reports whether an export error is transient, following the OTLP
+// specification's list of retryable gRPC codes
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// countRecords counts the log records in resourceLogs
+func countRecords(resourceLogs []*logspb.ResourceLogs) int {
+	count := 0
+	for _, resourceLog := range resourceLogs {
+		for _, scopeLog := range resourceLog.GetScopeLogs() {
+			count += len(scopeLog.GetLogRecords())
+		}
+	}
+	return count
+}
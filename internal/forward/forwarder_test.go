@@ -0,0 +1,214 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// logsCollector is an in-process OTLP logs receiver. handle, when set, decides
+// the response to each request; every request that reaches it is recorded.
+type logsCollector struct {
+	collectorpb.UnimplementedLogsServiceServer
+	attempts atomic.Int32
+	handle   func(attempt int32) (*collectorpb.ExportLogsServiceResponse, error)
+	requests chan *collectorpb.ExportLogsServiceRequest
+}
+
+func (c *logsCollector) Export(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+	attempt := c.attempts.Add(1)
+	if c.handle != nil {
+		resp, err := c.handle(attempt)
+		if err != nil {
+			return nil, err
+		}
+		c.requests <- req
+		return resp, nil
+	}
+	c.requests <- req
+	return &collectorpb.ExportLogsServiceResponse{}, nil
+}
+
+// startCollector serves c on a loopback port and returns its address
+func startCollector(t *testing.T, c *logsCollector) string {
+	t.Helper()
+
+	c.requests = make(chan *collectorpb.ExportLogsServiceRequest, 100)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	collectorpb.RegisterLogsServiceServer(server, c)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func testConfig(mode string, endpoints ...string) Config {
+	return Config{
+		Endpoints:      endpoints,
+		Insecure:       true,
+		Mode:           mode,
+		Timeout:        5 * time.Second,
+		QueueSize:      10,
+		BatchSize:      100,
+		BatchTimeout:   20 * time.Millisecond,
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	}
+}
+
+// request builds a request with one resource holding n log records
+func request(n int) *collectorpb.ExportLogsServiceRequest {
+	records := make([]*logspb.LogRecord, n)
+	for i := range records {
+		records[i] = &logspb.LogRecord{}
+	}
+	return &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}}}},
+	}
+}
+
+func receive(t *testing.T, c *logsCollector) *collectorpb.ExportLogsServiceRequest {
+	t.Helper()
+	select {
+	case req := <-c.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a forwarded request")
+		return nil
+	}
+}
+
+func TestForwarder_AsyncBatchesRequests(t *testing.T) {
+	collector := &logsCollector{}
+	endpoint := startCollector(t, collector)
+	testLogger, _ := logger.New(false)
+
+	f, err := New(testConfig(ModeAsync, endpoint), testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 3; i++ {
+		partial, err := f.Forward(context.Background(), request(2))
+		if err != nil || partial.GetRejectedLogRecords() != 0 {
+			t.Fatalf("Unexpected result %v, %v", partial, err)
+		}
+	}
+
+	batch := receive(t, collector)
+	if len(batch.ResourceLogs) != 3 || countRecords(batch.ResourceLogs) != 6 {
+		t.Errorf("Expected one batch of 3 resources and 6 records, got %d resources and %d records",
+			len(batch.ResourceLogs), countRecords(batch.ResourceLogs))
+	}
+}
+
+func TestForwarder_AsyncRejectsWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	collector := &logsCollector{handle: func(int32) (*collectorpb.ExportLogsServiceResponse, error) {
+		<-release
+		return &collectorpb.ExportLogsServiceResponse{}, nil
+	}}
+	endpoint := startCollector(t, collector)
+	testLogger, _ := logger.New(false)
+
+	config := testConfig(ModeAsync, endpoint)
+	config.QueueSize = 1
+	config.BatchSize = 1
+	f, _ := New(config, testLogger)
+	defer f.Close()
+	defer close(release)
+
+	// The first request occupies the worker, the second the queue
+	_, _ = f.Forward(context.Background(), request(1))
+	for collector.attempts.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = f.Forward(context.Background(), request(1))
+
+	partial, err := f.Forward(context.Background(), request(3))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if partial.GetRejectedLogRecords() != 3 || partial.GetErrorMessage() == "" {
+		t.Errorf("Expected the 3 records to be rejected, got %v", partial)
+	}
+}
+
+func TestForwarder_SyncPropagatesPartialSuccess(t *testing.T) {
+	collector := &logsCollector{handle: func(int32) (*collectorpb.ExportLogsServiceResponse, error) {
+		return &collectorpb.ExportLogsServiceResponse{PartialSuccess: &collectorpb.ExportLogsPartialSuccess{
+			RejectedLogRecords: 2,
+			ErrorMessage:       "records too old",
+		}}, nil
+	}}
+	rejecting := startCollector(t, collector)
+	accepting := startCollector(t, &logsCollector{})
+	testLogger, _ := logger.New(false)
+
+	f, _ := New(testConfig(ModeSync, accepting, rejecting), testLogger)
+	defer f.Close()
+
+	partial, err := f.Forward(context.Background(), request(5))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if partial.GetRejectedLogRecords() != 2 || partial.GetErrorMessage() != rejecting+": records too old" {
+		t.Errorf("Unexpected partial success %v", partial)
+	}
+}
+
+func TestForwarder_SyncRetriesTransientErrors(t *testing.T) {
+	collector := &logsCollector{handle: func(attempt int32) (*collectorpb.ExportLogsServiceResponse, error) {
+		if attempt <= 2 {
+			return nil, status.Error(codes.Unavailable, "warming up")
+		}
+		return &collectorpb.ExportLogsServiceResponse{}, nil
+	}}
+	endpoint := startCollector(t, collector)
+	testLogger, _ := logger.New(false)
+
+	f, _ := New(testConfig(ModeSync, endpoint), testLogger)
+	defer f.Close()
+
+	if _, err := f.Forward(context.Background(), request(1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := collector.attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestForwarder_SyncPropagatesErrors(t *testing.T) {
+	collector := &logsCollector{handle: func(int32) (*collectorpb.ExportLogsServiceResponse, error) {
+		return nil, status.Error(codes.InvalidArgument, "malformed")
+	}}
+	endpoint := startCollector(t, collector)
+	testLogger, _ := logger.New(false)
+
+	f, _ := New(testConfig(ModeSync, endpoint), testLogger)
+	defer f.Close()
+
+	_, err := f.Forward(context.Background(), request(1))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+	if got := collector.attempts.Load(); got != 1 {
+		t.Errorf("Expected a permanent error not to be retried, got %d attempts", got)
+	}
+}
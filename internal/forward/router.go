@@ -0,0 +1,175 @@
+package forward
+
+import (
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	"otlp-log-parser-assignment/internal/attributes"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+// Policies for records whose routing value matches no route
+const (
+	// UnmatchedDefault sends unmatched records to the default route
+	UnmatchedDefault = "default"
+	// UnmatchedDrop discards unmatched records
+	UnmatchedDrop = "drop"
+	// UnmatchedReject discards unmatched records and reports them as rejected
+	UnmatchedReject = "reject"
+)
+
+// Route outcomes of records, used in route metrics
+const (
+	routeMatched  = "matched"
+	routeDefault  = "default"
+	routeDropped  = "dropped"
+	routeRejected = "rejected"
+)
+
+// routeCount keys the route metrics: the outcome and, for matched records, the route value
+type routeCount struct {
+	outcome string
+	value   string
+}
+
+// Route sends the records whose routing value equals Value to Endpoint
+type Route struct {
+	Value    string
+	Endpoint string
+}
+
+// RouteConfig configures attribute-based routing
+type RouteConfig struct {
+	// Key is the attribute whose value, resolved Log > Scope > Resource, selects the route
+	Key    string
+	Routes []Route
+	// Default is the endpoint unmatched records are sent to under UnmatchedDefault
+	Default string
+	// Unmatched is UnmatchedDefault, UnmatchedDrop or UnmatchedReject
+	Unmatched string
+}
+
+// Enabled reports whether any route is configured
+func (c RouteConfig) Enabled() bool {
+	return len(c.Routes) > 0 || c.Default != ""
+}
+
+// Endpoints returns the distinct endpoints records can be routed to
+func (c RouteConfig) Endpoints() []string {
+	var endpoints []string
+	seen := make(map[string]bool)
+	add := func(endpoint string) {
+		if endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	for _, route := range c.Routes {
+		add(route.Endpoint)
+	}
+	if c.Unmatched == UnmatchedDefault {
+		add(c.Default)
+	}
+	return endpoints
+}
+
+// router splits requests into per-endpoint requests by each record's routing value
+type router struct {
+	config    RouteConfig
+	extractor *attributes.Extractor
+	endpoints map[string]string
+	metrics   *metrics.Metrics
+}
+
+func newRouter(config RouteConfig, m *metrics.Metrics) *router {
+	endpoints := make(map[string]string, len(config.Routes))
+	for _, route := range config.Routes {
+		endpoints[route.Value] = route.Endpoint
+	}
+	return &router{
+		config:    config,
+		extractor: attributes.NewExtractor(config.Key),
+		endpoints: endpoints,
+		metrics:   m,
+	}
+}
+
+// split returns a request per endpoint holding the records routed to it, in
+// their original resource and scope structure, and the number of unmatched
+// records rejected under UnmatchedReject
+func (r *router) split(req *collectorpb.ExportLogsServiceRequest) (map[string]*collectorpb.ExportLogsServiceRequest, int) {
+	requests := make(map[string]*collectorpb.ExportLogsServiceRequest)
+	counts := make(map[routeCount]int)
+
+	for _, resourceLog := range req.ResourceLogs {
+		if resourceLog == nil {
+			continue
+		}
+
+		resourceValue := r.extractor.ResourceValue(resourceLog.Resource)
+		resources := make(map[string]*logspb.ResourceLogs)
+
+		for _, scopeLog := range resourceLog.ScopeLogs {
+			if scopeLog == nil {
+				continue
+			}
+
+			scopeValue := r.extractor.ScopeValue(scopeLog.Scope)
+			scopes := make(map[string]*logspb.ScopeLogs)
+
+			for _, logRecord := range scopeLog.LogRecords {
+				if logRecord == nil {
+					continue
+				}
+
+				value := r.extractor.RecordValue(logRecord, scopeValue, resourceValue)
+
+				endpoint, route := r.route(value)
+				counts[route]++
+				if endpoint == "" {
+					continue
+				}
+
+				scope, ok := scopes[endpoint]
+				if !ok {
+					resource, ok := resources[endpoint]
+					if !ok {
+						resource = &logspb.ResourceLogs{Resource: resourceLog.Resource, SchemaUrl: resourceLog.SchemaUrl}
+						resources[endpoint] = resource
+						if requests[endpoint] == nil {
+							requests[endpoint] = &collectorpb.ExportLogsServiceRequest{}
+						}
+						requests[endpoint].ResourceLogs = append(requests[endpoint].ResourceLogs, resource)
+					}
+					scope = &logspb.ScopeLogs{Scope: scopeLog.Scope, SchemaUrl: scopeLog.SchemaUrl}
+					scopes[endpoint] = scope
+					resource.ScopeLogs = append(resource.ScopeLogs, scope)
+				}
+				scope.LogRecords = append(scope.LogRecords, logRecord)
+			}
+		}
+	}
+
+	for route, count := range counts {
+		r.metrics.RoutedLogRecordsTotal.WithLabelValues(route.outcome, route.value).Add(float64(count))
+	}
+
+	return requests, counts[routeCount{outcome: routeRejected}]
+}
+
+// route returns the endpoint a record with value goes to, empty when it is
+// discarded, and the route it is counted under
+func (r *router) route(value string) (string, routeCount) {
+	if endpoint, ok := r.endpoints[value]; ok {
+		return endpoint, routeCount{outcome: routeMatched, value: value}
+	}
+
+	switch r.config.Unmatched {
+	case UnmatchedDefault:
+		return r.config.Default, routeCount{outcome: routeDefault}
+	case UnmatchedReject:
+		return "", routeCount{outcome: routeRejected}
+	default:
+		return "", routeCount{outcome: routeDropped}
+	}
+}
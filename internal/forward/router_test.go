@@ -0,0 +1,250 @@
+package forward
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+func env(value string) []*commonpb.KeyValue {
+	return []*commonpb.KeyValue{{
+		Key:   "deployment.environment",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}}
+}
+
+// mixedRequest holds a prod resource whose second scope is overridden to
+// staging and whose last record is overridden to dev
+func mixedRequest() *collectorpb.ExportLogsServiceRequest {
+	return &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  &resourcepb.Resource{Attributes: env("prod")},
+			SchemaUrl: "https://opentelemetry.io/schemas/1.21.0",
+			ScopeLogs: []*logspb.ScopeLogs{
+				{
+					Scope:      &commonpb.InstrumentationScope{Name: "api"},
+					LogRecords: []*logspb.LogRecord{{}, {}},
+				},
+				{
+					Scope:      &commonpb.InstrumentationScope{Name: "jobs", Attributes: env("staging")},
+					LogRecords: []*logspb.LogRecord{{}, {Attributes: env("dev")}},
+				},
+			},
+		}},
+	}
+}
+
+func testRoutes(unmatched string) RouteConfig {
+	return RouteConfig{
+		Key: "deployment.environment",
+		Routes: []Route{
+			{Value: "prod", Endpoint: "prod:4317"},
+			{Value: "staging", Endpoint: "staging:4317"},
+		},
+		Default:   "fallback:4317",
+		Unmatched: unmatched,
+	}
+}
+
+func TestRouter_SplitKeepsStructure(t *testing.T) {
+	m := metrics.New(nil)
+
+	requests, rejected := newRouter(testRoutes(UnmatchedDefault), m).split(mixedRequest())
+	if rejected != 0 {
+		t.Errorf("Expected no rejected records, got %d", rejected)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected requests for 3 endpoints, got %d", len(requests))
+	}
+
+	prod := requests["prod:4317"].ResourceLogs
+	if len(prod) != 1 || len(prod[0].ScopeLogs) != 1 || len(prod[0].ScopeLogs[0].LogRecords) != 2 {
+		t.Fatalf("Expected the api scope's 2 records routed to prod, got %v", prod)
+	}
+	if prod[0].SchemaUrl == "" || prod[0].Resource.Attributes[0].Value.GetStringValue() != "prod" || prod[0].ScopeLogs[0].Scope.Name != "api" {
+		t.Errorf("Expected the resource and scope to be kept, got %v", prod[0])
+	}
+
+	staging := requests["staging:4317"].ResourceLogs
+	if len(staging) != 1 || staging[0].ScopeLogs[0].Scope.Name != "jobs" || len(staging[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Errorf("Expected the jobs scope's first record routed to staging, got %v", staging)
+	}
+
+	fallback := requests["fallback:4317"].ResourceLogs
+	if len(fallback) != 1 || len(fallback[0].ScopeLogs[0].LogRecords) != 1 {
+		t.Errorf("Expected the dev record routed to the default route, got %v", fallback)
+	}
+
+	if got := testutil.ToFloat64(m.RoutedLogRecordsTotal.WithLabelValues("matched", "prod")); got != 2 {
+		t.Errorf("Expected 2 records counted on the prod route, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RoutedLogRecordsTotal.WithLabelValues("default", "")); got != 1 {
+		t.Errorf("Expected 1 record counted on the default route, got %v", got)
+	}
+}
+
+func TestRouter_UnmatchedPolicies(t *testing.T) {
+	tests := []struct {
+		policy       string
+		wantRejected int
+	}{
+		{policy: UnmatchedDrop, wantRejected: 0},
+		{policy: UnmatchedReject, wantRejected: 1},
+	}
+
+	for _, tt := range tests {
+		requests, rejected := newRouter(testRoutes(tt.policy), metrics.New(nil)).split(mixedRequest())
+		if _, ok := requests["fallback:4317"]; ok {
+			t.Errorf("%s: expected nothing routed to the default route", tt.policy)
+		}
+		if rejected != tt.wantRejected {
+			t.Errorf("%s: expected %d rejected records, got %d", tt.policy, tt.wantRejected, rejected)
+		}
+	}
+}
+
+func TestRouter_RouteValueNamedLikeOutcome(t *testing.T) {
+	m := metrics.New(nil)
+	config := testRoutes(UnmatchedReject)
+	config.Routes = []Route{{Value: "dev", Endpoint: "dev:4317"}, {Value: "default", Endpoint: "named-default:4317"}}
+
+	req := mixedRequest()
+	req.ResourceLogs[0].Resource.Attributes = env("default")
+	if _, rejected := newRouter(config, m).split(req); rejected != 1 {
+		t.Errorf("Expected the staging record to be rejected, got %d", rejected)
+	}
+
+	if got := testutil.ToFloat64(m.RoutedLogRecordsTotal.WithLabelValues("matched", "default")); got != 2 {
+		t.Errorf("Expected 2 records matched on the route named default, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RoutedLogRecordsTotal.WithLabelValues("default", "")); got != 0 {
+		t.Errorf("Expected no records under the default outcome, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.RoutedLogRecordsTotal.WithLabelValues("rejected", "")); got != 1 {
+		t.Errorf("Expected 1 rejected record, got %v", got)
+	}
+}
+
+func TestForwarder_RoutesRecords(t *testing.T) {
+	prodCollector, stagingCollector := &logsCollector{}, &logsCollector{}
+	prod := startCollector(t, prodCollector)
+	staging := startCollector(t, stagingCollector)
+	testLogger, _ := logger.New(false)
+
+	config := testConfig(ModeSync)
+	config.Routing = RouteConfig{
+		Key:       "deployment.environment",
+		Routes:    []Route{{Value: "prod", Endpoint: prod}, {Value: "staging", Endpoint: staging}},
+		Unmatched: UnmatchedReject,
+	}
+	f, err := New(config, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer f.Close()
+
+	partial, err := f.Forward(context.Background(), mixedRequest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if partial.GetRejectedLogRecords() != 1 {
+		t.Errorf("Expected the unmatched dev record to be rejected, got %v", partial)
+	}
+
+	if got := countRecords(receive(t, prodCollector).ResourceLogs); got != 2 {
+		t.Errorf("Expected 2 records forwarded to prod, got %d", got)
+	}
+	if got := countRecords(receive(t, stagingCollector).ResourceLogs); got != 1 {
+		t.Errorf("Expected 1 record forwarded to staging, got %d", got)
+	}
+}
+
+func TestForwarder_SetRouting(t *testing.T) {
+	prodCollector, stagingCollector := &logsCollector{}, &logsCollector{}
+	prod := startCollector(t, prodCollector)
+	staging := startCollector(t, stagingCollector)
+	testLogger, _ := logger.New(false)
+
+	config := testConfig(ModeSync)
+	config.Routing = RouteConfig{
+		Key:       "deployment.environment",
+		Routes:    []Route{{Value: "prod", Endpoint: prod}},
+		Unmatched: UnmatchedDrop,
+	}
+	f, err := New(config, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer f.Close()
+	kept := f.routed[prod]
+
+	// Everything now goes to staging; prod is no longer routed to
+	if err := f.SetRouting(RouteConfig{Key: "deployment.environment", Default: staging, Unmatched: UnmatchedDefault}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.removing.Wait()
+	if _, ok := f.routed[prod]; ok || !kept.closed {
+		t.Errorf("Expected the removed prod endpoint to be closed")
+	}
+
+	if _, err := f.Forward(context.Background(), mixedRequest()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := countRecords(receive(t, stagingCollector).ResourceLogs); got != 4 {
+		t.Errorf("Expected every record routed to staging, got %d", got)
+	}
+
+	// Disabling routing leaves only the tees
+	if err := f.SetRouting(RouteConfig{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deliveries, _ := f.plan(mixedRequest(), 4); len(deliveries) != 0 {
+		t.Errorf("Expected no deliveries without routes or tees, got %d", len(deliveries))
+	}
+}
+
+func TestForwarder_PlannedKeepsEndpoints(t *testing.T) {
+	prodCollector, stagingCollector := &logsCollector{}, &logsCollector{}
+	prod := startCollector(t, prodCollector)
+	staging := startCollector(t, stagingCollector)
+	testLogger, _ := logger.New(false)
+
+	config := testConfig(ModeSync)
+	config.Routing = RouteConfig{
+		Key:       "deployment.environment",
+		Routes:    []Route{{Value: "prod", Endpoint: prod}},
+		Unmatched: UnmatchedDrop,
+	}
+	f, err := New(config, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer f.Close()
+	removed := f.routed[prod]
+
+	// The routing changes without waiting for the planned request
+	planned := f.Plan(mixedRequest())
+	if err := f.SetRouting(RouteConfig{Key: "deployment.environment", Default: staging, Unmatched: UnmatchedDefault}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := planned.Send(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := countRecords(receive(t, prodCollector).ResourceLogs); got != 2 {
+		t.Errorf("Expected the planned prod records sent under the old routing, got %d", got)
+	}
+
+	f.removing.Wait()
+	if !removed.closed {
+		t.Error("Expected the removed endpoint to be closed once the planned request was sent")
+	}
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// Health service names of the OTLP collector services
+const (
+	LogsService    = "opentelemetry.proto.collector.logs.v1.LogsService"
+	TracesService  = "opentelemetry.proto.collector.trace.v1.TraceService"
+	MetricsService = "opentelemetry.proto.collector.metrics.v1.MetricsService"
+)
+
+// Check returns nil when a component can accept more requests, or the reason it cannot
+type Check func() error
+
+// Monitor drives the gRPC health status and readiness from the server's
+// state: not serving until started, while any check fails and once draining.
+// The overall status ("") and LogsService follow that state; TracesService
+// and MetricsService are never served by this server and always report
+// NOT_SERVING, so per-signal probes route traces and metrics elsewhere.
+type Monitor struct {
+	server *health.Server
+	logger *logger.Logger
+
+	mu       sync.Mutex
+	checks   map[string]Check
+	started  bool
+	draining bool
+	// reason is why the server is not ready, empty when it is
+	reason string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a monitor reporting NOT_SERVING until Start is called
+func New(server *health.Server, logger *logger.Logger) *Monitor {
+	m := &Monitor{
+		server: server,
+		logger: logger,
+		checks: make(map[string]Check),
+		reason: "not started",
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, service := range []string{"", LogsService, TracesService, MetricsService} {
+		server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return m
+}
+
+// AddCheck makes the server not ready while check fails
+func (m *Monitor) AddCheck(name string, check Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checks[name] = check
+}
+
+// Start marks the server started and re-evaluates the checks every interval
+// until Drain is called
+func (m *Monitor) Start(interval time.Duration) {
+	m.mu.Lock()
+	m.started = true
+	m.mu.Unlock()
+	m.Update()
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Update()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Update evaluates the checks and publishes the resulting status
+func (m *Monitor) Update() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reason := ""
+	switch {
+	case m.draining:
+		reason = "draining"
+	case !m.started:
+		reason = "not started"
+	default:
+		for name, check := range m.checks {
+			if err := check(); err != nil {
+				reason = fmt.Sprintf("%s: %v", name, err)
+				break
+			}
+		}
+	}
+	if reason == m.reason {
+		return
+	}
+
+	m.reason = reason
+	status := healthpb.HealthCheckResponse_SERVING
+	if reason != "" {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+		m.logger.Warnw("Server not ready", "reason", reason)
+	} else {
+		m.logger.Infow("Server ready")
+	}
+	m.server.SetServingStatus("", status)
+	m.server.SetServingStatus(LogsService, status)
+}
+
+// Drain reports NOT_SERVING from now on and stops re-evaluating the checks
+func (m *Monitor) Drain() {
+	m.mu.Lock()
+	started := m.started
+	m.draining = true
+	m.mu.Unlock()
+	m.Update()
+
+	if started {
+		select {
+		case <-m.stop:
+		default:
+			close(m.stop)
+		}
+		<-m.done
+	}
+}
+
+// Ready returns nil when the server accepts requests, or the reason it does not
+func (m *Monitor) Ready() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reason != "" {
+		return fmt.Errorf("not ready: %s", m.reason)
+	}
+	return nil
+}
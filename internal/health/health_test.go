@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+func status(t *testing.T, server *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Health check of %q failed: %v", service, err)
+	}
+	return resp.Status
+}
+
+func TestMonitor_Lifecycle(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	server := health.NewServer()
+	m := New(server, testLogger)
+
+	if got := status(t, server, LogsService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING before start, got %v", got)
+	}
+	if err := m.Ready(); err == nil || !strings.Contains(err.Error(), "not started") {
+		t.Errorf("Expected not ready before start, got %v", err)
+	}
+
+	m.Start(time.Hour)
+	for _, service := range []string{"", LogsService} {
+		if got := status(t, server, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q SERVING once started, got %v", service, got)
+		}
+	}
+	for _, service := range []string{TracesService, MetricsService} {
+		if got := status(t, server, service); got != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("Expected %q NOT_SERVING as it is not implemented, got %v", service, got)
+		}
+	}
+	if err := m.Ready(); err != nil {
+		t.Errorf("Expected ready once started, got %v", err)
+	}
+
+	m.Drain()
+	if got := status(t, server, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING while draining, got %v", got)
+	}
+	if err := m.Ready(); err == nil || !strings.Contains(err.Error(), "draining") {
+		t.Errorf("Expected not ready while draining, got %v", err)
+	}
+}
+
+func TestMonitor_Saturation(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	server := health.NewServer()
+	m := New(server, testLogger)
+
+	var full atomic.Bool
+	m.AddCheck("file_sink", func() error {
+		if full.Load() {
+			return errors.New("queue is full")
+		}
+		return nil
+	})
+	m.Start(10 * time.Millisecond)
+	defer m.Drain()
+
+	full.Store(true)
+	waitFor(t, func() bool { return status(t, server, LogsService) == healthpb.HealthCheckResponse_NOT_SERVING })
+	if err := m.Ready(); err == nil || !strings.Contains(err.Error(), "file_sink: queue is full") {
+		t.Errorf("Expected the saturated check as the reason, got %v", err)
+	}
+
+	full.Store(false)
+	waitFor(t, func() bool { return status(t, server, LogsService) == healthpb.HealthCheckResponse_SERVING })
+}
+
+func TestMonitor_DrainBeforeStart(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	m := New(health.NewServer(), testLogger)
+
+	// Draining a monitor that never started must not block
+	m.Drain()
+	if err := m.Ready(); err == nil {
+		t.Error("Expected not ready after drain")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
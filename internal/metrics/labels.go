@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"otlp-log-parser-assignment/internal/attributes"
+	"otlp-log-parser-assignment/internal/sketch"
+)
+
+// LabelLimiter admits a bounded number of distinct label values and maps
+// everything beyond the cap to attributes.OverflowValue
+type LabelLimiter struct {
+	mu         sync.Mutex
+	max        int
+	admitted   map[string]struct{}
+	overflowed *sketch.HyperLogLog
+}
+
+// NewLabelLimiter creates a limiter admitting at most max distinct values (0 disables the cap)
+func NewLabelLimiter(max int) *LabelLimiter {
+	overflowed, _ := sketch.New(sketch.DefaultPrecision)
+	return &LabelLimiter{
+		max:        max,
+		admitted:   make(map[string]struct{}),
+		overflowed: overflowed,
+	}
+}
+
+// Admit returns the label to use for value and whether it was diverted to the overflow label
+func (l *LabelLimiter) Admit(value string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max <= 0 {
+		return value, false
+	}
+	if _, ok := l.admitted[value]; ok {
+		return value, false
+	}
+	if len(l.admitted) < l.max {
+		l.admitted[value] = struct{}{}
+		return value, false
+	}
+
+	l.overflowed.Add(value)
+	return attributes.OverflowValue, true
+}
+
+// Admitted reports whether value holds a slot or the cap is disabled, without
+// taking a slot for it
+func (l *LabelLimiter) Admitted(value string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max <= 0 {
+		return true
+	}
+	_, ok := l.admitted[value]
+	return ok
+}
+
+// Release frees the slot of an admitted value so another value can take it
+func (l *LabelLimiter) Release(value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.admitted, value)
+}
+
+// OverflowedValues returns the estimated number of distinct values diverted to the overflow label
+func (l *LabelLimiter) OverflowedValues() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.overflowed.Estimate()
+}
+
+// SetAttributeLabelLimit replaces the limiter guarding AttributeValuesTotal labels.
+// It must be called before any values are observed.
+func (m *Metrics) SetAttributeLabelLimit(max int) {
+	m.attributeLabels = NewLabelLimiter(max)
+}
+
+// Sample is a single log record as recorded in the attribute value metrics
+type Sample struct {
+	Value    string
+	Bytes    int64
+	Severity string
+}
+
+// labelSeverity identifies a value/severity series
+type labelSeverity struct {
+	label    string
+	severity string
+}
+
+// ObserveAttributeSamples records a batch of log records in the attribute value
+// metrics, respecting the attribute label limit
+func (m *Metrics) ObserveAttributeSamples(samples []Sample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	bytes := make(map[string]int64)
+	severities := make(map[labelSeverity]int)
+	totalBytes := int64(0)
+	overflowed := false
+	for _, sample := range samples {
+		label, diverted := m.attributeLabels.Admit(sample.Value)
+		counts[label]++
+		bytes[label] += sample.Bytes
+		totalBytes += sample.Bytes
+		if sample.Severity != "" {
+			severities[labelSeverity{label: label, severity: sample.Severity}]++
+		}
+		overflowed = overflowed || diverted
+	}
+
+	m.touchSeries(counts)
+	for label, count := range counts {
+		m.AttributeValuesTotal.WithLabelValues(label).Add(float64(count))
+	}
+	for label, size := range bytes {
+		m.AttributeValueBytesTotal.WithLabelValues(label).Add(float64(size))
+	}
+	for key, count := range severities {
+		m.AttributeValueSeverityTotal.WithLabelValues(key.label, key.severity).Add(float64(count))
+	}
+	m.LogRecordBytesProcessed.Add(float64(totalBytes))
+
+	if overflowed {
+		m.AttributeLabelOverflowValues.Set(float64(m.attributeLabels.OverflowedValues()))
+	}
+}
+
+// SetAttributeValueDistinct replaces the distinct estimates exported per attribute value.
+// Only values already admitted under the attribute label limit by their counts
+// are exported; the estimates never take a slot themselves.
+func (m *Metrics) SetAttributeValueDistinct(estimates map[string]uint64) {
+	m.AttributeValueDistinct.Reset()
+	for value, estimate := range estimates {
+		if m.attributeLabels.Admitted(value) {
+			m.AttributeValueDistinct.WithLabelValues(value).Set(float64(estimate))
+		}
+	}
+}
+
+// SetAttributeValueAnomalyScores replaces the anomaly z-scores exported per attribute value.
+// Only values already admitted under the attribute label limit by their counts
+// are exported; the scores never take a slot themselves.
+func (m *Metrics) SetAttributeValueAnomalyScores(scores map[string]float64) {
+	m.AttributeValueAnomalyScore.Reset()
+	for value, score := range scores {
+		if m.attributeLabels.Admitted(value) {
+			m.AttributeValueAnomalyScore.WithLabelValues(value).Set(score)
+		}
+	}
+}
+
+// seriesState tracks the activity of a value label
+type seriesState struct {
+	// seen is set when the label is observed and cleared when a window closes
+	seen bool
+	// idle counts the consecutive closed windows without observations
+	idle int
+}
+
+// SetSeriesIdleWindows sets the number of consecutive idle windows after which
+// a value's label series are removed (0 keeps them forever)
+func (m *Metrics) SetSeriesIdleWindows(windows int) {
+	m.seriesMu.Lock()
+	defer m.seriesMu.Unlock()
+
+	m.seriesIdleWindows = windows
+}
+
+// touchSeries marks labels as active in the current window
+func (m *Metrics) touchSeries(labels map[string]int) {
+	m.seriesMu.Lock()
+	defer m.seriesMu.Unlock()
+
+	for label := range labels {
+		state, ok := m.series[label]
+		if !ok {
+			state = &seriesState{}
+			m.series[label] = state
+		}
+		state.seen = true
+	}
+}
+
+// ObserveWindow exports the counts of a closed window per attribute value and
+// removes the label series of values idle for the configured number of
+// windows, freeing their slot under the attribute label limit. Tracked values
+// absent from the window are exported with a count of zero until they expire.
+func (m *Metrics) ObserveWindow(counts map[string]int64) {
+	labelCounts := make(map[string]int64, len(counts))
+	for value, count := range counts {
+		label, _ := m.attributeLabels.Admit(value)
+		labelCounts[label] += count
+	}
+
+	m.seriesMu.Lock()
+	defer m.seriesMu.Unlock()
+
+	for label, count := range labelCounts {
+		if _, ok := m.series[label]; !ok {
+			m.series[label] = &seriesState{}
+		}
+		m.AttributeValueWindowCount.WithLabelValues(label).Set(float64(count))
+	}
+
+	for label, state := range m.series {
+		if state.seen || labelCounts[label] > 0 {
+			state.seen = false
+			state.idle = 0
+			continue
+		}
+
+		state.idle++
+		if m.seriesIdleWindows > 0 && state.idle >= m.seriesIdleWindows {
+			m.expireSeries(label)
+			continue
+		}
+		m.AttributeValueWindowCount.WithLabelValues(label).Set(0)
+	}
+}
+
+// expireSeries removes every series of label; seriesMu must be held
+func (m *Metrics) expireSeries(label string) {
+	delete(m.series, label)
+	m.AttributeValuesTotal.DeleteLabelValues(label)
+	m.AttributeValueBytesTotal.DeleteLabelValues(label)
+	m.AttributeValueSeverityTotal.DeletePartialMatch(prometheus.Labels{"value": label})
+	m.AttributeValueWindowCount.DeleteLabelValues(label)
+	m.attributeLabels.Release(label)
+	m.AttributeValueExpiredSeriesTotal.Inc()
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"otlp-log-parser-assignment/internal/attributes"
+)
+
+func TestLabelLimiter_Admit(t *testing.T) {
+	limiter := NewLabelLimiter(2)
+
+	tests := []struct {
+		value        string
+		wantLabel    string
+		wantDiverted bool
+	}{
+		{value: "a", wantLabel: "a"},
+		{value: "b", wantLabel: "b"},
+		{value: "a", wantLabel: "a"},
+		{value: "c", wantLabel: attributes.OverflowValue, wantDiverted: true},
+		{value: "d", wantLabel: attributes.OverflowValue, wantDiverted: true},
+		{value: "b", wantLabel: "b"},
+	}
+
+	for _, tt := range tests {
+		label, diverted := limiter.Admit(tt.value)
+		if label != tt.wantLabel || diverted != tt.wantDiverted {
+			t.Errorf("Admit(%q) = (%q, %v), want (%q, %v)", tt.value, label, diverted, tt.wantLabel, tt.wantDiverted)
+		}
+	}
+
+	if got := limiter.OverflowedValues(); got != 2 {
+		t.Errorf("Expected 2 overflowed values, got %d", got)
+	}
+}
+
+func TestLabelLimiter_Unlimited(t *testing.T) {
+	limiter := NewLabelLimiter(0)
+
+	for _, value := range []string{"a", "b", "c"} {
+		if label, diverted := limiter.Admit(value); label != value || diverted {
+			t.Errorf("Admit(%q) = (%q, %v), want value admitted", value, label, diverted)
+		}
+	}
+}
+
+func TestObserveAttributeSamples_Overflow(t *testing.T) {
+	m := New(nil)
+	m.SetAttributeLabelLimit(1)
+
+	m.ObserveAttributeSamples([]Sample{{Value: "limited-a"}, {Value: "limited-b"}, {Value: "limited-c"}, {Value: "limited-a"}})
+
+	if got := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues("limited-a")); got < 2 {
+		t.Errorf("Expected limited-a count to be at least 2, got %f", got)
+	}
+
+	overflow := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues(attributes.OverflowValue))
+	if overflow != 2 {
+		t.Errorf("Expected 2 observations under the overflow label, got %f", overflow)
+	}
+
+	if got := testutil.ToFloat64(m.AttributeLabelOverflowValues); got != 2 {
+		t.Errorf("Expected 2 distinct overflowed values, got %f", got)
+	}
+}
+
+func TestObserveAttributeSamples_Bytes(t *testing.T) {
+	m := New(nil)
+
+	m.ObserveAttributeSamples([]Sample{
+		{Value: "bytes-a", Bytes: 100},
+		{Value: "bytes-b", Bytes: 50},
+		{Value: "bytes-a", Bytes: 25},
+	})
+
+	if got := testutil.ToFloat64(m.AttributeValueBytesTotal.WithLabelValues("bytes-a")); got != 125 {
+		t.Errorf("Expected 125 bytes for bytes-a, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.LogRecordBytesProcessed); got != 175 {
+		t.Errorf("Expected 175 bytes processed, got %f", got)
+	}
+}
+
+func TestObserveAttributeSamples_Severity(t *testing.T) {
+	m := New(nil)
+
+	m.ObserveAttributeSamples([]Sample{
+		{Value: "severity-a", Severity: "ERROR"},
+		{Value: "severity-a", Severity: "ERROR"},
+		{Value: "severity-a", Severity: "INFO"},
+	})
+
+	if got := testutil.ToFloat64(m.AttributeValueSeverityTotal.WithLabelValues("severity-a", "ERROR")); got != 2 {
+		t.Errorf("Expected 2 ERROR records for severity-a, got %f", got)
+	}
+}
+
+func TestObserveWindow_ExpiresIdleSeries(t *testing.T) {
+	m := New(nil)
+	m.SetAttributeLabelLimit(2)
+	m.SetSeriesIdleWindows(2)
+
+	m.ObserveAttributeSamples([]Sample{{Value: "retired", Severity: "ERROR"}, {Value: "active"}})
+	m.ObserveWindow(map[string]int64{"retired": 1, "active": 1})
+	if got := testutil.ToFloat64(m.AttributeValueWindowCount.WithLabelValues("retired")); got != 1 {
+		t.Errorf("Expected a window count of 1 for retired, got %f", got)
+	}
+
+	// retired stays idle for two windows; active keeps receiving records
+	for i := 0; i < 2; i++ {
+		m.ObserveAttributeSamples([]Sample{{Value: "active"}, {Value: "active"}})
+		m.ObserveWindow(map[string]int64{"active": 2})
+		if i == 0 {
+			if got := testutil.ToFloat64(m.AttributeValueWindowCount.WithLabelValues("retired")); got != 0 {
+				t.Errorf("Expected an idle window count of 0 for retired, got %f", got)
+			}
+		}
+	}
+
+	if got := testutil.ToFloat64(m.AttributeValueExpiredSeriesTotal); got != 1 {
+		t.Errorf("Expected 1 expired series, got %f", got)
+	}
+	if got := testutil.CollectAndCount(m.AttributeValuesTotal); got != 1 {
+		t.Errorf("Expected only the active values_total series, got %d", got)
+	}
+	if got := testutil.CollectAndCount(m.AttributeValueSeverityTotal); got != 0 {
+		t.Errorf("Expected the retired severity series to be removed, got %d", got)
+	}
+	if got := testutil.CollectAndCount(m.AttributeValueWindowCount); got != 1 {
+		t.Errorf("Expected only the active window count series, got %d", got)
+	}
+
+	// The expired value's label slot is free again
+	m.ObserveAttributeSamples([]Sample{{Value: "new"}})
+	if got := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues("new")); got != 1 {
+		t.Errorf("Expected new to take the freed label slot, got %f", got)
+	}
+}
+
+func TestObserveWindow_NoExpiry(t *testing.T) {
+	m := New(nil)
+
+	m.ObserveAttributeSamples([]Sample{{Value: "a"}})
+	for i := 0; i < 5; i++ {
+		m.ObserveWindow(nil)
+	}
+
+	if got := testutil.CollectAndCount(m.AttributeValuesTotal); got != 1 {
+		t.Errorf("Expected series to be kept without an idle limit, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.AttributeValueExpiredSeriesTotal); got != 0 {
+		t.Errorf("Expected no expired series, got %f", got)
+	}
+}
+
+func TestSetAttributeValueGauges_DoNotTakeLabelSlots(t *testing.T) {
+	m := New(nil)
+	m.SetAttributeLabelLimit(1)
+
+	// Gauges for values without counts are dropped and leave the slot free
+	m.SetAttributeValueDistinct(map[string]uint64{"gauge-only": 3})
+	m.SetAttributeValueAnomalyScores(map[string]float64{"gauge-only": 2.5})
+	if got := testutil.CollectAndCount(m.AttributeValueDistinct); got != 0 {
+		t.Errorf("Expected no distinct series for an uncounted value, got %d", got)
+	}
+	if got := testutil.CollectAndCount(m.AttributeValueAnomalyScore); got != 0 {
+		t.Errorf("Expected no anomaly series for an uncounted value, got %d", got)
+	}
+
+	m.ObserveAttributeSamples([]Sample{{Value: "counted"}})
+	if got := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues("counted")); got != 1 {
+		t.Errorf("Expected counted to take the free label slot, got %f", got)
+	}
+
+	m.SetAttributeValueDistinct(map[string]uint64{"counted": 4, "gauge-only": 3})
+	m.SetAttributeValueAnomalyScores(map[string]float64{"counted": 1.5, "gauge-only": 2.5})
+	if got := testutil.ToFloat64(m.AttributeValueDistinct.WithLabelValues("counted")); got != 4 {
+		t.Errorf("Expected a distinct estimate of 4 for counted, got %f", got)
+	}
+	if got := testutil.CollectAndCount(m.AttributeValueAnomalyScore); got != 1 {
+		t.Errorf("Expected only the counted anomaly series, got %d", got)
+	}
+}
@@ -1,23 +1,258 @@
 package metrics
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var (
-	RequestsTotal = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "otlp_log_parser_assignment_requests_total",
-		Help: "Total number of OTLP log export requests received.",
-	})
-
-	LogRecordsProcessed = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "otlp_log_parser_assignment_log_records_processed_total",
-		Help: "Total number of log records processed.",
-	})
-
-	AttributeValuesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "otlp_log_parser_assignment_attribute_values_total",
-		Help: "Total number of times each attribute value has been seen.",
-	}, []string{"value"})
-)
+// Metrics holds the collectors of one server instance
+type Metrics struct {
+	RequestsTotal                prometheus.Counter
+	LogRecordsProcessed          prometheus.Counter
+	LogRecordBytesProcessed      prometheus.Counter
+	AttributeValuesTotal         *prometheus.CounterVec
+	AttributeValueSeverityTotal  *prometheus.CounterVec
+	AttributeValueBytesTotal     *prometheus.CounterVec
+	AttributeLabelOverflowValues prometheus.Gauge
+	WindowOverflowValues         prometheus.Gauge
+	AttributeValueDistinct       *prometheus.GaugeVec
+	AttributeValueAnomalyScore   *prometheus.GaugeVec
+	AttributeValueAnomaliesTotal *prometheus.CounterVec
+	CheckpointsTotal             prometheus.Counter
+	CheckpointErrorsTotal        prometheus.Counter
+	MissedWindowsTotal           prometheus.Counter
+	WebhookDeliveriesTotal       *prometheus.CounterVec
+	OTLPMetricsExportsTotal      *prometheus.CounterVec
+	ForwardedLogRecordsTotal     *prometheus.CounterVec
+	RoutedLogRecordsTotal        *prometheus.CounterVec
+	ForwardRetriesTotal          *prometheus.CounterVec
+	ForwardQueueLength           *prometheus.GaugeVec
+	FileSinkRecordsTotal         *prometheus.CounterVec
+	FileSinkBytes                prometheus.Gauge
+	FileSinkRotationsTotal       prometheus.Counter
+	FileSinkDeletedFilesTotal    prometheus.Counter
+	WindowOverflowValuesTotal    prometheus.Counter
+
+	ExportDuration      prometheus.Histogram
+	RequestBytes        prometheus.Histogram
+	RecordsPerRequest   prometheus.Histogram
+	ResourcesPerRequest prometheus.Histogram
+	ScopesPerRequest    prometheus.Histogram
+	ExportErrorsTotal   *prometheus.CounterVec
+
+	AttributeValueWindowCount        *prometheus.GaugeVec
+	AttributeValueExpiredSeriesTotal prometheus.Counter
+
+	WatchSubscribers         prometheus.Gauge
+	WatchDroppedWindowsTotal prometheus.Counter
+
+	attributeLabels *LabelLimiter
+
+	// seriesMu guards series and seriesIdleWindows
+	seriesMu          sync.Mutex
+	series            map[string]*seriesState
+	seriesIdleWindows int
+}
+
+// New creates the collectors and registers them with reg. A nil reg leaves
+// them unregistered, which suits tests and components used without a server.
+func New(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+	return &Metrics{
+		RequestsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_requests_total",
+			Help: "Total number of OTLP log export requests received.",
+		}),
+
+		LogRecordsProcessed: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_log_records_processed_total",
+			Help: "Total number of log records processed.",
+		}),
+
+		LogRecordBytesProcessed: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_log_record_bytes_processed_total",
+			Help: "Total serialized size in bytes of the log records processed.",
+		}),
+
+		AttributeValuesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_attribute_values_total",
+			Help: "Total number of times each attribute value has been seen.",
+		}, []string{"value"}),
+
+		AttributeValueSeverityTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_severity_total",
+			Help: "Total number of log records seen per attribute value and severity bucket.",
+		}, []string{"value", "severity"}),
+
+		AttributeValueBytesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_bytes_total",
+			Help: "Total serialized size in bytes of the log records seen per attribute value.",
+		}, []string{"value"}),
+
+		AttributeLabelOverflowValues: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_attribute_label_overflow_values",
+			Help: "Estimated number of distinct attribute values recorded under the overflow label since start.",
+		}),
+
+		WindowOverflowValues: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_window_overflow_values",
+			Help: "Estimated number of distinct attribute values that exceeded the per-window cap in the last completed window.",
+		}),
+
+		AttributeValueDistinct: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_distinct",
+			Help: "Estimated number of distinct values of the distinct-of attribute per attribute value in the last completed window.",
+		}, []string{"value"}),
+
+		AttributeValueAnomalyScore: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_anomaly_score",
+			Help: "Z-score of each attribute value's count in the last completed window against its baseline.",
+		}, []string{"value"}),
+
+		AttributeValueAnomaliesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_anomalies_total",
+			Help: "Total number of attribute value anomalies detected, by direction (spike or drop).",
+		}, []string{"direction"}),
+
+		CheckpointsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_checkpoints_total",
+			Help: "Total number of counter state snapshots written to disk.",
+		}),
+
+		CheckpointErrorsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_checkpoint_errors_total",
+			Help: "Total number of failures writing or restoring counter checkpoints.",
+		}),
+
+		MissedWindowsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_missed_windows_total",
+			Help: "Total number of windows that passed while the process was down, detected on restore.",
+		}),
+
+		WebhookDeliveriesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_webhook_deliveries_total",
+			Help: "Total number of webhook report delivery outcomes, by result (success, retry, rejected, dropped).",
+		}, []string{"result"}),
+
+		OTLPMetricsExportsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_otlp_metrics_exports_total",
+			Help: "Total number of OTLP window metrics export outcomes, by result (success, partial, failure, dropped).",
+		}, []string{"result"}),
+
+		ForwardedLogRecordsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_forwarded_log_records_total",
+			Help: "Total number of log records forwarded downstream, by endpoint and result (success, rejected, failed, dropped).",
+		}, []string{"endpoint", "result"}),
+
+		RoutedLogRecordsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_routed_log_records_total",
+			Help: "Total number of log records routed, by outcome (matched, default, dropped or rejected) and the matched route value.",
+		}, []string{"outcome", "value"}),
+
+		ForwardRetriesTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_forward_retries_total",
+			Help: "Total number of retried downstream forwarding attempts, by endpoint.",
+		}, []string{"endpoint"}),
+
+		ForwardQueueLength: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_forward_queue_length",
+			Help: "Number of requests waiting to be forwarded, by endpoint.",
+		}, []string{"endpoint"}),
+
+		FileSinkRecordsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_file_sink_records_total",
+			Help: "Total number of log records handled by the file sink, by result (written, dropped, failed).",
+		}, []string{"result"}),
+
+		FileSinkBytes: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_file_sink_bytes",
+			Help: "Bytes of active and rotated files in the file sink directory.",
+		}),
+
+		FileSinkRotationsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_file_sink_rotations_total",
+			Help: "Total number of file sink files rotated and compressed.",
+		}),
+
+		FileSinkDeletedFilesTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_file_sink_deleted_files_total",
+			Help: "Total number of rotated file sink files deleted to stay within the disk budget.",
+		}),
+
+		WindowOverflowValuesTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_window_overflow_values_total",
+			Help: "Total estimated number of distinct attribute values that exceeded the per-window cap, summed over windows.",
+		}),
+
+		ExportDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_log_parser_assignment_export_duration_seconds",
+			Help:    "Time taken to handle OTLP log export requests.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}),
+
+		RequestBytes: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_log_parser_assignment_request_bytes",
+			Help:    "Serialized size in bytes of OTLP log export requests.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 10),
+		}),
+
+		RecordsPerRequest: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_log_parser_assignment_request_log_records",
+			Help:    "Number of log records per OTLP log export request.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+		}),
+
+		ResourcesPerRequest: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_log_parser_assignment_request_resources",
+			Help:    "Number of resources per OTLP log export request.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+
+		ScopesPerRequest: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "otlp_log_parser_assignment_request_scopes",
+			Help:    "Number of instrumentation scopes per OTLP log export request.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+
+		ExportErrorsTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_export_errors_total",
+			Help: "Total number of OTLP log export requests that failed, by gRPC status code.",
+		}, []string{"code"}),
+
+		AttributeValueWindowCount: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_window_count",
+			Help: "Number of log records seen per attribute value in the last completed window.",
+		}, []string{"value"}),
+
+		AttributeValueExpiredSeriesTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_attribute_value_expired_series_total",
+			Help: "Total number of attribute value label series removed after staying idle.",
+		}),
+
+		WatchSubscribers: factory.NewGauge(prometheus.GaugeOpts{
+			Name: "otlp_log_parser_assignment_watch_subscribers",
+			Help: "Number of subscribers to completed windows: WatchWindows streams and dashboard pages.",
+		}),
+
+		WatchDroppedWindowsTotal: factory.NewCounter(prometheus.CounterOpts{
+			Name: "otlp_log_parser_assignment_watch_dropped_windows_total",
+			Help: "Total number of window reports dropped because a WatchWindows subscriber's buffer was full.",
+		}),
+
+		attributeLabels: NewLabelLimiter(0),
+		series:          make(map[string]*seriesState),
+	}
+}
+
+// NewRegistry creates a registry with the standard Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
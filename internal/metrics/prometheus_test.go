@@ -9,65 +9,63 @@ import (
 )
 
 func TestRequestsTotal(t *testing.T) {
-	// Reset the counter to ensure clean test state
-	RequestsTotal.Add(0)
-	initialValue := testutil.ToFloat64(RequestsTotal)
+	m := New(nil)
 
 	// Increment the counter
-	RequestsTotal.Inc()
-	RequestsTotal.Inc()
+	m.RequestsTotal.Inc()
+	m.RequestsTotal.Inc()
 
 	// Verify the counter increased by 2
-	finalValue := testutil.ToFloat64(RequestsTotal)
-	expected := initialValue + 2
-	if finalValue != expected {
-		t.Errorf("Expected RequestsTotal to be %f, got %f", expected, finalValue)
+	if got := testutil.ToFloat64(m.RequestsTotal); got != 2 {
+		t.Errorf("Expected RequestsTotal to be 2, got %f", got)
 	}
 }
 
 func TestLogRecordsProcessed(t *testing.T) {
-	// Reset and get initial value
-	LogRecordsProcessed.Add(0)
-	initialValue := testutil.ToFloat64(LogRecordsProcessed)
+	m := New(nil)
 
 	// Add some log records
-	LogRecordsProcessed.Add(10)
-	LogRecordsProcessed.Add(5)
+	m.LogRecordsProcessed.Add(10)
+	m.LogRecordsProcessed.Add(5)
 
 	// Verify the counter increased by 15
-	finalValue := testutil.ToFloat64(LogRecordsProcessed)
-	expected := initialValue + 15
-	if finalValue != expected {
-		t.Errorf("Expected LogRecordsProcessed to be %f, got %f", expected, finalValue)
+	if got := testutil.ToFloat64(m.LogRecordsProcessed); got != 15 {
+		t.Errorf("Expected LogRecordsProcessed to be 15, got %f", got)
 	}
 }
 
 func TestAttributeValuesTotal(t *testing.T) {
+	m := New(nil)
+
 	// Test with different attribute values
 	testValues := []string{"service-a", "service-b", "service-a"}
 
 	// Record the values
 	for _, value := range testValues {
-		AttributeValuesTotal.WithLabelValues(value).Inc()
+		m.AttributeValuesTotal.WithLabelValues(value).Inc()
 	}
 
 	// Verify service-a has count of 2
-	serviceACount := testutil.ToFloat64(AttributeValuesTotal.WithLabelValues("service-a"))
-	if serviceACount < 2 {
-		t.Errorf("Expected service-a count to be at least 2, got %f", serviceACount)
+	if got := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues("service-a")); got != 2 {
+		t.Errorf("Expected service-a count to be 2, got %f", got)
 	}
 
-	// Verify service-b has count of at least 1
-	serviceBCount := testutil.ToFloat64(AttributeValuesTotal.WithLabelValues("service-b"))
-	if serviceBCount < 1 {
-		t.Errorf("Expected service-b count to be at least 1, got %f", serviceBCount)
+	// Verify service-b has count of 1
+	if got := testutil.ToFloat64(m.AttributeValuesTotal.WithLabelValues("service-b")); got != 1 {
+		t.Errorf("Expected service-b count to be 1, got %f", got)
 	}
 }
 
 func TestMetricsRegistration(t *testing.T) {
-	// Verify that our metrics are properly registered by checking
-	// if they appear in the default registry
-	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	// Verify that our metrics and the runtime collectors are registered
+	// on the registry passed to New
+	reg := NewRegistry()
+	m := New(reg)
+	m.AttributeValuesTotal.WithLabelValues("test-service").Inc()
+	m.ExportErrorsTotal.WithLabelValues("Internal").Inc()
+	m.ExportDuration.Observe(0.01)
+
+	metricFamilies, err := reg.Gather()
 	if err != nil {
 		t.Fatalf("Failed to gather metrics: %v", err)
 	}
@@ -76,6 +74,10 @@ func TestMetricsRegistration(t *testing.T) {
 		"otlp_log_parser_assignment_requests_total",
 		"otlp_log_parser_assignment_log_records_processed_total",
 		"otlp_log_parser_assignment_attribute_values_total",
+		"otlp_log_parser_assignment_export_duration_seconds",
+		"otlp_log_parser_assignment_export_errors_total",
+		"go_goroutines",
+		"process_start_time_seconds",
 	}
 
 	foundMetrics := make(map[string]bool)
@@ -90,14 +92,35 @@ func TestMetricsRegistration(t *testing.T) {
 	}
 }
 
+func TestMetricsIsolation(t *testing.T) {
+	// Two instances on separate registries do not conflict or share values
+	first, second := NewRegistry(), NewRegistry()
+	New(first).RequestsTotal.Inc()
+	New(second)
+
+	if got := testutil.ToFloat64(New(nil).RequestsTotal); got != 0 {
+		t.Errorf("Expected a fresh instance to start at 0, got %f", got)
+	}
+
+	for name, reg := range map[string]*prometheus.Registry{"first": first, "second": second} {
+		count, err := testutil.GatherAndCount(reg, "otlp_log_parser_assignment_requests_total")
+		if err != nil || count != 1 {
+			t.Errorf("Expected the %s registry to hold its own requests counter, got %d (%v)", name, count, err)
+		}
+	}
+}
+
 func TestMetricsOutput(t *testing.T) {
+	reg := NewRegistry()
+	m := New(reg)
+
 	// Increment some metrics
-	RequestsTotal.Inc()
-	LogRecordsProcessed.Add(42)
-	AttributeValuesTotal.WithLabelValues("test-service").Inc()
+	m.RequestsTotal.Inc()
+	m.LogRecordsProcessed.Add(42)
+	m.AttributeValuesTotal.WithLabelValues("test-service").Inc()
 
 	// Gather metrics and verify they contain expected content
-	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	metricFamilies, err := reg.Gather()
 	if err != nil {
 		t.Fatalf("Failed to gather metrics: %v", err)
 	}
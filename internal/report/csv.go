@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader names the columns written by CSVReporter, one row per value per window
+var csvHeader = []string{
+	"window_number", "window_start", "window_end", "value", "count", "percentage", "bytes",
+	"trace", "debug", "info", "warn", "error", "fatal", "unspecified", "distinct",
+}
+
+// CSVReporter writes the per-value counts of each window as CSV rows
+type CSVReporter struct {
+	out    io.Writer
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVReporter creates a reporter writing rows to out, preceded by a header
+// row when header is true. out is closed by Close if it is an io.Closer.
+func NewCSVReporter(out io.Writer, header bool) *CSVReporter {
+	return &CSVReporter{out: out, w: csv.NewWriter(out), header: header}
+}
+
+func (c *CSVReporter) Report(r Report) error {
+	if r.Delta == nil {
+		return nil
+	}
+
+	if c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = false
+	}
+
+	windowNumber := strconv.FormatInt(r.WindowNumber, 10)
+	start := r.Start.Format(time.RFC3339Nano)
+	end := r.End.Format(time.RFC3339Nano)
+	for _, v := range r.Delta.Values {
+		row := []string{
+			windowNumber, start, end, v.Value,
+			strconv.FormatInt(v.Count, 10),
+			strconv.FormatFloat(v.Percentage, 'f', 2, 64),
+			strconv.FormatInt(v.Bytes, 10),
+		}
+		for _, bucket := range tableSeverities {
+			row = append(row, strconv.FormatInt(v.Severity[bucket.String()], 10))
+		}
+		row = append(row, strconv.FormatUint(v.Distinct, 10))
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *CSVReporter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return closeOutput(c.out)
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestCSVReporter_Report(t *testing.T) {
+	var out bytes.Buffer
+	reporter := NewCSVReporter(&out, true)
+
+	r := sampleReport()
+	if err := reporter.Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.WindowNumber++
+	if err := reporter.Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+
+	// One header, then two values per window
+	if len(rows) != 5 {
+		t.Fatalf("Expected 5 rows, got %d", len(rows))
+	}
+	if rows[0][0] != "window_number" || rows[0][len(rows[0])-1] != "distinct" {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	checkout := rows[2]
+	if checkout[0] != "7" || checkout[3] != "checkout" || checkout[4] != "3" || checkout[5] != "75.00" || checkout[6] != "300" || checkout[9] != "3" {
+		t.Errorf("Unexpected row %v", checkout)
+	}
+	if rows[4][0] != "8" {
+		t.Errorf("Expected the second window's rows to follow, got %v", rows[4])
+	}
+}
+
+func TestCSVReporter_SkipsCumulativeOnlyReports(t *testing.T) {
+	var out bytes.Buffer
+	r := sampleReport()
+	r.Delta = nil
+
+	if err := NewCSVReporter(&out, true).Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
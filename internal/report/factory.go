@@ -0,0 +1,164 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+// Reporter kinds accepted by Open
+const (
+	// KindLog logs reports as structured JSON
+	KindLog = "log"
+	// KindTable prints ASCII tables to a file, stdout by default
+	KindTable = "table"
+	// KindCSV writes per-value CSV rows to a file, stdout by default
+	KindCSV = "csv"
+	// KindNDJSON appends one JSON report per line to a file
+	KindNDJSON = "ndjson"
+	// KindTemplate renders the Go template in the given file to stdout
+	KindTemplate = "template"
+	// KindWebhook POSTs reports as JSON to the given URL
+	KindWebhook = "webhook"
+	// KindOTLP exports per-value counts as OTLP metrics to the given host:port
+	KindOTLP = "otlp"
+	// KindStatsD sends per-value counts as StatsD counters to the given UDP host:port
+	KindStatsD = "statsd"
+	// KindGraphite sends per-value counts as Graphite plaintext to the given TCP host:port
+	KindGraphite = "graphite"
+)
+
+// Kinds lists the reporter kinds accepted by Open
+var Kinds = []string{KindLog, KindTable, KindCSV, KindNDJSON, KindTemplate, KindWebhook, KindOTLP, KindStatsD, KindGraphite}
+
+// Options holds the settings shared by reporters opened with Open
+type Options struct {
+	Logger *logger.Logger
+	// Webhook configures webhook reporters; its URL is taken from the target
+	Webhook WebhookConfig
+	// OTLP configures OTLP metrics reporters; its endpoint is taken from the target
+	OTLP OTLPConfig
+	// Line configures statsd and graphite reporters; its address is taken from the target
+	Line LineConfig
+	// Metrics records webhook and OTLP delivery metrics; nil uses an unregistered instance
+	Metrics *metrics.Metrics
+}
+
+// Validate checks that a reporter of kind can be opened with target
+func Validate(kind, target string) error {
+	switch kind {
+	case KindLog:
+		if target != "" {
+			return fmt.Errorf("reporter %q does not take a target", kind)
+		}
+	case KindTable, KindCSV:
+	case KindNDJSON, KindTemplate:
+		if target == "" {
+			return fmt.Errorf("reporter %q requires a file path", kind)
+		}
+	case KindWebhook:
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("reporter %q requires an http(s) URL, got %q", kind, target)
+		}
+	case KindOTLP, KindStatsD, KindGraphite:
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("reporter %q requires a host:port endpoint, got %q", kind, target)
+		}
+	default:
+		return fmt.Errorf("unknown reporter %q (must be one of %v)", kind, Kinds)
+	}
+	return nil
+}
+
+// Open creates a reporter of kind. target is the output file for table, csv
+// and ndjson reporters ("" or "-" for stdout), the template file for template
+// reporters, the URL for webhook reporters and the host:port address for otlp,
+// statsd and graphite reporters. Output files are appended to.
+func Open(kind, target string, opts Options) (Reporter, error) {
+	if err := Validate(kind, target); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case KindLog:
+		return NewLogReporter(opts.Logger), nil
+	case KindWebhook:
+		config := opts.Webhook
+		config.URL = target
+		config.Metrics = opts.Metrics
+		return NewWebhookReporter(config, opts.Logger)
+	case KindOTLP:
+		config := opts.OTLP
+		config.Endpoint = target
+		config.Metrics = opts.Metrics
+		return NewOTLPReporter(config, opts.Logger)
+	case KindStatsD:
+		config := opts.Line
+		config.Address = target
+		return NewStatsDReporter(config)
+	case KindGraphite:
+		config := opts.Line
+		config.Address = target
+		return NewGraphiteReporter(config, opts.Logger)
+	case KindTemplate:
+		text, err := os.ReadFile(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report template: %w", err)
+		}
+		tmpl, err := ParseTemplate(filepath.Base(target), string(text))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse report template: %w", err)
+		}
+		return NewTemplateReporter(os.Stdout, tmpl), nil
+	}
+
+	out, empty, err := openOutput(target)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case KindTable:
+		return NewTableReporter(out), nil
+	case KindCSV:
+		return NewCSVReporter(out, empty), nil
+	default:
+		return NewNDJSONReporter(out), nil
+	}
+}
+
+// openOutput opens target for appending, reporting whether it was empty
+func openOutput(target string) (io.Writer, bool, error) {
+	if target == "" || target == "-" {
+		return os.Stdout, true, nil
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open report output: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, false, fmt.Errorf("failed to open report output: %w", err)
+	}
+	return f, info.Size() == 0, nil
+}
+
+// closeOutput closes out unless it is a standard stream
+func closeOutput(out io.Writer) error {
+	if out == os.Stdout || out == os.Stderr {
+		return nil
+	}
+	if closer, ok := out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
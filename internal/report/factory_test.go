@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		kind    string
+		target  string
+		wantErr bool
+	}{
+		{kind: KindLog},
+		{kind: KindLog, target: "out.log", wantErr: true},
+		{kind: KindTable},
+		{kind: KindCSV, target: "counts.csv"},
+		{kind: KindNDJSON, wantErr: true},
+		{kind: KindNDJSON, target: "reports.ndjson"},
+		{kind: KindTemplate, wantErr: true},
+		{kind: KindWebhook, target: "https://bot.example.com/hooks/otlp"},
+		{kind: KindWebhook, target: "bot.example.com/hooks", wantErr: true},
+		{kind: KindWebhook, wantErr: true},
+		{kind: KindOTLP, target: "collector:4317"},
+		{kind: KindOTLP, target: "collector", wantErr: true},
+		{kind: KindStatsD, target: "localhost:8125"},
+		{kind: KindGraphite, wantErr: true},
+		{kind: "carrier-pigeon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := Validate(tt.kind, tt.target); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q, %q) error = %v, wantErr %v", tt.kind, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOpen_NDJSONAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.ndjson")
+	testLogger, _ := logger.New(false)
+
+	// Two reporters in a row stand in for a restart
+	for i := 0; i < 2; i++ {
+		reporter, err := Open(KindNDJSON, path, Options{Logger: testLogger})
+		if err != nil {
+			t.Fatalf("Failed to open reporter: %v", err)
+		}
+		if err := reporter.Report(sampleReport()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := reporter.Close(); err != nil {
+			t.Fatalf("Failed to close reporter: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+
+	var decoded Report
+	if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil {
+		t.Fatalf("Failed to decode line: %v", err)
+	}
+	if decoded.WindowNumber != 7 || decoded.Delta.Values[1].Count != 3 || decoded.Anomalies[0].Direction != "drop" {
+		t.Errorf("Unexpected decoded report %+v", decoded)
+	}
+}
+
+func TestOpen_CSVHeaderOnlyForNewFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counts.csv")
+	testLogger, _ := logger.New(false)
+
+	for i := 0; i < 2; i++ {
+		reporter, err := Open(KindCSV, path, Options{Logger: testLogger})
+		if err != nil {
+			t.Fatalf("Failed to open reporter: %v", err)
+		}
+		_ = reporter.Report(sampleReport())
+		_ = reporter.Close()
+	}
+
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "window_number"); n != 1 {
+		t.Errorf("Expected a single header row, got %d", n)
+	}
+}
+
+func TestOpen_Template(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.tmpl")
+	testLogger, _ := logger.New(false)
+
+	if err := os.WriteFile(path, []byte("{{.WindowNumber"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if _, err := Open(KindTemplate, path, Options{Logger: testLogger}); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+
+	if _, err := Open(KindTemplate, filepath.Join(dir, "missing.tmpl"), Options{Logger: testLogger}); err == nil {
+		t.Errorf("Expected an error for a missing template")
+	}
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// graphiteQueueSize bounds the reports waiting to be sent
+const graphiteQueueSize = 64
+
+// GraphiteReporter sends each window's per-value record and byte counts as
+// Graphite plaintext lines over TCP, timestamped with the window end. Lines
+// are sent by a background worker so an unreachable receiver never delays
+// window rollover; reports are dropped when the worker falls too far behind.
+type GraphiteReporter struct {
+	config LineConfig
+	namer  *lineNamer
+	logger *logger.Logger
+	// conn is only used by the worker
+	conn net.Conn
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+}
+
+// NewGraphiteReporter creates a Graphite reporter. It connects on the first
+// report, so an unavailable receiver does not prevent startup.
+func NewGraphiteReporter(config LineConfig, logger *logger.Logger) (*GraphiteReporter, error) {
+	namer, err := newLineNamer(config)
+	if err != nil {
+		return nil, err
+	}
+	g := &GraphiteReporter{
+		config: config,
+		namer:  namer,
+		logger: logger.With("graphite_address", config.Address),
+		queue:  make(chan []byte, graphiteQueueSize),
+		done:   make(chan struct{}),
+	}
+	go g.run()
+
+	return g, nil
+}
+
+// Report queues the window's counts to be sent in a single write. Reports
+// without a window delta are skipped.
+func (g *GraphiteReporter) Report(r Report) error {
+	if r.Delta == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	timestamp := " " + strconv.FormatInt(r.End.Unix(), 10) + "\n"
+	g.namer.lineMetrics(r, func(name string, value int64) {
+		buf.WriteString(name + " " + strconv.FormatInt(value, 10) + timestamp)
+	})
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return fmt.Errorf("Graphite reporter is closed")
+	}
+	select {
+	case g.queue <- buf.Bytes():
+		return nil
+	default:
+		return fmt.Errorf("Graphite send queue is full, dropping window %d", r.WindowNumber)
+	}
+}
+
+// Close sends the queued reports and closes the connection
+func (g *GraphiteReporter) Close() error {
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.queue)
+	}
+	g.mu.Unlock()
+
+	<-g.done
+	return nil
+}
+
+func (g *GraphiteReporter) run() {
+	defer close(g.done)
+	defer func() {
+		if g.conn != nil {
+			g.conn.Close()
+		}
+	}()
+
+	for data := range g.queue {
+		if err := g.deliver(data); err != nil {
+			g.logger.Errorw("Failed to send Graphite metrics", "error", err)
+		}
+	}
+}
+
+// deliver writes data, reconnecting once if the connection was lost
+func (g *GraphiteReporter) deliver(data []byte) error {
+	err := g.send(data)
+	if err != nil && g.conn != nil {
+		// The receiver may have closed an idle connection; retry once on a new one
+		g.conn.Close()
+		g.conn = nil
+		err = g.send(data)
+	}
+	if err != nil && g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	return err
+}
+
+func (g *GraphiteReporter) send(data []byte) error {
+	if g.conn == nil {
+		conn, err := net.DialTimeout("tcp", g.config.Address, g.config.Timeout)
+		if err != nil {
+			return err
+		}
+		g.conn = conn
+	}
+
+	if err := g.conn.SetWriteDeadline(time.Now().Add(g.config.Timeout)); err != nil {
+		return err
+	}
+	_, err := g.conn.Write(data)
+	return err
+}
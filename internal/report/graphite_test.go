@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+func TestGraphiteReporter_SendsPlaintext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	testLogger, _ := logger.New(false)
+	reporter, err := Open(KindGraphite, listener.Addr().String(), Options{
+		Logger: testLogger,
+		Line:   LineConfig{AttributeKey: "service.name", Template: "logs.{value}.{metric}", Replacement: "_", Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Failed to open reporter: %v", err)
+	}
+	defer reporter.Close()
+
+	r := sampleReport()
+	if err := reporter.Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{
+		"logs.cart.records 1 1792144810",
+		"logs.cart.bytes 100 1792144810",
+		"logs.checkout.records 3 1792144810",
+		"logs.checkout.bytes 300 1792144810",
+	}
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Errorf("Unexpected line %q, want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for Graphite line %q", w)
+		}
+	}
+}
+
+func TestGraphiteReporter_CloseSendsQueuedReports(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	testLogger, _ := logger.New(false)
+	reporter, err := NewGraphiteReporter(LineConfig{
+		Address:      listener.Addr().String(),
+		AttributeKey: "service.name",
+		Template:     "logs.{value}.{metric}",
+		Replacement:  "_",
+		Timeout:      time.Second,
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+
+	// Reports are queued without waiting for the receiver to accept the connection
+	for i := 0; i < 3; i++ {
+		if err := reporter.Report(sampleReport()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	received := make(chan int, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- 0
+			return
+		}
+		defer conn.Close()
+		lines := 0
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines++
+		}
+		received <- lines
+	}()
+
+	if err := reporter.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := reporter.Report(sampleReport()); err == nil {
+		t.Error("Expected a closed reporter to refuse reports")
+	}
+	if got := <-received; got != 12 {
+		t.Errorf("Expected the 3 queued reports to be sent on close, got %d lines", got)
+	}
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMetricTemplate names line-protocol metrics by attribute key, value and metric
+	DefaultMetricTemplate = "otlp_log_parser.{key}.{value}.{metric}"
+	// DefaultSanitizePattern matches the characters replaced in metric name parts
+	DefaultSanitizePattern = "[^A-Za-z0-9_-]"
+)
+
+// LineConfig configures the StatsD and Graphite reporters
+type LineConfig struct {
+	// Address is the host:port of the StatsD agent or Graphite receiver
+	Address string
+	// Template names each metric; {key}, {value} and {metric} (records or bytes)
+	// are replaced with the sanitized attribute key, value and metric
+	Template string
+	// SanitizePattern is a regular expression matching the characters of the key
+	// and value replaced by Replacement
+	SanitizePattern string
+	Replacement     string
+	// AttributeKey is the group-by attribute key substituted for {key}
+	AttributeKey string
+	// Timeout bounds connecting and writing
+	Timeout time.Duration
+}
+
+// ValidateLineConfig checks the metric template and sanitize pattern of config
+func ValidateLineConfig(config LineConfig) error {
+	_, err := newLineNamer(config)
+	return err
+}
+
+// lineNamer renders metric names for line-protocol reporters
+type lineNamer struct {
+	template    string
+	sanitize    *regexp.Regexp
+	replacement string
+	key         string
+}
+
+func newLineNamer(config LineConfig) (*lineNamer, error) {
+	template, pattern := config.Template, config.SanitizePattern
+	if template == "" {
+		template = DefaultMetricTemplate
+	}
+	if pattern == "" {
+		pattern = DefaultSanitizePattern
+	}
+
+	sanitize, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sanitize pattern: %w", err)
+	}
+	if !strings.Contains(template, "{value}") {
+		return nil, fmt.Errorf("metric template %q must contain {value}", template)
+	}
+
+	n := &lineNamer{template: template, sanitize: sanitize, replacement: config.Replacement}
+	n.key = n.clean(config.AttributeKey)
+	return n, nil
+}
+
+// name returns the metric name of a value's metric
+func (n *lineNamer) name(value, metric string) string {
+	return n.keyedName(n.key, value, metric)
+}
+
+// keyedName returns the metric name of a value's metric under an already sanitized key
+func (n *lineNamer) keyedName(key, value, metric string) string {
+	return strings.NewReplacer("{key}", key, "{value}", n.clean(value), "{metric}", metric).Replace(n.template)
+}
+
+func (n *lineNamer) clean(s string) string {
+	return n.sanitize.ReplaceAllLiteralString(s, n.replacement)
+}
+
+// lineMetrics calls emit with the name and value of every per-value metric in r
+func (n *lineNamer) lineMetrics(r Report, emit func(name string, value int64)) {
+	// Reports name their key so metric names follow a reconfigured attribute
+	key := n.key
+	if r.AttributeKey != "" {
+		key = n.clean(r.AttributeKey)
+	}
+	for _, v := range r.Delta.Values {
+		emit(n.keyedName(key, v.Value, "records"), v.Count)
+		emit(n.keyedName(key, v.Value, "bytes"), v.Bytes)
+	}
+}
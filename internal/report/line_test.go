@@ -0,0 +1,64 @@
+package report
+
+import "testing"
+
+func TestLineNamer(t *testing.T) {
+	tests := []struct {
+		name   string
+		config LineConfig
+		value  string
+		want   string
+	}{
+		{
+			name:   "defaults",
+			config: LineConfig{AttributeKey: "service.name", Replacement: "_"},
+			value:  "checkout/v2.1",
+			want:   "otlp_log_parser.service_name.checkout_v2_1.records",
+		},
+		{
+			name:   "custom template and sanitization",
+			config: LineConfig{AttributeKey: "service.name", Template: "logs.{value}.{metric}", SanitizePattern: "[^a-z]+", Replacement: "-"},
+			value:  "Checkout API",
+			want:   "logs.-heckout-.records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namer, err := newLineNamer(tt.config)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := namer.name(tt.value, "records"); got != tt.want {
+				t.Errorf("name() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLineConfig(t *testing.T) {
+	if err := ValidateLineConfig(LineConfig{Template: "logs.{metric}"}); err == nil {
+		t.Errorf("Expected an error for a template without {value}")
+	}
+	if err := ValidateLineConfig(LineConfig{SanitizePattern: "[a-"}); err == nil {
+		t.Errorf("Expected an error for an invalid sanitize pattern")
+	}
+}
+
+func TestLineNamer_ReportAttributeKey(t *testing.T) {
+	namer, err := newLineNamer(LineConfig{AttributeKey: "service.name", Replacement: "_"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := sampleReport()
+	r.AttributeKey = "k8s.namespace"
+	var names []string
+	namer.lineMetrics(r, func(name string, value int64) {
+		names = append(names, name)
+	})
+
+	if len(names) == 0 || names[0] != "otlp_log_parser.k8s_namespace.cart.records" {
+		t.Errorf("Expected names keyed by the report's attribute key, got %v", names)
+	}
+}
@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// LogReporter emits each report as a structured JSON log entry
+type LogReporter struct {
+	logger *logger.Logger
+}
+
+// NewLogReporter creates a reporter logging through logger
+func NewLogReporter(logger *logger.Logger) *LogReporter {
+	return &LogReporter{logger: logger}
+}
+
+// AttributeCount is a value's entry in the attribute_counts log field
+type AttributeCount struct {
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CumulativeEntry is a value's entry in the attribute_cumulative log field
+type CumulativeEntry struct {
+	Total      int64     `json:"total"`
+	SinceReset int64     `json:"since_reset"`
+	Bytes      int64     `json:"bytes"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+func (l *LogReporter) Report(r Report) error {
+	l.logger.Infow("Log attribute counts report", Fields(r)...)
+	return nil
+}
+
+func (l *LogReporter) Close() error {
+	return nil
+}
+
+// Fields returns the structured log fields describing r
+func Fields(r Report) []interface{} {
+	fields := []interface{}{
+		"window_number", r.WindowNumber,
+		"report_mode", r.Mode,
+		"time_range", fmt.Sprintf("%s - %s", r.Start.Format("15:04:05"), r.End.Format("15:04:05")),
+		"duration", r.Duration().Round(time.Millisecond).String(),
+	}
+	if r.Reconfigured {
+		fields = append(fields, "reconfigured", true)
+	}
+	if r.MissedWindows > 0 {
+		fields = append(fields, "missed_windows", r.MissedWindows)
+	}
+
+	if d := r.Delta; d != nil {
+		counts := make(map[string]AttributeCount, len(d.Values))
+		byteCounts := make(map[string]int64, len(d.Values))
+		severityCounts := make(map[string]map[string]int64, len(d.Values))
+		for _, v := range d.Values {
+			counts[v.Value] = AttributeCount{Count: v.Count, Percentage: v.Percentage}
+			byteCounts[v.Value] = v.Bytes
+			severityCounts[v.Value] = v.Severity
+		}
+
+		fields = append(fields,
+			"total_logs", d.TotalLogs,
+			"total_bytes", d.TotalBytes,
+			"unique_values", len(d.Values),
+			"overflow_logs", d.OverflowLogs,
+			"overflow_values", d.OverflowValues,
+			"attribute_counts", counts,
+			"attribute_bytes", byteCounts,
+			"severity_totals", d.Severity,
+			"attribute_severity", severityCounts,
+		)
+
+		if r.DistinctKey != "" {
+			distinctCounts := make(map[string]uint64, len(d.Values))
+			for _, v := range d.Values {
+				distinctCounts[v.Value] = v.Distinct
+			}
+			fields = append(fields, "distinct_key", r.DistinctKey, "distinct_counts", distinctCounts)
+		}
+	}
+
+	if c := r.Cumulative; c != nil {
+		counts := make(map[string]CumulativeEntry, len(c.Values))
+		for _, v := range c.Values {
+			counts[v.Value] = CumulativeEntry{
+				Total:      v.Total,
+				SinceReset: v.SinceReset,
+				Bytes:      v.Bytes,
+				FirstSeen:  v.FirstSeen,
+				LastSeen:   v.LastSeen,
+			}
+		}
+
+		fields = append(fields,
+			"cumulative_since", c.Since,
+			"cumulative_logs", c.TotalLogs,
+			"cumulative_bytes", c.TotalBytes,
+			"reset_at", c.ResetAt,
+			"since_reset_logs", c.SinceResetLogs,
+			"attribute_cumulative", counts,
+		)
+	}
+
+	if r.Anomalies != nil {
+		fields = append(fields, "anomalies", r.Anomalies)
+	}
+
+	return fields
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+// sampleReport builds a report with a delta, cumulative totals and an anomaly
+func sampleReport() Report {
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	return Report{
+		WindowNumber: 7,
+		Mode:         "both",
+		Start:        start,
+		End:          start.Add(10 * time.Second),
+		Delta: &Delta{
+			TotalLogs:  4,
+			TotalBytes: 400,
+			Severity:   map[string]int64{"ERROR": 1, "INFO": 3},
+			Values: []ValueCount{
+				{Value: "cart", Count: 1, Percentage: 25, Bytes: 100, Severity: map[string]int64{"ERROR": 1}},
+				{Value: "checkout", Count: 3, Percentage: 75, Bytes: 300, Severity: map[string]int64{"INFO": 3}},
+			},
+		},
+		Cumulative: &Cumulative{
+			Since:     start.Add(-time.Hour),
+			ResetAt:   start.Add(-time.Hour),
+			TotalLogs: 40,
+			Values: []CumulativeCount{
+				{Value: "cart", Total: 10, SinceReset: 10},
+				{Value: "checkout", Total: 30, SinceReset: 30},
+			},
+		},
+		Anomalies: []Anomaly{{Value: "cart", Count: 1, Expected: 9, ZScore: -3.2, Direction: "drop", Baseline: "ewma"}},
+	}
+}
+
+// field returns the value of a structured log field
+func field(fields []interface{}, key string) interface{} {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == key {
+			return fields[i+1]
+		}
+	}
+	return nil
+}
+
+func TestFields_AttributeCounts(t *testing.T) {
+	fields := Fields(sampleReport())
+
+	counts, ok := field(fields, "attribute_counts").(map[string]AttributeCount)
+	if !ok {
+		t.Fatalf("Expected attribute_counts map, got %T", field(fields, "attribute_counts"))
+	}
+	want := map[string]AttributeCount{
+		"cart":     {Count: 1, Percentage: 25},
+		"checkout": {Count: 3, Percentage: 75},
+	}
+	for value, entry := range want {
+		if counts[value] != entry {
+			t.Errorf("attribute_counts[%q] = %+v, want %+v", value, counts[value], entry)
+		}
+	}
+
+	if got := field(fields, "total_logs"); got != int64(4) {
+		t.Errorf("Expected total_logs 4, got %v", got)
+	}
+	if got, ok := field(fields, "attribute_cumulative").(map[string]CumulativeEntry); !ok || got["checkout"].Total != 30 {
+		t.Errorf("Unexpected attribute_cumulative %v", field(fields, "attribute_cumulative"))
+	}
+	if got, ok := field(fields, "anomalies").([]Anomaly); !ok || len(got) != 1 {
+		t.Errorf("Unexpected anomalies %v", field(fields, "anomalies"))
+	}
+	if field(fields, "distinct_counts") != nil {
+		t.Errorf("Expected no distinct_counts without a distinct key")
+	}
+}
+
+func TestFields_DeltaOmitted(t *testing.T) {
+	r := sampleReport()
+	r.Delta = nil
+	r.Anomalies = nil
+
+	fields := Fields(r)
+
+	if field(fields, "attribute_counts") != nil || field(fields, "anomalies") != nil {
+		t.Errorf("Expected only cumulative fields, got %v", fields)
+	}
+	if field(fields, "cumulative_logs") != int64(40) {
+		t.Errorf("Expected cumulative_logs 40, got %v", field(fields, "cumulative_logs"))
+	}
+}
+
+func TestFields_Reconfigured(t *testing.T) {
+	r := sampleReport()
+	if field(Fields(r), "reconfigured") != nil {
+		t.Errorf("Expected no reconfigured field on a regular window")
+	}
+
+	r.Reconfigured = true
+	if field(Fields(r), "reconfigured") != true {
+		t.Errorf("Expected reconfigured=true, got %v", field(Fields(r), "reconfigured"))
+	}
+}
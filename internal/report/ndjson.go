@@ -0,0 +1,26 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// NDJSONReporter writes each report as one JSON object per line
+type NDJSONReporter struct {
+	out io.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONReporter creates a reporter writing to out. out is closed by Close
+// if it is an io.Closer.
+func NewNDJSONReporter(out io.Writer) *NDJSONReporter {
+	return &NDJSONReporter{out: out, enc: json.NewEncoder(out)}
+}
+
+func (n *NDJSONReporter) Report(r Report) error {
+	return n.enc.Encode(r)
+}
+
+func (n *NDJSONReporter) Close() error {
+	return closeOutput(n.out)
+}
@@ -0,0 +1,227 @@
+package report
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	metricscollectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+const (
+	// OTLPRecordsMetric is the Sum metric carrying per-value log record counts
+	OTLPRecordsMetric = "otlp_log_parser.log_records"
+	// OTLPBytesMetric is the Sum metric carrying per-value serialized log record bytes
+	OTLPBytesMetric = "otlp_log_parser.log_record.bytes"
+
+	// DefaultOTLPServiceName is the exported resource's service.name, distinct
+	// from the services whose logs are counted
+	DefaultOTLPServiceName = "otlp-log-parser-assignment-reports"
+
+	// otlpScopeName identifies this program as the instrumentation scope
+	otlpScopeName = "otlp-log-parser-assignment"
+	// otlpQueueSize bounds the reports waiting for export
+	otlpQueueSize = 64
+)
+
+// OTLPConfig configures an OTLP metrics reporter
+type OTLPConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver
+	Endpoint string
+	// Insecure disables TLS
+	Insecure bool
+	// Timeout bounds a single export call
+	Timeout time.Duration
+	// AttributeKey is the group-by attribute key set on every data point
+	AttributeKey string
+	// ServiceName is the exported resource's service.name; empty uses DefaultOTLPServiceName
+	ServiceName string
+	// Metrics records export metrics; nil uses an unregistered instance
+	Metrics *metrics.Metrics
+}
+
+// OTLPReporter exports the per-value counts of each window as OTLP Sum
+// metrics with delta temporality. Exports run on a background worker so a
+// slow receiver never delays window rollover; reports are dropped when the
+// worker falls too far behind.
+type OTLPReporter struct {
+	config OTLPConfig
+	conn   *grpc.ClientConn
+	client metricscollectorpb.MetricsServiceClient
+	logger *logger.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan *metricscollectorpb.ExportMetricsServiceRequest
+	done   chan struct{}
+}
+
+// NewOTLPReporter creates a reporter exporting to config.Endpoint. The
+// connection is established lazily, so an unavailable receiver does not
+// prevent startup.
+func NewOTLPReporter(config OTLPConfig, logger *logger.Logger) (*OTLPReporter, error) {
+	if config.Metrics == nil {
+		config.Metrics = metrics.New(nil)
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = DefaultOTLPServiceName
+	}
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+
+	conn, err := grpc.NewClient(config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metrics client: %w", err)
+	}
+
+	o := &OTLPReporter{
+		config: config,
+		conn:   conn,
+		client: metricscollectorpb.NewMetricsServiceClient(conn),
+		logger: logger.With("otlp_endpoint", config.Endpoint),
+		queue:  make(chan *metricscollectorpb.ExportMetricsServiceRequest, otlpQueueSize),
+		done:   make(chan struct{}),
+	}
+	go o.run()
+
+	return o, nil
+}
+
+// Report queues the window's per-value counts for export. Reports without a
+// window delta are skipped.
+func (o *OTLPReporter) Report(r Report) error {
+	if r.Delta == nil {
+		return nil
+	}
+
+	attributeKey := r.AttributeKey
+	if attributeKey == "" {
+		attributeKey = o.config.AttributeKey
+	}
+	req := BuildMetricsRequest(r, attributeKey, o.config.ServiceName)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return fmt.Errorf("OTLP metrics reporter is closed")
+	}
+	select {
+	case o.queue <- req:
+		return nil
+	default:
+		o.config.Metrics.OTLPMetricsExportsTotal.WithLabelValues("dropped").Inc()
+		return fmt.Errorf("OTLP metrics export queue is full, dropping window %d", r.WindowNumber)
+	}
+}
+
+// Close exports the queued reports and closes the connection
+func (o *OTLPReporter) Close() error {
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.queue)
+	}
+	o.mu.Unlock()
+
+	<-o.done
+	return o.conn.Close()
+}
+
+func (o *OTLPReporter) run() {
+	defer close(o.done)
+
+	for req := range o.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), o.config.Timeout)
+		resp, err := o.client.Export(ctx, req)
+		cancel()
+
+		switch {
+		case err != nil:
+			o.config.Metrics.OTLPMetricsExportsTotal.WithLabelValues("failure").Inc()
+			o.logger.Errorw("Failed to export window metrics", "error", err)
+		case resp.GetPartialSuccess().GetRejectedDataPoints() > 0:
+			o.config.Metrics.OTLPMetricsExportsTotal.WithLabelValues("partial").Inc()
+			o.logger.Warnw("Window metrics partially rejected",
+				"rejected_data_points", resp.GetPartialSuccess().GetRejectedDataPoints(),
+				"message", resp.GetPartialSuccess().GetErrorMessage(),
+			)
+		default:
+			o.config.Metrics.OTLPMetricsExportsTotal.WithLabelValues("success").Inc()
+		}
+	}
+}
+
+// BuildMetricsRequest converts a window report into delta Sum metrics of
+// record counts and bytes, one data point per value keyed by attributeKey,
+// under a resource named serviceName
+func BuildMetricsRequest(r Report, attributeKey, serviceName string) *metricscollectorpb.ExportMetricsServiceRequest {
+	start := uint64(r.Start.UnixNano())
+	end := uint64(r.End.UnixNano())
+
+	records := make([]*metricspb.NumberDataPoint, 0, len(r.Delta.Values))
+	bytes := make([]*metricspb.NumberDataPoint, 0, len(r.Delta.Values))
+	for _, v := range r.Delta.Values {
+		attrs := []*commonpb.KeyValue{stringAttribute(attributeKey, v.Value)}
+		records = append(records, &metricspb.NumberDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v.Count},
+		})
+		bytes = append(bytes, &metricspb.NumberDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Value:             &metricspb.NumberDataPoint_AsInt{AsInt: v.Bytes},
+		})
+	}
+
+	return &metricscollectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{stringAttribute("service.name", serviceName)},
+			},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope: &commonpb.InstrumentationScope{Name: otlpScopeName},
+				Metrics: []*metricspb.Metric{
+					deltaSum(OTLPRecordsMetric, "Log records counted per attribute value in the window.", "{record}", records),
+					deltaSum(OTLPBytesMetric, "Serialized size of the log records counted per attribute value in the window.", "By", bytes),
+				},
+			}},
+		}},
+	}
+}
+
+func deltaSum(name, description, unit string, points []*metricspb.NumberDataPoint) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name:        name,
+		Description: description,
+		Unit:        unit,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			IsMonotonic:            true,
+			DataPoints:             points,
+		}},
+	}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
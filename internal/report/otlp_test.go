@@ -0,0 +1,131 @@
+package report
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	metricscollectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// metricsCollector is an in-process OTLP metrics receiver that hands every
+// export request to the test
+type metricsCollector struct {
+	metricscollectorpb.UnimplementedMetricsServiceServer
+	requests chan *metricscollectorpb.ExportMetricsServiceRequest
+}
+
+func (c *metricsCollector) Export(ctx context.Context, req *metricscollectorpb.ExportMetricsServiceRequest) (*metricscollectorpb.ExportMetricsServiceResponse, error) {
+	c.requests <- req
+	return &metricscollectorpb.ExportMetricsServiceResponse{}, nil
+}
+
+// startMetricsCollector serves a metricsCollector on a loopback port
+func startMetricsCollector(t *testing.T) (*metricsCollector, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	collector := &metricsCollector{requests: make(chan *metricscollectorpb.ExportMetricsServiceRequest, 10)}
+	server := grpc.NewServer()
+	metricscollectorpb.RegisterMetricsServiceServer(server, collector)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return collector, listener.Addr().String()
+}
+
+func TestOTLPReporter_ExportsWindowAsDeltaSum(t *testing.T) {
+	collector, endpoint := startMetricsCollector(t)
+	testLogger, _ := logger.New(false)
+
+	reporter, err := Open(KindOTLP, endpoint, Options{
+		Logger: testLogger,
+		OTLP:   OTLPConfig{Insecure: true, Timeout: 5 * time.Second, AttributeKey: "service.name"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to open reporter: %v", err)
+	}
+	defer reporter.Close()
+
+	r := sampleReport()
+	if err := reporter.Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var req *metricscollectorpb.ExportMetricsServiceRequest
+	select {
+	case req = <-collector.requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the export")
+	}
+
+	resource := req.GetResourceMetrics()[0].GetResource().GetAttributes()[0]
+	if resource.GetKey() != "service.name" || resource.GetValue().GetStringValue() != DefaultOTLPServiceName {
+		t.Errorf("Expected resource service.name %q, got %v", DefaultOTLPServiceName, resource)
+	}
+
+	metrics := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(metrics) != 2 || metrics[0].GetName() != OTLPRecordsMetric || metrics[1].GetName() != OTLPBytesMetric {
+		t.Fatalf("Unexpected metrics %v", metrics)
+	}
+
+	sum := metrics[0].GetSum()
+	if sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA || !sum.GetIsMonotonic() {
+		t.Errorf("Expected a monotonic delta sum, got %v", sum)
+	}
+
+	counts := make(map[string]int64)
+	for _, point := range sum.GetDataPoints() {
+		attr := point.GetAttributes()[0]
+		if attr.GetKey() != "service.name" {
+			t.Errorf("Expected data points keyed by service.name, got %q", attr.GetKey())
+		}
+		if point.GetStartTimeUnixNano() != uint64(r.Start.UnixNano()) || point.GetTimeUnixNano() != uint64(r.End.UnixNano()) {
+			t.Errorf("Data point does not span the window: %v", point)
+		}
+		counts[attr.GetValue().GetStringValue()] = point.GetAsInt()
+	}
+	if counts["cart"] != 1 || counts["checkout"] != 3 || len(counts) != 2 {
+		t.Errorf("Unexpected record counts %v", counts)
+	}
+
+	if got := metrics[1].GetSum().GetDataPoints()[1].GetAsInt(); got != 300 {
+		t.Errorf("Expected 300 bytes for checkout, got %d", got)
+	}
+}
+
+func TestBuildMetricsRequest_ServiceName(t *testing.T) {
+	req := BuildMetricsRequest(sampleReport(), "service.name", "parser-reports")
+
+	resource := req.GetResourceMetrics()[0].GetResource().GetAttributes()[0]
+	if got := resource.GetValue().GetStringValue(); got != "parser-reports" {
+		t.Errorf("Expected resource service.name parser-reports, got %q", got)
+	}
+}
+
+func TestOTLPReporter_SkipsCumulativeOnlyReports(t *testing.T) {
+	collector, endpoint := startMetricsCollector(t)
+	testLogger, _ := logger.New(false)
+
+	reporter, _ := NewOTLPReporter(OTLPConfig{Endpoint: endpoint, Insecure: true, Timeout: 5 * time.Second, AttributeKey: "service.name"}, testLogger)
+
+	r := sampleReport()
+	r.Delta = nil
+	if err := reporter.Report(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Close drains the queue, so nothing can arrive afterwards
+	reporter.Close()
+
+	if len(collector.requests) != 0 {
+		t.Errorf("Expected no export for a report without a delta, got %d", len(collector.requests))
+	}
+}
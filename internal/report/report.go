@@ -0,0 +1,97 @@
+package report
+
+import (
+	"time"
+)
+
+// Reporter publishes completed window reports
+type Reporter interface {
+	// Report publishes a single window report. Reporters share the report's
+	// slices and maps and must not modify them.
+	Report(r Report) error
+	// Close flushes any buffered output and releases the reporter's resources
+	Close() error
+}
+
+// Report is the summary of a completed window handed to every Reporter
+type Report struct {
+	WindowNumber int64     `json:"window_number"`
+	Mode         string    `json:"report_mode"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	// AttributeKey is the group-by attribute the window was counted by
+	AttributeKey string `json:"attribute_key,omitempty"`
+	// Reconfigured marks a window closed early because grouping changed
+	Reconfigured bool `json:"reconfigured,omitempty"`
+	// MissedWindows marks a gap report: the number of windows between Start
+	// and End that passed while the process was down, which hold no counts
+	MissedWindows int64 `json:"missed_windows,omitempty"`
+	// DistinctKey names the distinct-of attribute, empty when distinct tracking is disabled
+	DistinctKey string `json:"distinct_key,omitempty"`
+	// Delta describes the window itself, nil when the report mode omits it
+	Delta *Delta `json:"delta,omitempty"`
+	// Cumulative holds running totals, nil when the report mode omits them
+	Cumulative *Cumulative `json:"cumulative,omitempty"`
+	// Anomalies is nil when anomaly detection is disabled
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+}
+
+// Duration returns the length of the window
+func (r Report) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Delta describes the log records counted in a single window
+type Delta struct {
+	TotalLogs  int64 `json:"total_logs"`
+	TotalBytes int64 `json:"total_bytes"`
+	// OverflowLogs is the number of records counted under the overflow value
+	OverflowLogs int64 `json:"overflow_logs"`
+	// OverflowValues estimates the distinct values diverted to the overflow value
+	OverflowValues uint64           `json:"overflow_values"`
+	Severity       map[string]int64 `json:"severity"`
+	// Values are sorted by value
+	Values []ValueCount `json:"values"`
+}
+
+// ValueCount is a single attribute value's share of a window
+type ValueCount struct {
+	Value      string           `json:"value"`
+	Count      int64            `json:"count"`
+	Percentage float64          `json:"percentage"`
+	Bytes      int64            `json:"bytes"`
+	Severity   map[string]int64 `json:"severity,omitempty"`
+	// Distinct estimates distinct-of values, 0 when distinct tracking is disabled
+	Distinct uint64 `json:"distinct,omitempty"`
+}
+
+// Cumulative holds running totals since start and since the last reset
+type Cumulative struct {
+	Since          time.Time `json:"since"`
+	ResetAt        time.Time `json:"reset_at"`
+	TotalLogs      int64     `json:"total_logs"`
+	TotalBytes     int64     `json:"total_bytes"`
+	SinceResetLogs int64     `json:"since_reset_logs"`
+	// Values are sorted by value
+	Values []CumulativeCount `json:"values"`
+}
+
+// CumulativeCount is a value's running totals
+type CumulativeCount struct {
+	Value      string    `json:"value"`
+	Total      int64     `json:"total"`
+	SinceReset int64     `json:"since_reset"`
+	Bytes      int64     `json:"bytes"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// Anomaly describes a value whose window count deviates from its baseline
+type Anomaly struct {
+	Value     string  `json:"value"`
+	Count     int64   `json:"count"`
+	Expected  float64 `json:"expected"`
+	ZScore    float64 `json:"z_score"`
+	Direction string  `json:"direction"`
+	Baseline  string  `json:"baseline"`
+}
@@ -0,0 +1,70 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// statsdPacketSize keeps StatsD datagrams within a typical Ethernet MTU
+const statsdPacketSize = 1432
+
+// StatsDReporter sends each window's per-value record and byte counts as
+// StatsD counters over UDP
+type StatsDReporter struct {
+	namer *lineNamer
+	conn  net.Conn
+}
+
+// NewStatsDReporter creates a StatsD reporter. UDP is connectionless, so an
+// unavailable agent does not prevent startup.
+func NewStatsDReporter(config LineConfig) (*StatsDReporter, error) {
+	namer, err := newLineNamer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("udp", config.Address, config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve StatsD address: %w", err)
+	}
+
+	return &StatsDReporter{namer: namer, conn: conn}, nil
+}
+
+// Report sends the window's counts, several per datagram. Reports without a
+// window delta are skipped.
+func (s *StatsDReporter) Report(r Report) error {
+	if r.Delta == nil {
+		return nil
+	}
+
+	var packet bytes.Buffer
+	var err error
+	flush := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		if _, writeErr := s.conn.Write(packet.Bytes()); writeErr != nil && err == nil {
+			err = fmt.Errorf("failed to send StatsD metrics: %w", writeErr)
+		}
+		packet.Reset()
+	}
+
+	s.namer.lineMetrics(r, func(name string, value int64) {
+		line := name + ":" + strconv.FormatInt(value, 10) + "|c\n"
+		if packet.Len()+len(line) > statsdPacketSize {
+			flush()
+		}
+		packet.WriteString(line)
+	})
+	flush()
+
+	return err
+}
+
+// Close closes the socket
+func (s *StatsDReporter) Close() error {
+	return s.conn.Close()
+}
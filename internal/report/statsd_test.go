@@ -0,0 +1,75 @@
+package report
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsDReporter_SendsCounters(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	reporter, err := Open(KindStatsD, listener.LocalAddr().String(), Options{
+		Line: LineConfig{AttributeKey: "service.name", Replacement: "_", Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Failed to open reporter: %v", err)
+	}
+	defer reporter.Close()
+
+	if err := reporter.Report(sampleReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := make([]byte, statsdPacketSize)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read datagram: %v", err)
+	}
+
+	want := "otlp_log_parser.service_name.cart.records:1|c\n" +
+		"otlp_log_parser.service_name.cart.bytes:100|c\n" +
+		"otlp_log_parser.service_name.checkout.records:3|c\n" +
+		"otlp_log_parser.service_name.checkout.bytes:300|c\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("Unexpected datagram:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStatsDReporter_SplitsDatagrams(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	reporter, _ := NewStatsDReporter(LineConfig{Address: listener.LocalAddr().String(), Replacement: "_", Timeout: time.Second})
+	defer reporter.Close()
+
+	r := sampleReport()
+	r.Delta.Values = nil
+	for i := 0; i < 40; i++ {
+		r.Delta.Values = append(r.Delta.Values, ValueCount{Value: strings.Repeat("v", 20) + string(rune('a'+i%26)), Count: 1})
+	}
+	_ = reporter.Report(r)
+
+	lines := 0
+	buf := make([]byte, 64*1024)
+	_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for lines < 80 {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read datagram after %d lines: %v", lines, err)
+		}
+		if n > statsdPacketSize {
+			t.Errorf("Datagram of %d bytes exceeds %d", n, statsdPacketSize)
+		}
+		lines += strings.Count(string(buf[:n]), "\n")
+	}
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"otlp-log-parser-assignment/internal/severity"
+)
+
+// TableReporter renders each report as a beautiful ASCII table
+type TableReporter struct {
+	out io.Writer
+}
+
+// NewTableReporter creates a reporter writing tables to out
+func NewTableReporter(out io.Writer) *TableReporter {
+	return &TableReporter{out: out}
+}
+
+// tableSeverities are the severity columns of the table, in display order
+var tableSeverities = []severity.Bucket{
+	severity.Trace, severity.Debug, severity.Info, severity.Warn, severity.Error, severity.Fatal, severity.Unspecified,
+}
+
+func (t *TableReporter) Report(r Report) error {
+	w := bufio.NewWriter(t.out)
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "╔═══════════════════════════════════════════════════════════╗")
+	fmt.Fprintln(w, "║          Log Attribute Counts Report                      ║")
+	fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+	fmt.Fprintf(w, "║ Window #%-3d                                               ║\n", r.WindowNumber)
+	fmt.Fprintf(w, "║ Time Range: %-45s ║\n", r.Start.Format("15:04:05")+" - "+r.End.Format("15:04:05"))
+	fmt.Fprintf(w, "║ Duration: %-47s ║\n", r.Duration().Round(time.Millisecond).String())
+	if r.MissedWindows > 0 {
+		fmt.Fprintf(w, "║ Missed Windows (server down): %-27d ║\n", r.MissedWindows)
+	}
+
+	if d := r.Delta; d != nil {
+		fmt.Fprintf(w, "║ Total Logs: %-45d ║\n", d.TotalLogs)
+		fmt.Fprintf(w, "║ Total Bytes: %-44s ║\n", formatBytes(d.TotalBytes))
+		fmt.Fprintf(w, "║ Unique Values: %-42d ║\n", len(d.Values))
+		if d.OverflowValues > 0 {
+			fmt.Fprintf(w, "║ Overflowed Values: %-38d ║\n", d.OverflowValues)
+		}
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		fmt.Fprintln(w, "║ Attribute Value Counts:              Logs      Bytes      ║")
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		for _, v := range d.Values {
+			fmt.Fprintf(w, "║ %-28s %8d %10s (%5.1f%%) ║\n", truncate(v.Value, 28), v.Count, formatBytes(v.Bytes), v.Percentage)
+		}
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		fmt.Fprintln(w, "║ Severity:       TRACE DEBUG  INFO  WARN ERROR FATAL   N/A ║")
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		for _, v := range d.Values {
+			fmt.Fprintf(w, "║ %-15s", truncate(v.Value, 15))
+			for _, bucket := range tableSeverities {
+				fmt.Fprintf(w, " %5s", formatCount(v.Severity[bucket.String()]))
+			}
+			fmt.Fprintln(w, " ║")
+		}
+		if r.DistinctKey != "" {
+			fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+			fmt.Fprintf(w, "║ Distinct %-48s ║\n", truncate(r.DistinctKey, 44)+":")
+			fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+			for _, v := range d.Values {
+				fmt.Fprintf(w, "║ %-40s ~%15d ║\n", truncate(v.Value, 40), v.Distinct)
+			}
+		}
+	}
+
+	if c := r.Cumulative; c != nil && len(c.Values) > 0 {
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		fmt.Fprintf(w, "║ Cumulative Since: %-39s ║\n", c.Since.Format("2006-01-02 15:04:05"))
+		fmt.Fprintf(w, "║ Last Reset: %-45s ║\n", c.ResetAt.Format("2006-01-02 15:04:05"))
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		fmt.Fprintf(w, "║ %-21s %8s %8s %-17s ║\n", "Cumulative Totals:", "Total", "Reset", "First - Last Seen")
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		for _, v := range c.Values {
+			seen := v.FirstSeen.Format("15:04:05") + "-" + v.LastSeen.Format("15:04:05")
+			fmt.Fprintf(w, "║ %-21s %8s %8s %-17s ║\n", truncate(v.Value, 21), formatCount(v.Total), formatCount(v.SinceReset), seen)
+		}
+	}
+
+	if len(r.Anomalies) > 0 {
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		fmt.Fprintf(w, "║ %-24s %-5s %8s %8s %8s ║\n", "Anomalies:", "", "Logs", "Expected", "Z-Score")
+		fmt.Fprintln(w, "╠═══════════════════════════════════════════════════════════╣")
+		for _, a := range r.Anomalies {
+			fmt.Fprintf(w, "║ %-24s %-5s %8s %8.1f %8.1f ║\n", truncate(a.Value, 24), a.Direction,
+				formatCount(a.Count), a.Expected, a.ZScore)
+		}
+	}
+
+	fmt.Fprintln(w, "╚═══════════════════════════════════════════════════════════╝")
+	fmt.Fprintln(w, "")
+
+	return w.Flush()
+}
+
+func (t *TableReporter) Close() error {
+	return nil
+}
+
+// formatCount renders a count in at most five characters
+func formatCount(n int64) string {
+	switch {
+	case n < 100000:
+		return fmt.Sprintf("%d", n)
+	case n < 10000000:
+		return fmt.Sprintf("%dk", n/1000)
+	default:
+		return fmt.Sprintf("%dM", n/1000000)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit suffix
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// truncate truncates a string to maxLen characters
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	if maxLen <= 3 {
+		return s[:maxLen]
+	}
+	return s[:maxLen-3] + "..."
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTableReporter_Report(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewTableReporter(&out).Report(sampleReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	table := out.String()
+	for _, want := range []string{"Window #7", "checkout", "75.0%", "Cumulative Totals:", "Anomalies:", "drop"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("Expected table to contain %q:\n%s", want, table)
+		}
+	}
+
+	// Every row of the box must line up
+	for _, line := range strings.Split(strings.TrimSpace(table), "\n") {
+		if n := utf8.RuneCountInString(line); n != 61 {
+			t.Errorf("Expected 61 columns, got %d: %q", n, line)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{bytes: 0, want: "0B"},
+		{bytes: 1023, want: "1023B"},
+		{bytes: 1024, want: "1.0KiB"},
+		{bytes: 1536, want: "1.5KiB"},
+		{bytes: 5 * 1024 * 1024, want: "5.0MiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.bytes); got != tt.want {
+			t.Errorf("formatBytes(%d) = %s, want %s", tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 99999, want: "99999"},
+		{n: 123456, want: "123k"},
+		{n: 45000000, want: "45M"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("formatCount(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
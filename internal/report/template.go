@@ -0,0 +1,38 @@
+package report
+
+import (
+	"io"
+	"text/template"
+)
+
+// TemplateFuncs are the helper functions available to report templates
+var TemplateFuncs = template.FuncMap{
+	"formatBytes": formatBytes,
+	"formatCount": formatCount,
+	"truncate":    truncate,
+}
+
+// TemplateReporter renders each report with a Go text/template
+type TemplateReporter struct {
+	out  io.Writer
+	tmpl *template.Template
+}
+
+// NewTemplateReporter creates a reporter rendering tmpl to out for every
+// report. out is closed by Close if it is an io.Closer.
+func NewTemplateReporter(out io.Writer, tmpl *template.Template) *TemplateReporter {
+	return &TemplateReporter{out: out, tmpl: tmpl}
+}
+
+// ParseTemplate parses a report template with TemplateFuncs available
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(TemplateFuncs).Parse(text)
+}
+
+func (t *TemplateReporter) Report(r Report) error {
+	return t.tmpl.Execute(t.out, r)
+}
+
+func (t *TemplateReporter) Close() error {
+	return closeOutput(t.out)
+}
@@ -0,0 +1,22 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTemplateReporter_Report(t *testing.T) {
+	tmpl, err := ParseTemplate("test", `#{{.WindowNumber}}{{range .Delta.Values}} {{.Value}}={{.Count}}/{{formatBytes .Bytes}}{{end}}`+"\n")
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := NewTemplateReporter(&out, tmpl).Report(sampleReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := "#7 cart=1/100B checkout=3/300B\n"; out.String() != want {
+		t.Errorf("Got %q, want %q", out.String(), want)
+	}
+}
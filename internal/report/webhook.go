@@ -0,0 +1,422 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=" followed by the hex HMAC-SHA256
+	// of the timestamp, a dot and the request body
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader carries the Unix time the delivery attempt was signed at
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+
+	queueFileSuffix = ".json"
+)
+
+// WebhookConfig configures a webhook reporter
+type WebhookConfig struct {
+	URL string
+	// Secret signs every delivery with HMAC-SHA256; empty disables signing
+	Secret string
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxRetries is the number of retries after a failed attempt before the
+	// report is parked until the next retry round
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// QueueDir persists undelivered reports so they survive restarts; empty keeps them in memory
+	QueueDir string
+	// QueueSize caps the undelivered reports kept; the oldest are dropped first
+	QueueSize int
+	// DrainTimeout bounds how long Close keeps delivering queued reports; 0 uses Timeout
+	DrainTimeout time.Duration
+	// Metrics records delivery metrics; nil uses an unregistered instance
+	Metrics *metrics.Metrics
+}
+
+// WebhookReporter POSTs each report as JSON to a URL. Reports are queued and
+// delivered in order by a background worker, so a slow or unavailable
+// endpoint never delays window rollover.
+type WebhookReporter struct {
+	config WebhookConfig
+	client *http.Client
+	logger *logger.Logger
+	queue  *webhookQueue
+
+	mu     sync.Mutex
+	closed bool
+
+	wake chan struct{}
+	// draining is closed by Close; the worker returns once the queue is empty
+	draining chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// errPermanent marks a delivery the endpoint rejected and that must not be retried
+var errPermanent = errors.New("permanent webhook failure")
+
+// NewWebhookReporter creates a webhook reporter and starts delivering any
+// reports left in its queue directory
+func NewWebhookReporter(config WebhookConfig, logger *logger.Logger) (*WebhookReporter, error) {
+	if config.Metrics == nil {
+		config.Metrics = metrics.New(nil)
+	}
+	dir := ""
+	if config.QueueDir != "" {
+		// One queue per URL so several webhooks can share a directory
+		sum := sha256.Sum256([]byte(config.URL))
+		dir = filepath.Join(config.QueueDir, hex.EncodeToString(sum[:8]))
+	}
+	queue, dropped, err := openWebhookQueue(dir, config.QueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = config.Timeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &WebhookReporter{
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		logger:   logger.With("webhook", config.URL),
+		queue:    queue,
+		wake:     make(chan struct{}, 1),
+		draining: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	if dropped > 0 {
+		config.Metrics.WebhookDeliveriesTotal.WithLabelValues("dropped").Add(float64(dropped))
+		w.logger.Warnw("Webhook queue directory held more reports than the queue size, dropped the oldest", "dropped", dropped)
+	}
+	go w.run()
+
+	return w, nil
+}
+
+// Report queues r for delivery
+func (w *WebhookReporter) Report(r Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("webhook reporter is closed")
+	}
+
+	dropped, err := w.queue.push(body)
+	if dropped > 0 {
+		w.config.Metrics.WebhookDeliveriesTotal.WithLabelValues("dropped").Add(float64(dropped))
+		w.logger.Warnw("Webhook queue full, dropped oldest reports", "dropped", dropped)
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops accepting reports and keeps delivering the queued ones for up
+// to DrainTimeout before it stops delivery. Reports still undelivered stay in
+// the queue directory, if any, and are lost otherwise.
+func (w *WebhookReporter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.draining)
+	}
+	w.mu.Unlock()
+
+	timer := time.NewTimer(w.config.DrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+	case <-timer.C:
+		w.cancel()
+		<-w.done
+		if pending := w.queue.len(); pending > 0 && w.queue.dir == "" {
+			w.config.Metrics.WebhookDeliveriesTotal.WithLabelValues("dropped").Add(float64(pending))
+			w.logger.Warnw("Webhook reports undelivered at shutdown were dropped", "dropped", pending)
+		}
+	}
+	w.cancel()
+	return nil
+}
+
+// Pending returns the number of reports waiting for delivery
+func (w *WebhookReporter) Pending() int {
+	return w.queue.len()
+}
+
+func (w *WebhookReporter) run() {
+	defer close(w.done)
+
+	for {
+		item, ok := w.queue.peek()
+		if !ok {
+			select {
+			case <-w.wake:
+				continue
+			case <-w.draining:
+				return
+			case <-w.ctx.Done():
+				return
+			}
+		}
+
+		err := w.deliver(item.body)
+		switch {
+		case err == nil:
+			w.config.Metrics.WebhookDeliveriesTotal.WithLabelValues("success").Inc()
+			w.queue.remove(item)
+		case errors.Is(err, errPermanent):
+			w.config.Metrics.WebhookDeliveriesTotal.WithLabelValues("rejected").Inc()
+			w.logger.Errorw("Webhook rejected report, dropping it", "error", err)
+			w.queue.remove(item)
+		case w.ctx.Err() != nil:
+			return
+		default:
+			// Keep the report at the head of the queue and try again later
+			w.logger.Warnw("Webhook delivery failed, will retry", "pending", w.queue.len(), "error", err)
+			select {
+			case <-time.After(w.config.MaxBackoff):
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// deliver POSTs body, retrying with exponential backoff
+func (w *WebhookReporter) deliver(body []byte) error {
+	backoff := w.config.InitialBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = w.post(body); err == nil || errors.Is(err, errPermanent) || attempt >= w.config.MaxRetries {
+			return err
+		}
+
+		w.config.Metrics.WebhookDeliveriesTotal.WithLabelValues("retry").Inc()
+		select {
+		case <-time.After(backoff):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+		backoff = min(backoff*2, w.config.MaxBackoff)
+	}
+}
+
+// post makes a single signed delivery attempt
+func (w *WebhookReporter) post(body []byte) error {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, Sign(w.config.Secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", errPermanent, resp.Status)
+	default:
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// Sign returns the signature header value for a delivery of body at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// queuedReport is an encoded report waiting for delivery
+type queuedReport struct {
+	seq  uint64
+	body []byte
+}
+
+// webhookQueue is a bounded FIFO of encoded reports, mirrored to one file per
+// report in dir when dir is set
+type webhookQueue struct {
+	mu    sync.Mutex
+	dir   string
+	max   int
+	next  uint64
+	items []*queuedReport
+}
+
+// openWebhookQueue loads the reports left in dir, keeping the newest max of
+// them. It returns how many older reports were dropped.
+func openWebhookQueue(dir string, max int) (*webhookQueue, int, error) {
+	q := &webhookQueue{dir: dir, max: max}
+	if dir == "" {
+		return q, 0, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, fmt.Errorf("failed to create webhook queue directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook queue directory: %w", err)
+	}
+	for _, entry := range entries {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), queueFileSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), queueFileSuffix) {
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read queued webhook report: %w", err)
+		}
+		q.items = append(q.items, &queuedReport{seq: seq, body: body})
+	}
+	sort.Slice(q.items, func(i, j int) bool { return q.items[i].seq < q.items[j].seq })
+	if n := len(q.items); n > 0 {
+		q.next = q.items[n-1].seq + 1
+	}
+
+	dropped := 0
+	for q.max > 0 && len(q.items) > q.max {
+		q.removeLocked(q.items[0])
+		dropped++
+	}
+	return q, dropped, nil
+}
+
+// push appends body, dropping the oldest reports beyond the cap. It returns
+// how many reports were dropped.
+func (q *webhookQueue) push(body []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item := &queuedReport{seq: q.next, body: body}
+	q.next++
+
+	if q.dir != "" {
+		if err := writeFileAtomic(q.path(item.seq), body); err != nil {
+			return 0, fmt.Errorf("failed to queue webhook report: %w", err)
+		}
+	}
+	q.items = append(q.items, item)
+
+	dropped := 0
+	// The head may be in flight; it is dropped like any other and its removal becomes a no-op
+	for q.max > 0 && len(q.items) > q.max {
+		q.removeLocked(q.items[0])
+		dropped++
+	}
+	return dropped, nil
+}
+
+func (q *webhookQueue) peek() (*queuedReport, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return q.items[0], true
+}
+
+func (q *webhookQueue) remove(item *queuedReport) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.removeLocked(item)
+}
+
+func (q *webhookQueue) removeLocked(item *queuedReport) {
+	for i, queued := range q.items {
+		if queued == item {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			if q.dir != "" {
+				_ = os.Remove(q.path(item.seq))
+			}
+			return
+		}
+	}
+}
+
+func (q *webhookQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+func (q *webhookQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, queueFileSuffix))
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,293 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// testWebhookConfig returns fast retry settings for url
+func testWebhookConfig(url string) WebhookConfig {
+	return WebhookConfig{
+		URL:            url,
+		Secret:         "s3cret",
+		Timeout:        time.Second,
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+		QueueSize:      100,
+	}
+}
+
+// webhookSink records the bodies and headers of successful deliveries
+type webhookSink struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	received chan struct{}
+}
+
+func newWebhookSink() *webhookSink {
+	return &webhookSink{received: make(chan struct{}, 100)}
+}
+
+func (s *webhookSink) record(r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.bodies = append(s.bodies, body)
+	s.headers = append(s.headers, r.Header.Clone())
+	s.mu.Unlock()
+	s.received <- struct{}{}
+}
+
+func (s *webhookSink) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for delivery %d", i+1)
+		}
+	}
+}
+
+func TestWebhookReporter_DeliversSignedReport(t *testing.T) {
+	sink := newWebhookSink()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sink.record(r)
+	}))
+	defer server.Close()
+
+	testLogger, _ := logger.New(false)
+	reporter, err := NewWebhookReporter(testWebhookConfig(server.URL), testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+	defer reporter.Close()
+
+	if err := reporter.Report(sampleReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink.wait(t, 1)
+
+	body, header := sink.bodies[0], sink.headers[0]
+	if header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type %q", header.Get("Content-Type"))
+	}
+	if want := Sign("s3cret", header.Get(WebhookTimestampHeader), body); header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("Signature %q does not match %q", header.Get(WebhookSignatureHeader), want)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if decoded.WindowNumber != 7 || decoded.Delta.TotalLogs != 4 {
+		t.Errorf("Unexpected delivered report %+v", decoded)
+	}
+}
+
+func TestWebhookReporter_RetriesServerErrors(t *testing.T) {
+	sink := newWebhookSink()
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		sink.record(r)
+	}))
+	defer server.Close()
+
+	testLogger, _ := logger.New(false)
+	reporter, _ := NewWebhookReporter(testWebhookConfig(server.URL), testLogger)
+	defer reporter.Close()
+
+	_ = reporter.Report(sampleReport())
+	sink.wait(t, 1)
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestWebhookReporter_DropsRejectedReports(t *testing.T) {
+	var attempts atomic.Int32
+	sink := newWebhookSink()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sink.record(r)
+	}))
+	defer server.Close()
+
+	testLogger, _ := logger.New(false)
+	reporter, _ := NewWebhookReporter(testWebhookConfig(server.URL), testLogger)
+	defer reporter.Close()
+
+	first, second := sampleReport(), sampleReport()
+	second.WindowNumber = 8
+	_ = reporter.Report(first)
+	_ = reporter.Report(second)
+	sink.wait(t, 1)
+
+	var decoded Report
+	_ = json.Unmarshal(sink.bodies[0], &decoded)
+	if decoded.WindowNumber != 8 || attempts.Load() != 2 {
+		t.Errorf("Expected the rejected report to be dropped without retries, got window %d after %d attempts", decoded.WindowNumber, attempts.Load())
+	}
+}
+
+func TestWebhookReporter_DiskQueueSurvivesOutage(t *testing.T) {
+	dir := t.TempDir()
+	testLogger, _ := logger.New(false)
+
+	// Both reporters must target the same URL to share a queue, so the endpoint recovers in place
+	var healthy atomic.Bool
+	sink := newWebhookSink()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		sink.record(r)
+	}))
+	defer server.Close()
+
+	config := testWebhookConfig(server.URL)
+	config.QueueDir = dir
+
+	reporter, _ := NewWebhookReporter(config, testLogger)
+	for i := 0; i < 3; i++ {
+		r := sampleReport()
+		r.WindowNumber = int64(i + 1)
+		_ = reporter.Report(r)
+	}
+	reporter.Close()
+
+	if got := reporter.Pending(); got != 3 {
+		t.Fatalf("Expected 3 reports to remain queued, got %d", got)
+	}
+
+	healthy.Store(true)
+	restarted, err := NewWebhookReporter(config, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to reopen reporter: %v", err)
+	}
+	defer restarted.Close()
+	sink.wait(t, 3)
+
+	for i, body := range sink.bodies {
+		var decoded Report
+		_ = json.Unmarshal(body, &decoded)
+		if decoded.WindowNumber != int64(i+1) {
+			t.Errorf("Delivery %d carried window %d, expected reports in order", i, decoded.WindowNumber)
+		}
+	}
+}
+
+func TestWebhookQueue_DropsOldest(t *testing.T) {
+	q, _, err := openWebhookQueue(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+
+	for _, body := range []string{"1", "2", "3"} {
+		dropped, err := q.push([]byte(body))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body == "3" && dropped != 1 {
+			t.Errorf("Expected 1 report to be dropped, got %d", dropped)
+		}
+	}
+
+	reopened, _, _ := openWebhookQueue(q.dir, 2)
+	head, _ := reopened.peek()
+	if reopened.len() != 2 || string(head.body) != "2" {
+		t.Errorf("Expected reports 2 and 3 to remain on disk, got %d starting with %q", reopened.len(), head.body)
+	}
+}
+
+func TestWebhookQueue_TrimsOnOpen(t *testing.T) {
+	q, _, err := openWebhookQueue(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+	for _, body := range []string{"1", "2", "3", "4", "5"} {
+		if _, err := q.push([]byte(body)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	reopened, dropped, err := openWebhookQueue(q.dir, 2)
+	if err != nil {
+		t.Fatalf("Failed to reopen queue: %v", err)
+	}
+	head, _ := reopened.peek()
+	if dropped != 3 || reopened.len() != 2 || string(head.body) != "4" {
+		t.Errorf("Expected the 3 oldest reports dropped, got %d dropped and %d left starting with %q", dropped, reopened.len(), head.body)
+	}
+	if entries, _ := os.ReadDir(q.dir); len(entries) != 2 {
+		t.Errorf("Expected the dropped reports removed from disk, got %d files", len(entries))
+	}
+}
+
+func TestWebhookReporter_CloseDeliversQueuedReports(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	sink := newWebhookSink()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sink.record(r)
+	}))
+	defer server.Close()
+
+	reporter, err := NewWebhookReporter(testWebhookConfig(server.URL), testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := reporter.Report(sampleReport()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	reporter.Close()
+
+	if got := len(sink.received); got != 3 || reporter.Pending() != 0 {
+		t.Errorf("Expected the queued reports delivered before close returned, got %d delivered and %d pending", got, reporter.Pending())
+	}
+	if err := reporter.Report(sampleReport()); err == nil {
+		t.Error("Expected a closed reporter to refuse reports")
+	}
+}
+
+func TestWebhookReporter_CloseIsBounded(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	config := testWebhookConfig(server.URL)
+	config.DrainTimeout = 50 * time.Millisecond
+	reporter, err := NewWebhookReporter(config, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create reporter: %v", err)
+	}
+	_ = reporter.Report(sampleReport())
+
+	start := time.Now()
+	reporter.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected close to give up after the drain timeout, took %s", elapsed)
+	}
+}
@@ -4,56 +4,272 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/health"
+	grpchealth "google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"otlp-log-parser-assignment/config"
+	"otlp-log-parser-assignment/internal/admin"
+	"otlp-log-parser-assignment/internal/api"
 	"otlp-log-parser-assignment/internal/attributes"
 	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/dashboard"
+	"otlp-log-parser-assignment/internal/filesink"
+	"otlp-log-parser-assignment/internal/forward"
+	"otlp-log-parser-assignment/internal/health"
 	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/report"
 	"otlp-log-parser-assignment/internal/service"
+	"otlp-log-parser-assignment/internal/tracing"
+	"otlp-log-parser-assignment/internal/watch"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
 )
 
+// openReporters opens the configured window reporters. Debug mode adds an
+// ASCII table on stdout unless one is configured already.
+func openReporters(cfg *config.Config, logger *logger.Logger, m *metrics.Metrics) ([]report.Reporter, error) {
+	opts := report.Options{
+		Logger:  logger,
+		Metrics: m,
+		Webhook: report.WebhookConfig{
+			Secret:         cfg.WebhookSecret,
+			Timeout:        cfg.WebhookTimeout,
+			MaxRetries:     cfg.WebhookMaxRetries,
+			InitialBackoff: cfg.WebhookBackoff,
+			MaxBackoff:     cfg.WebhookMaxBackoff,
+			QueueDir:       cfg.WebhookQueueDir,
+			QueueSize:      cfg.WebhookQueueSize,
+		},
+		OTLP: report.OTLPConfig{
+			Insecure:     cfg.OTLPMetricsInsecure,
+			Timeout:      cfg.OTLPMetricsTimeout,
+			AttributeKey: cfg.AttributeKey,
+			ServiceName:  cfg.OTLPMetricsServiceName,
+		},
+		Line: cfg.LineReporterConfig(),
+	}
+
+	var reporters []report.Reporter
+	if len(cfg.Reporters) == 0 {
+		// Keep the counter's default when no reporter is configured
+		reporters = append(reporters, report.NewLogReporter(logger))
+	}
+	table := false
+	for _, spec := range cfg.Reporters {
+		reporter, err := report.Open(spec.Kind, spec.Target, opts)
+		if err != nil {
+			for _, opened := range reporters {
+				_ = opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open %s reporter: %w", spec.Kind, err)
+		}
+		reporters = append(reporters, reporter)
+		table = table || spec.Kind == report.KindTable
+	}
+	if cfg.Debug && !table {
+		reporters = append(reporters, report.NewTableReporter(os.Stdout))
+	}
+	return reporters, nil
+}
+
+// routeConfig returns the forwarder's routing settings
+func routeConfig(cfg *config.Config) forward.RouteConfig {
+	routes := make([]forward.Route, len(cfg.Routes))
+	for i, route := range cfg.Routes {
+		routes[i] = forward.Route{Value: route.Value, Endpoint: route.Endpoint}
+	}
+	routeKey := cfg.RouteKey
+	if routeKey == "" {
+		routeKey = cfg.AttributeKey
+	}
+
+	return forward.RouteConfig{
+		Key:       routeKey,
+		Routes:    routes,
+		Default:   cfg.RouteDefault,
+		Unmatched: cfg.RouteUnmatched,
+	}
+}
+
 // Server represents the gRPC server
 type Server struct {
+	// configMu guards config and serializes reconfigurations
+	configMu      sync.Mutex
 	config        *config.Config
 	grpcServer    *grpc.Server
 	logsService   *service.LogsService
 	windowCounter *counter.WindowCounter
+	forwarder     *forward.Forwarder
+	fileSink      *filesink.Sink
+	registry      *prometheus.Registry
+	tracing       *tracing.Provider
+	admin         *admin.Server
+	watchHub      *watch.Hub
+	dashboard     *dashboard.Dashboard
+	health        *health.Monitor
 	listener      net.Listener
 	logger        *logger.Logger
 }
 
-func NewServer(cfg *config.Config, logger *logger.Logger) (*Server, error) {
+func NewServer(cfg *config.Config, logger *logger.Logger) (_ *Server, err error) {
+	// Release whatever was already opened when a later step fails
+	var cleanup []func()
+	defer func() {
+		if err != nil {
+			for i := len(cleanup) - 1; i >= 0; i-- {
+				cleanup[i]()
+			}
+		}
+	}()
+
 	// Create attribute extractor
 	extractor := attributes.NewExtractor(cfg.AttributeKey)
 
+	// Register the server's metrics on its own registry and bound the
+	// attribute value labels exported to Prometheus
+	registry := metrics.NewRegistry()
+	m := metrics.New(registry)
+	m.SetAttributeLabelLimit(cfg.MaxMetricLabels)
+	m.SetSeriesIdleWindows(cfg.MetricSeriesIdleWindows)
+
+	// Trace requests, continuing traces started by clients
+	tracer, err := tracing.New(context.Background(), cfg.Tracing())
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { _ = tracer.Shutdown(context.Background()) })
+
 	// Create window counter
-	windowCounter := counter.NewWindowCounter(cfg.WindowDuration, logger, cfg.Debug)
+	counterOpts := []counter.Option{
+		counter.WithAttributeKey(cfg.AttributeKey),
+		counter.WithMaxValues(cfg.MaxValuesPerWindow),
+		counter.WithMetrics(m),
+	}
+	serviceOpts := []service.Option{service.WithMetrics(m), service.WithTracerProvider(tracer)}
+	if cfg.ReportMode != "" {
+		counterOpts = append(counterOpts, counter.WithReportMode(counter.ReportMode(cfg.ReportMode)))
+	}
+	if cfg.DistinctAttributeKey != "" {
+		counterOpts = append(counterOpts, counter.WithDistinct(cfg.DistinctAttributeKey, uint8(cfg.DistinctPrecision)))
+		serviceOpts = append(serviceOpts, service.WithDistinctExtractor(attributes.NewExtractor(cfg.DistinctAttributeKey)))
+	}
+	if cfg.HistorySize > 0 {
+		rollups := make([]counter.Rollup, len(cfg.HistoryRollups))
+		for i, rollup := range cfg.HistoryRollups {
+			rollups[i] = counter.Rollup{Resolution: rollup.Resolution, Retention: rollup.Retention}
+		}
+		history := counter.NewHistory(cfg.WindowDuration, cfg.HistorySize, rollups, cfg.MaxValuesPerWindow)
+		counterOpts = append(counterOpts, counter.WithHistory(history))
+	}
+	if cfg.CheckpointDir != "" {
+		counterOpts = append(counterOpts, counter.WithCheckpoint(cfg.CheckpointDir, cfg.CheckpointInterval))
+	}
+	if cfg.AnomalyThreshold > 0 {
+		counterOpts = append(counterOpts, counter.WithAnomalyDetection(counter.AnomalyConfig{
+			Threshold:     cfg.AnomalyThreshold,
+			Alpha:         cfg.AnomalyAlpha,
+			WarmupWindows: cfg.AnomalyWarmup,
+			Seasonality:   cfg.AnomalySeasonality,
+		}))
+	}
+
+	// Stream every window to WatchWindows subscribers besides the configured reporters
+	watchHub := watch.NewHub(watch.Config{
+		AttributeKey: cfg.AttributeKey,
+		BufferSize:   cfg.WatchBufferSize,
+		Metrics:      m,
+	}, logger.With("component", "watch"))
+	reporters, err := openReporters(cfg, logger.With("component", "counter"), m)
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func() {
+		for _, reporter := range reporters {
+			_ = reporter.Close()
+		}
+	})
+	counterOpts = append(counterOpts, counter.WithReporters(append(reporters, watchHub)...))
+	var forwarder *forward.Forwarder
+	if cfg.Forwarding() {
+		forwarder, err = forward.New(forward.Config{
+			Endpoints:      cfg.ForwardEndpoints,
+			Insecure:       cfg.ForwardInsecure,
+			Mode:           cfg.ForwardMode,
+			Timeout:        cfg.ForwardTimeout,
+			QueueSize:      cfg.ForwardQueueSize,
+			BatchSize:      cfg.ForwardBatchSize,
+			BatchTimeout:   cfg.ForwardBatchTimeout,
+			MaxRetries:     cfg.ForwardMaxRetries,
+			InitialBackoff: cfg.ForwardBackoff,
+			MaxBackoff:     cfg.ForwardMaxBackoff,
+			Routing:        routeConfig(cfg),
+			Metrics:        m,
+		}, logger.With("component", "forwarder"))
+		if err != nil {
+			return nil, err
+		}
+		cleanup = append(cleanup, func() { _ = forwarder.Close() })
+		serviceOpts = append(serviceOpts, service.WithForwarder(forwarder))
+	}
+	var fileSink *filesink.Sink
+	if cfg.FileSinkDir != "" {
+		fileSink, err = filesink.New(filesink.Config{
+			Dir:            cfg.FileSinkDir,
+			MaxFileSize:    int64(cfg.FileSinkMaxFileMB) << 20,
+			RotateInterval: cfg.FileSinkRotateInterval,
+			MaxTotalSize:   int64(cfg.FileSinkMaxTotalMB) << 20,
+			QueueSize:      1000,
+			Metrics:        m,
+		}, logger.With("component", "file_sink"))
+		if err != nil {
+			return nil, err
+		}
+		cleanup = append(cleanup, func() { _ = fileSink.Close() })
+		serviceOpts = append(serviceOpts, service.WithFileSink(fileSink))
+	}
+	windowCounter := counter.NewWindowCounter(cfg.WindowDuration, logger, cfg.Debug, counterOpts...)
 
 	// Create logs service
-	logsService := service.NewLogsService(extractor, windowCounter, logger)
+	logsService := service.NewLogsService(extractor, windowCounter, logger, serviceOpts...)
 
 	// Create gRPC server with options for high throughput
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(16*1024*1024), // 16MB max message size
 		grpc.MaxConcurrentStreams(1000),   // Support many concurrent streams
+		grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithTracerProvider(tracer),
+			otelgrpc.WithPropagators(tracing.Propagator),
+		)),
 	)
 
 	// Register services
 	collectorpb.RegisterLogsServiceServer(grpcServer, logsService)
+	countsv1.RegisterCountsServiceServer(grpcServer, watch.NewService(watchHub, logger.With("component", "watch")))
 
-	// Register health check service
-	healthServer := health.NewServer()
+	// Register health check service; it reports NOT_SERVING until Start and
+	// whenever a queue the ingest path feeds is full
+	healthServer := grpchealth.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	monitor := health.New(healthServer, logger.With("component", "health"))
+	if forwarder != nil {
+		monitor.AddCheck("forwarder", forwarder.Saturated)
+	}
+	if fileSink != nil {
+		monitor.AddCheck("file_sink", fileSink.Saturated)
+	}
 
 	// Register reflection for debugging
 	reflection.Register(grpcServer)
@@ -63,15 +279,55 @@ func NewServer(cfg *config.Config, logger *logger.Logger) (*Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create listener: %w", err)
 	}
+	cleanup = append(cleanup, func() { _ = listener.Close() })
 
-	return &Server{
+	s := &Server{
 		config:        cfg,
 		grpcServer:    grpcServer,
 		logsService:   logsService,
 		windowCounter: windowCounter,
+		forwarder:     forwarder,
+		fileSink:      fileSink,
+		registry:      registry,
+		tracing:       tracer,
+		health:        monitor,
+		watchHub:      watchHub,
 		listener:      listener,
 		logger:        logger,
-	}, nil
+	}
+
+	handlers := admin.Handlers{
+		Metrics: s.MetricsHandler(),
+		Ready:   monitor.Ready,
+		API:     api.NewHandler(windowCounter),
+		Config:  api.NewConfigHandler(s),
+	}
+	if cfg.DashboardWindows > 0 {
+		s.dashboard = dashboard.New(dashboard.Config{
+			Windows: cfg.DashboardWindows,
+		}, windowCounter, watchHub, logger.With("component", "dashboard"))
+		handlers.Dashboard = s.dashboard
+	}
+
+	s.admin, err = admin.New(admin.Config{
+		Address:      fmt.Sprintf(":%d", cfg.MetricsPort),
+		ReadTimeout:  cfg.AdminReadTimeout,
+		WriteTimeout: cfg.AdminWriteTimeout,
+		Username:     cfg.AdminUsername,
+		Password:     cfg.AdminPassword,
+		TLSCertFile:  cfg.AdminTLSCert,
+		TLSKeyFile:   cfg.AdminTLSKey,
+	}, handlers, logger.With("component", "admin"))
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// MetricsHandler serves the server's metrics in the Prometheus exposition format
+func (s *Server) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{Registry: s.registry})
 }
 
 // Start starts the server
@@ -80,14 +336,23 @@ func (s *Server) Start() error {
 		"port", s.config.GRPCPort,
 		"attribute_key", s.config.AttributeKey,
 		"window_duration", s.config.WindowDuration,
+		"distinct_key", s.config.DistinctAttributeKey,
+		"report_mode", s.config.ReportMode,
+		"anomaly_threshold", s.config.AnomalyThreshold,
+		"checkpoint_dir", s.config.CheckpointDir,
+		"reporters", s.config.Reporters.String(),
+		"forward_endpoints", s.config.ForwardEndpoints.String(),
+		"forward_mode", s.config.ForwardMode,
+		"routes", s.config.Routes.String(),
+		"file_sink_dir", s.config.FileSinkDir,
 		"debug", s.config.Debug,
 	)
 
 	// Start window counter
 	s.windowCounter.Start()
 
-	// Start gRPC server in a goroutine
-	errCh := make(chan error, 1)
+	// Start the gRPC and admin servers in goroutines
+	errCh := make(chan error, 2)
 	go func() {
 		s.logger.Infow("gRPC server listening", "address", s.listener.Addr().String())
 		if err := s.grpcServer.Serve(s.listener); err != nil {
@@ -95,24 +360,158 @@ func (s *Server) Start() error {
 			errCh <- fmt.Errorf("gRPC server error: %w", err)
 		}
 	}()
+	go func() {
+		if err := s.admin.Serve(); err != nil {
+			s.logger.Errorw("Admin server failed", "error", err)
+			errCh <- fmt.Errorf("admin server error: %w", err)
+		}
+	}()
+	s.health.Start(time.Second)
+	if s.dashboard != nil {
+		s.dashboard.Start()
+	}
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal, reloading the configuration on SIGHUP
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
 
-	select {
-	case err := <-errCh:
-		return err
-	case sig := <-sigCh:
-		s.logger.Infow("Received signal, initiating graceful shutdown", "signal", sig.String())
-		return s.Shutdown()
+	for {
+		select {
+		case err := <-errCh:
+			// The server cannot serve without either listener; stop the rest
+			// right away, as there is nothing left for load balancers to drain
+			s.health.Drain()
+			s.stop()
+			return err
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				s.reload()
+				continue
+			}
+			s.logger.Infow("Received signal, initiating graceful shutdown", "signal", sig.String())
+			return s.Shutdown()
+		}
+	}
+}
+
+// reload loads the configuration again from the command line and config file
+// and applies it; failures are logged and leave the running configuration
+func (s *Server) reload() {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	next, err := s.config.Reload()
+	if err != nil {
+		s.logger.Errorw("Failed to reload configuration", "file", s.config.File, "error", err)
+		return
+	}
+	if _, err := s.reconfigure(next); err != nil {
+		s.logger.Errorw("Failed to apply reloaded configuration", "file", s.config.File, "error", err)
+	}
+}
+
+// ReloadableSettings returns the current values of config.ReloadableSettings
+func (s *Server) ReloadableSettings() map[string]string {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	all := s.config.Settings()
+	settings := make(map[string]string, len(config.ReloadableSettings))
+	for _, name := range config.ReloadableSettings {
+		settings[name] = all[name]
 	}
+	return settings
+}
+
+// ApplySettings applies settings keyed by flag name over the running
+// configuration and returns the names of the settings that changed
+func (s *Server) ApplySettings(settings map[string]string) ([]string, error) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	next, err := s.config.Patch(settings)
+	if err != nil {
+		return nil, err
+	}
+	return s.reconfigure(next)
+}
+
+// reconfigure applies next, which may only differ from the running
+// configuration in config.ReloadableSettings. Requests counted from then on
+// use the new extractor, routing and windows together. Callers must hold
+// s.configMu.
+func (s *Server) reconfigure(next *config.Config) ([]string, error) {
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", config.ErrInvalidSettings, err)
+	}
+	changed, err := s.config.ReloadChanges(next)
+	if err != nil || len(changed) == 0 {
+		return nil, err
+	}
+
+	current := s.config
+	routing := routeConfig(next)
+	reroute := !reflect.DeepEqual(routeConfig(current), routing)
+	if reroute && s.forwarder == nil {
+		if routing.Enabled() {
+			return nil, fmt.Errorf("%w: routing needs forwarding, which was disabled at startup", config.ErrRestartRequired)
+		}
+		reroute = false
+	}
+	regroup := next.AttributeKey != current.AttributeKey || next.WindowDuration != current.WindowDuration
+
+	err = s.logsService.Reconfigure(attributes.NewExtractor(next.AttributeKey), func() error {
+		if reroute {
+			if err := s.forwarder.SetRouting(routing); err != nil {
+				return fmt.Errorf("failed to update routing: %w", err)
+			}
+		}
+		if regroup {
+			s.windowCounter.Reconfigure(next.AttributeKey, next.WindowDuration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.config = next
+	s.logger.Infow("Configuration applied",
+		"changed", changed,
+		"attribute_key", next.AttributeKey,
+		"window_duration", next.WindowDuration,
+		"routes", next.Routes.String(),
+	)
+	return changed, nil
 }
 
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown() error {
 	s.logger.Infow("Shutting down server")
 
+	// Fail health checks first so load balancers stop sending traffic before
+	// the listener closes
+	s.health.Drain()
+	s.configMu.Lock()
+	drain := s.config.ShutdownDrain
+	s.configMu.Unlock()
+	if drain > 0 {
+		s.logger.Infow("Waiting for load balancers to drain", "drain", drain)
+		time.Sleep(drain)
+	}
+
+	s.stop()
+
+	s.logger.Infow("Server shutdown complete")
+	return nil
+}
+
+// stop stops serving and closes every component, flushing what they have queued
+func (s *Server) stop() {
+	// End WatchWindows streams, which would otherwise hold GracefulStop open
+	s.watchHub.Close()
+
 	// Stop accepting new requests
 	stopped := make(chan struct{})
 	go func() {
@@ -132,9 +531,31 @@ func (s *Server) Shutdown() error {
 		s.grpcServer.Stop()
 	}
 
+	// End dashboard event streams, which would otherwise hold the admin server open
+	if s.dashboard != nil {
+		s.dashboard.Close()
+	}
+
+	// Keep metrics and probes available until gRPC has drained
+	if err := s.admin.Shutdown(ctx); err != nil {
+		s.logger.Warnw("Admin server did not shut down cleanly", "error", err)
+	}
+
+	// Send the batches still queued for downstream endpoints
+	if s.forwarder != nil {
+		s.forwarder.Close()
+	}
+
+	// Write the records still queued for the file sink
+	if s.fileSink != nil {
+		s.fileSink.Close()
+	}
+
 	// Stop window counter
 	s.windowCounter.Stop()
 
-	s.logger.Infow("Server shutdown complete")
-	return nil
+	// Flush the spans of the last requests
+	if err := s.tracing.Shutdown(ctx); err != nil {
+		s.logger.Warnw("Failed to flush traces", "error", err)
+	}
 }
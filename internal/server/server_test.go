@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"otlp-log-parser-assignment/config"
+	"otlp-log-parser-assignment/internal/logger"
+)
+
+// freePort returns a TCP port that was free when it was checked
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestNewServer_FailureReleasesOpenedComponents(t *testing.T) {
+	testLogger, _ := logger.New(false)
+
+	// Occupy the admin port so the last step of NewServer fails
+	occupied, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to occupy the admin port: %v", err)
+	}
+	defer occupied.Close()
+
+	grpcPort := freePort(t)
+	cfg, err := config.Load([]string{
+		"-port", fmt.Sprint(grpcPort),
+		"-metrics-port", fmt.Sprint(occupied.Addr().(*net.TCPAddr).Port),
+		"-file-sink-dir", t.TempDir(),
+		"-forward-endpoints", "127.0.0.1:1",
+		"-forward-insecure",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	if _, err := NewServer(cfg, testLogger); err == nil {
+		t.Fatal("Expected NewServer to fail when the admin port is in use")
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+	if err != nil {
+		t.Fatalf("Expected the gRPC port to be released, got %v", err)
+	}
+	listener.Close()
+
+	// The forwarder and file sink goroutines exit once they are closed
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d goroutines after the failure, got %d", goroutines, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStart_FailureStopsComponents(t *testing.T) {
+	testLogger, _ := logger.New(false)
+
+	// Start the runtime's signal watcher, which outlives Start, before counting goroutines
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	signal.Stop(signals)
+
+	grpcPort, adminPort := freePort(t), freePort(t)
+	cfg, err := config.Load([]string{
+		"-port", fmt.Sprint(grpcPort),
+		"-metrics-port", fmt.Sprint(adminPort),
+		"-file-sink-dir", t.TempDir(),
+		"-forward-endpoints", "127.0.0.1:1",
+		"-forward-insecure",
+		"-dashboard-windows", "10",
+	})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	s, err := NewServer(cfg, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	// A closed listener makes the gRPC server fail as soon as it starts
+	s.listener.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Expected Start to return the gRPC server error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected Start to return once the gRPC server failed")
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", adminPort))
+	if err != nil {
+		t.Fatalf("Expected the admin port to be released, got %v", err)
+	}
+	listener.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d goroutines after the failure, got %d", goroutines, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
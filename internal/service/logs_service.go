@@ -2,31 +2,116 @@ package service
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"otlp-log-parser-assignment/internal/attributes"
 	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/filesink"
+	"otlp-log-parser-assignment/internal/forward"
 	"otlp-log-parser-assignment/internal/logger"
 	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/severity"
 )
 
+// tracerName identifies the spans of the service
+const tracerName = "otlp-log-parser-assignment/internal/service"
+
 type LogsService struct {
 	collectorpb.UnimplementedLogsServiceServer
-	extractor *attributes.Extractor
-	counter   *counter.WindowCounter
-	logger    *logger.Logger
+	// mu is held for reading while a request is extracted and counted, so a
+	// reconfiguration never splits a request between two groupings
+	mu                sync.RWMutex
+	extractor         *attributes.Extractor
+	distinctExtractor *attributes.Extractor
+	counter           *counter.WindowCounter
+	forwarder         *forward.Forwarder
+	fileSink          *filesink.Sink
+	metrics           *metrics.Metrics
+	tracer            trace.Tracer
+	logger            *logger.Logger
+}
+
+// Option configures optional LogsService behaviour
+type Option func(*LogsService)
+
+// WithDistinctExtractor resolves a second attribute per log record whose distinct
+// values are estimated by the counter
+func WithDistinctExtractor(extractor *attributes.Extractor) Option {
+	return func(s *LogsService) {
+		s.distinctExtractor = extractor
+	}
+}
+
+// WithForwarder forwards every request downstream after it is counted
+func WithForwarder(forwarder *forward.Forwarder) Option {
+	return func(s *LogsService) {
+		s.forwarder = forwarder
+	}
+}
+
+// WithMetrics records request metrics in m instead of an unregistered instance
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *LogsService) {
+		s.metrics = m
+	}
+}
+
+// WithTracerProvider traces every request with tracers from provider
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *LogsService) {
+		s.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithFileSink writes every record to a file per extracted value
+func WithFileSink(sink *filesink.Sink) Option {
+	return func(s *LogsService) {
+		s.fileSink = sink
+	}
 }
 
-func NewLogsService(extractor *attributes.Extractor, counter *counter.WindowCounter, logger *logger.Logger) *LogsService {
-	return &LogsService{
+func NewLogsService(extractor *attributes.Extractor, counter *counter.WindowCounter, logger *logger.Logger, opts ...Option) *LogsService {
+	s := &LogsService{
 		extractor: extractor,
 		counter:   counter,
 		logger:    logger.With("component", "service"),
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.metrics == nil {
+		s.metrics = metrics.New(nil)
+	}
+	if s.tracer == nil {
+		s.tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+
+	return s
 }
 
-func (s *LogsService) Export(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+func (s *LogsService) Export(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (resp *collectorpb.ExportLogsServiceResponse, err error) {
+	start := time.Now()
+	ctx, span := s.tracer.Start(ctx, "LogsService.Export")
+	defer func() {
+		s.metrics.ExportDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			s.metrics.ExportErrorsTotal.WithLabelValues(status.Code(err).String()).Inc()
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	if req == nil {
 		s.logger.Infow("Received nil request")
 		return &collectorpb.ExportLogsServiceResponse{}, nil
@@ -35,19 +120,80 @@ func (s *LogsService) Export(ctx context.Context, req *collectorpb.ExportLogsSer
 	// Count log records for metrics
 	logRecordCount := s.countLogRecords(req.ResourceLogs)
 
+	s.mu.RLock()
+
 	// Process logs in batch for high throughput
-	attributeValues := s.extractAttributeValues(req.ResourceLogs)
+	_, extractSpan := s.tracer.Start(ctx, "extract")
+	observations := s.extractObservations(req.ResourceLogs)
+	extractSpan.SetAttributes(attribute.Int("attribute_values", len(observations)))
+	extractSpan.End()
 
-	s.logger.Infow("Processing request", "log_records", logRecordCount, "attribute_values", len(attributeValues))
+	_, metricsSpan := s.tracer.Start(ctx, "metrics")
+	samples := make([]metrics.Sample, len(observations))
+	for i, obs := range observations {
+		samples[i] = metrics.Sample{
+			Value:    obs.Value,
+			Bytes:    obs.Bytes,
+			Severity: obs.Severity.String(),
+		}
+	}
+
+	s.logger.Infow("Processing request", "log_records", logRecordCount, "attribute_values", len(observations))
 
 	// Record metrics
-	metrics.RequestsTotal.Inc()
-	metrics.LogRecordsProcessed.Add(float64(logRecordCount))
-	for _, value := range attributeValues {
-		metrics.AttributeValuesTotal.WithLabelValues(value).Inc()
+	resources, scopes := countFanOut(req.ResourceLogs)
+	requestBytes := proto.Size(req)
+	span.SetAttributes(
+		attribute.Int("log_records", logRecordCount),
+		attribute.Int("resources", resources),
+		attribute.Int("scopes", scopes),
+		attribute.Int("request_bytes", requestBytes),
+	)
+	s.metrics.RequestsTotal.Inc()
+	s.metrics.LogRecordsProcessed.Add(float64(logRecordCount))
+	s.metrics.RequestBytes.Observe(float64(requestBytes))
+	s.metrics.RecordsPerRequest.Observe(float64(logRecordCount))
+	s.metrics.ResourcesPerRequest.Observe(float64(resources))
+	s.metrics.ScopesPerRequest.Observe(float64(scopes))
+	s.metrics.ObserveAttributeSamples(samples)
+	metricsSpan.End()
+
+	counterCtx, counterSpan := s.tracer.Start(ctx, "counter")
+	s.counter.ObserveBatchContext(counterCtx, observations)
+	counterSpan.End()
+
+	if s.fileSink != nil {
+		_, fileSpan := s.tracer.Start(ctx, "file_sink")
+		values := make([]string, len(observations))
+		for i, obs := range observations {
+			values[i] = obs.Value
+		}
+		s.fileSink.Write(req, values)
+		fileSpan.End()
+	}
+
+	// Route under the routing the request was counted with; the planned
+	// endpoints stay open through a reconfiguration until it is sent
+	var planned *forward.Planned
+	if s.forwarder != nil {
+		planned = s.forwarder.Plan(req)
 	}
 
-	s.counter.IncrementBatch(attributeValues)
+	s.mu.RUnlock()
+
+	if planned != nil {
+		// In sync mode the downstream outcome becomes the response; the request is
+		// already counted, so a client retrying an error counts it again
+		forwardCtx, forwardSpan := s.tracer.Start(ctx, "forward")
+		partial, err := planned.Send(forwardCtx)
+		forwardSpan.SetAttributes(attribute.Int64("rejected_log_records", partial.GetRejectedLogRecords()))
+		forwardSpan.End()
+		if err != nil {
+			s.logger.Warnw("Failed to forward request", "log_records", logRecordCount, "error", err)
+			return nil, err
+		}
+		return &collectorpb.ExportLogsServiceResponse{PartialSuccess: partial}, nil
+	}
 
 	// Return success response
 	// Note: OTLP supports PartialSuccess for reporting non-fatal errors
@@ -59,31 +205,56 @@ func (s *LogsService) Export(ctx context.Context, req *collectorpb.ExportLogsSer
 	}, nil
 }
 
-// extractAttributeValues extracts all attribute values from the log request
-func (s *LogsService) extractAttributeValues(resourceLogs []*logspb.ResourceLogs) []string {
-	var values []string
+// Reconfigure waits for the requests being counted, then runs apply and
+// swaps the group-by extractor before any further request is counted. apply
+// reconfigures the counter and routing so they change together with the
+// extractor; when it fails the extractor is kept. Requests already counted are
+// still being forwarded under the old routing and are not waited for.
+func (s *LogsService) Reconfigure(extractor *attributes.Extractor, apply func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if apply != nil {
+		if err := apply(); err != nil {
+			return err
+		}
+	}
+	s.extractor = extractor
+	return nil
+}
+
+// extractObservations extracts the attribute value, the distinct-of value when
+// configured, the severity bucket and the serialized size of every log record
+// in the request
+func (s *LogsService) extractObservations(resourceLogs []*logspb.ResourceLogs) []counter.Observation {
+	var observations []counter.Observation
 
 	for _, resourceLog := range resourceLogs {
 		if resourceLog == nil {
 			continue
 		}
 
-		// Extract resource-level attribute (applies to all logs in this resource)
-		resourceValue := attributes.UnknownValue
-		if resourceLog.Resource != nil {
-			resourceValue = s.extractor.ExtractValue(resourceLog.Resource.Attributes)
-		}
+		resourceFirst := len(observations)
+		resourceBytes := embeddedSize(resourceLogsField, resourceLog)
+		scopesBytes := int64(0)
+
+		// Extract resource-level attributes (apply to all logs in this resource)
+		resourceValue := s.extractor.ResourceValue(resourceLog.Resource)
+		resourceDistinct := s.distinctExtractor.ResourceValue(resourceLog.Resource)
 
 		for _, scopeLog := range resourceLog.ScopeLogs {
 			if scopeLog == nil {
 				continue
 			}
 
-			// Extract scope-level attribute (applies to all logs in this scope)
-			scopeValue := attributes.UnknownValue
-			if scopeLog.Scope != nil {
-				scopeValue = s.extractor.ExtractValue(scopeLog.Scope.Attributes)
-			}
+			scopeFirst := len(observations)
+			scopeBytes := embeddedSize(scopeLogsField, scopeLog)
+			recordsBytes := int64(0)
+			scopesBytes += scopeBytes
+
+			// Extract scope-level attributes (apply to all logs in this scope)
+			scopeValue := s.extractor.ScopeValue(scopeLog.Scope)
+			scopeDistinct := s.distinctExtractor.ScopeValue(scopeLog.Scope)
 
 			for _, logRecord := range scopeLog.LogRecords {
 				if logRecord == nil {
@@ -91,23 +262,31 @@ func (s *LogsService) extractAttributeValues(resourceLogs []*logspb.ResourceLogs
 				}
 
 				// Priority: Log-level > Scope-level > Resource-level
-				logValue := s.extractor.ExtractValue(logRecord.Attributes)
-
-				var finalValue string
-				if logValue != attributes.UnknownValue {
-					finalValue = logValue
-				} else if scopeValue != attributes.UnknownValue {
-					finalValue = scopeValue
-				} else {
-					finalValue = resourceValue
+				finalValue := s.extractor.RecordValue(logRecord, scopeValue, resourceValue)
+
+				distinct := s.distinctExtractor.RecordValue(logRecord, scopeDistinct, resourceDistinct)
+				if distinct == attributes.UnknownValue {
+					distinct = ""
 				}
 
-				values = append(values, finalValue)
+				recordBytes := embeddedSize(logRecordsField, logRecord)
+				recordsBytes += recordBytes
+
+				observations = append(observations, counter.Observation{
+					Value:    finalValue,
+					Distinct: distinct,
+					Bytes:    recordBytes,
+					Severity: severity.FromRecord(logRecord),
+				})
 			}
+
+			apportionOverhead(observations[scopeFirst:], scopeBytes-recordsBytes)
 		}
+
+		apportionOverhead(observations[resourceFirst:], resourceBytes-scopesBytes)
 	}
 
-	return values
+	return observations
 }
 
 // countLogRecords counts the total number of log records in the request
@@ -126,3 +305,19 @@ func (s *LogsService) countLogRecords(resourceLogs []*logspb.ResourceLogs) int {
 	}
 	return count
 }
+
+// countFanOut counts the resources and scopes in the request
+func countFanOut(resourceLogs []*logspb.ResourceLogs) (resources, scopes int) {
+	for _, resourceLog := range resourceLogs {
+		if resourceLog == nil {
+			continue
+		}
+		resources++
+		for _, scopeLog := range resourceLog.ScopeLogs {
+			if scopeLog != nil {
+				scopes++
+			}
+		}
+	}
+	return resources, scopes
+}
@@ -2,16 +2,36 @@ package service
 
 import (
 	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"otlp-log-parser-assignment/internal/attributes"
 	"otlp-log-parser-assignment/internal/counter"
+	"otlp-log-parser-assignment/internal/filesink"
+	"otlp-log-parser-assignment/internal/forward"
 	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/tracing"
 )
 
 func TestLogsService_Export_NilRequest(t *testing.T) {
@@ -303,3 +323,450 @@ func TestLogsService_countLogRecords(t *testing.T) {
 		})
 	}
 }
+
+func TestLogsService_Export_DistinctAttribute(t *testing.T) {
+	extractor := attributes.NewExtractor("service.name")
+	testLogger, _ := logger.New(false)
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false, counter.WithDistinct("host.name", 10))
+	svc := NewLogsService(extractor, wc, testLogger, WithDistinctExtractor(attributes.NewExtractor("host.name")))
+
+	stringAttr := func(key, value string) *commonpb.KeyValue {
+		return &commonpb.KeyValue{
+			Key:   key,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+		}
+	}
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						stringAttr("service.name", "checkout"),
+						stringAttr("host.name", "host-a"),
+					},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						LogRecords: []*logspb.LogRecord{
+							{}, // Falls back to resource-level host
+							{Attributes: []*commonpb.KeyValue{stringAttr("host.name", "host-b")}},
+							{Attributes: []*commonpb.KeyValue{stringAttr("host.name", "host-b")}},
+						},
+					},
+				},
+			},
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{stringAttr("service.name", "cart")},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						LogRecords: []*logspb.LogRecord{{}}, // No host at any level
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	counts := wc.GetCurrentCounts()
+	if counts["checkout"] != 3 || counts["cart"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+
+	distinct := wc.GetCurrentDistinctCounts()
+	if distinct["checkout"] != 2 {
+		t.Errorf("Expected 2 distinct hosts for checkout, got %d", distinct["checkout"])
+	}
+	if _, ok := distinct["cart"]; ok {
+		t.Errorf("Expected no distinct sketch for cart, got %d", distinct["cart"])
+	}
+}
+
+func TestLogsService_Export_Severity(t *testing.T) {
+	extractor := attributes.NewExtractor("service.name")
+	testLogger, _ := logger.New(false)
+	h := counter.NewHistory(1*time.Second, 10, nil, 0)
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false, counter.WithHistory(h))
+	svc := NewLogsService(extractor, wc, testLogger)
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						{
+							Key:   "service.name",
+							Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}},
+						},
+					},
+				},
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						LogRecords: []*logspb.LogRecord{
+							{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG2},
+							{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR},
+							{SeverityText: "warning"},
+							{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	wc.Stop()
+
+	points, _ := h.Series("checkout", 1*time.Second, time.Time{}, time.Time{})
+	if len(points) != 1 {
+		t.Fatalf("Expected 1 point, got %d", len(points))
+	}
+
+	want := map[string]int64{"DEBUG": 1, "ERROR": 1, "WARN": 1, "UNSPECIFIED": 1}
+	for bucket, count := range want {
+		if points[0].Severity[bucket] != count {
+			t.Errorf("Expected %d %s records, got %d", count, bucket, points[0].Severity[bucket])
+		}
+	}
+}
+
+// rejectingCollector is a downstream OTLP receiver refusing every request
+type rejectingCollector struct {
+	collectorpb.UnimplementedLogsServiceServer
+}
+
+func (rejectingCollector) Export(context.Context, *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+	return nil, status.Error(codes.PermissionDenied, "tenant disabled")
+}
+
+func TestLogsService_Export_SyncForwardingError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	downstream := grpc.NewServer()
+	collectorpb.RegisterLogsServiceServer(downstream, rejectingCollector{})
+	go downstream.Serve(listener)
+	defer downstream.Stop()
+
+	testLogger, _ := logger.New(false)
+	forwarder, err := forward.New(forward.Config{
+		Endpoints:      []string{listener.Addr().String()},
+		Insecure:       true,
+		Mode:           forward.ModeSync,
+		Timeout:        5 * time.Second,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer forwarder.Close()
+
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false)
+	m := metrics.New(nil)
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger, WithForwarder(forwarder), WithMetrics(m))
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{}}}}}},
+	}
+	_, err = svc.Export(context.Background(), req)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected the downstream PermissionDenied, got %v", err)
+	}
+	if counts := wc.GetCurrentCounts(); counts[attributes.UnknownValue] != 1 {
+		t.Errorf("Expected the request to be counted before forwarding, got %v", counts)
+	}
+	if got := testutil.ToFloat64(m.ExportErrorsTotal.WithLabelValues("PermissionDenied")); got != 1 {
+		t.Errorf("Expected 1 PermissionDenied export error, got %v", got)
+	}
+}
+
+func TestLogsService_Export_RequestMetrics(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false)
+	m := metrics.New(nil)
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger, WithMetrics(m))
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{ScopeLogs: []*logspb.ScopeLogs{
+				{LogRecords: []*logspb.LogRecord{{}, {}}},
+				{LogRecords: []*logspb.LogRecord{{}}},
+			}},
+			{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{}}}}},
+		},
+	}
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		histogram prometheus.Histogram
+		wantSum   float64
+	}{
+		{name: "records", histogram: m.RecordsPerRequest, wantSum: 4},
+		{name: "resources", histogram: m.ResourcesPerRequest, wantSum: 2},
+		{name: "scopes", histogram: m.ScopesPerRequest, wantSum: 3},
+		{name: "bytes", histogram: m.RequestBytes, wantSum: float64(proto.Size(req))},
+	}
+	for _, tt := range tests {
+		var metric dto.Metric
+		if err := tt.histogram.Write(&metric); err != nil {
+			t.Fatalf("Failed to read %s histogram: %v", tt.name, err)
+		}
+		if metric.GetHistogram().GetSampleCount() != 1 || metric.GetHistogram().GetSampleSum() != tt.wantSum {
+			t.Errorf("Expected one %s observation of %v, got %v", tt.name, tt.wantSum, metric.GetHistogram())
+		}
+	}
+
+	var duration dto.Metric
+	_ = m.ExportDuration.Write(&duration)
+	if duration.GetHistogram().GetSampleCount() != 1 {
+		t.Errorf("Expected one export duration observation, got %v", duration.GetHistogram())
+	}
+}
+
+func TestLogsService_Export_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	testLogger, _ := logger.New(false)
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false)
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger, WithTracerProvider(provider))
+	server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler(
+		otelgrpc.WithTracerProvider(provider),
+		otelgrpc.WithPropagators(tracing.Propagator),
+	)))
+	collectorpb.RegisterLogsServiceServer(server, svc)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// The caller's trace context arrives in the traceparent metadata
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{}, {}}}}}},
+	}
+	if _, err := collectorpb.NewLogsServiceClient(conn).Export(ctx, req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("Expected span %s to continue trace %s, got %s", span.Name(), traceID, got)
+		}
+	}
+	for _, name := range []string{"LogsService.Export", "extract", "metrics", "counter"} {
+		if spans[name] == nil {
+			t.Errorf("Expected a %s span, got %v", name, spans)
+		}
+	}
+
+	export := spans["LogsService.Export"]
+	if export == nil {
+		return
+	}
+	for _, attr := range export.Attributes() {
+		if attr.Key == "log_records" && attr.Value.AsInt64() != 2 {
+			t.Errorf("Expected log_records=2, got %v", attr.Value.AsInt64())
+		}
+	}
+	if counterSpan := spans["counter"]; counterSpan != nil && len(counterSpan.Events()) != 1 {
+		t.Errorf("Expected the counter lock wait event, got %v", counterSpan.Events())
+	}
+}
+
+func TestLogsService_Reconfigure(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false, counter.WithAttributeKey("service.name"))
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger)
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "checkout"}}},
+				{Key: "k8s.namespace", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "prod"}}},
+			}},
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{}}}},
+		}},
+	}
+
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A failing apply keeps the extractor
+	if err := svc.Reconfigure(attributes.NewExtractor("k8s.namespace"), func() error {
+		return errors.New("routing unavailable")
+	}); err == nil {
+		t.Fatal("Expected the apply error to be returned")
+	}
+	if err := svc.Reconfigure(attributes.NewExtractor("k8s.namespace"), func() error {
+		wc.Reconfigure("k8s.namespace", time.Second)
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	counts := wc.GetCurrentCounts()
+	if counts["prod"] != 1 || len(counts) != 1 {
+		t.Errorf("Expected records counted by k8s.namespace after reconfiguring, got %v", counts)
+	}
+	if wc.TotalsSinceReset().Total() != 0 || wc.LifetimeTotals().Values["checkout"] == nil {
+		t.Errorf("Expected the first record counted by service.name in the closed window")
+	}
+}
+
+func TestLogsService_Export_FileSinkUsesCappedValues(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	dir := t.TempDir()
+	sink, err := filesink.New(filesink.Config{Dir: dir, RotateInterval: time.Hour, QueueSize: 10}, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create file sink: %v", err)
+	}
+	wc := counter.NewWindowCounter(1*time.Second, testLogger, false, counter.WithMaxValues(1))
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger, WithFileSink(sink))
+
+	var records []*logspb.LogRecord
+	for _, value := range []string{"checkout", "cart", "search"} {
+		records = append(records, &logspb.LogRecord{Attributes: []*commonpb.KeyValue{
+			{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}},
+		}})
+	}
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: records}}}},
+	}
+	if _, err := svc.Export(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sink.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to list file sink directory: %v", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		dirs = append(dirs, entry.Name())
+	}
+	sort.Strings(dirs)
+	if len(dirs) != 2 || dirs[0] != attributes.OverflowValue || dirs[1] != "checkout" {
+		t.Errorf("Expected files for the tracked value and the overflow bucket only, got %v", dirs)
+	}
+}
+
+type unavailableCollector struct {
+	collectorpb.UnimplementedLogsServiceServer
+}
+
+func (unavailableCollector) Export(context.Context, *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+	return nil, status.Error(codes.Unavailable, "overloaded")
+}
+
+func TestLogsService_ReconfigureDuringStuckForward(t *testing.T) {
+	serve := func(collector collectorpb.LogsServiceServer) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		server := grpc.NewServer()
+		collectorpb.RegisterLogsServiceServer(server, collector)
+		go server.Serve(listener)
+		t.Cleanup(server.Stop)
+		return listener.Addr().String()
+	}
+	// The healthy endpoint answers Unimplemented, which is not retried
+	stuck := serve(unavailableCollector{})
+	healthy := serve(&collectorpb.UnimplementedLogsServiceServer{})
+
+	testLogger, _ := logger.New(false)
+	forwarder, err := forward.New(forward.Config{
+		Insecure:       true,
+		Mode:           forward.ModeSync,
+		Timeout:        time.Second,
+		MaxRetries:     1000,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Routing:        forward.RouteConfig{Key: "service.name", Default: stuck, Unmatched: forward.UnmatchedDefault},
+	}, testLogger)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer forwarder.Close()
+
+	wc := counter.NewWindowCounter(time.Second, testLogger, false, counter.WithAttributeKey("service.name"))
+	svc := NewLogsService(attributes.NewExtractor("service.name"), wc, testLogger, WithForwarder(forwarder))
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{}}}}}},
+	}
+
+	// The first request keeps retrying against the unavailable endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stuckDone := make(chan struct{})
+	go func() {
+		defer close(stuckDone)
+		svc.Export(ctx, req)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	reconfigured := make(chan error, 1)
+	go func() {
+		reconfigured <- svc.Reconfigure(attributes.NewExtractor("service.name"), func() error {
+			return forwarder.SetRouting(forward.RouteConfig{Key: "service.name", Default: healthy, Unmatched: forward.UnmatchedDefault})
+		})
+	}()
+	select {
+	case err := <-reconfigured:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected reconfiguring not to wait for the stuck forward")
+	}
+
+	exported := make(chan error, 1)
+	go func() {
+		_, err := svc.Export(context.Background(), req)
+		exported <- err
+	}()
+	select {
+	case err := <-exported:
+		if status.Code(err) != codes.Unimplemented {
+			t.Errorf("Expected the new request forwarded to the healthy endpoint, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected new requests to keep flowing while a forward is stuck")
+	}
+
+	select {
+	case <-stuckDone:
+		t.Error("Expected the first request to still be retrying")
+	default:
+	}
+	cancel()
+	<-stuckDone
+}
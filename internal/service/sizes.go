@@ -0,0 +1,46 @@
+package service
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"otlp-log-parser-assignment/internal/counter"
+)
+
+// Field numbers of the repeated message fields along the OTLP logs hierarchy
+const (
+	resourceLogsField protowire.Number = 1 // ExportLogsServiceRequest.resource_logs
+	scopeLogsField    protowire.Number = 2 // ResourceLogs.scope_logs
+	logRecordsField   protowire.Number = 2 // ScopeLogs.log_records
+)
+
+// embeddedSize returns the number of bytes m occupies on the wire when embedded
+// in its parent as field num, including the tag and length prefix
+func embeddedSize(num protowire.Number, m proto.Message) int64 {
+	return int64(protowire.SizeTag(num) + protowire.SizeBytes(proto.Size(m)))
+}
+
+// apportionOverhead spreads overhead bytes across observations in proportion to
+// the bytes already attributed to each one. Cumulative rounding keeps the total
+// exact, so the sizes of all records add up to the size of the request.
+func apportionOverhead(observations []counter.Observation, overhead int64) {
+	if len(observations) == 0 || overhead <= 0 {
+		return
+	}
+
+	total := int64(0)
+	for _, obs := range observations {
+		total += obs.Bytes
+	}
+	if total == 0 {
+		return
+	}
+
+	cumulative := int64(0)
+	allocated := int64(0)
+	for i := range observations {
+		cumulative += observations[i].Bytes
+		share := overhead*cumulative/total - allocated
+		allocated += share
+		observations[i].Bytes += share
+	}
+}
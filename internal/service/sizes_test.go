@@ -0,0 +1,123 @@
+package service
+
+import (
+	"testing"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+	"otlp-log-parser-assignment/internal/attributes"
+	"otlp-log-parser-assignment/internal/counter"
+)
+
+func TestApportionOverhead(t *testing.T) {
+	tests := []struct {
+		name     string
+		bytes    []int64
+		overhead int64
+		want     []int64
+	}{
+		{
+			name:     "proportional split",
+			bytes:    []int64{10, 30},
+			overhead: 8,
+			want:     []int64{12, 36},
+		},
+		{
+			name:     "rounding keeps total exact",
+			bytes:    []int64{1, 1, 1},
+			overhead: 10,
+			want:     []int64{4, 4, 5},
+		},
+		{
+			name:     "no overhead",
+			bytes:    []int64{5, 7},
+			overhead: 0,
+			want:     []int64{5, 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observations := make([]counter.Observation, len(tt.bytes))
+			for i, b := range tt.bytes {
+				observations[i].Bytes = b
+			}
+
+			apportionOverhead(observations, tt.overhead)
+
+			for i, want := range tt.want {
+				if observations[i].Bytes != want {
+					t.Errorf("observation %d: got %d bytes, want %d", i, observations[i].Bytes, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogsService_extractObservations_Bytes(t *testing.T) {
+	svc := &LogsService{extractor: attributes.NewExtractor("service.name")}
+
+	body := func(text string) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: text}}
+	}
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						{Key: "service.name", Value: body("checkout")},
+					},
+				},
+				SchemaUrl: "https://opentelemetry.io/schemas/1.21.0",
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						Scope: &commonpb.InstrumentationScope{Name: "checkout-logger", Version: "1.0.0"},
+						LogRecords: []*logspb.LogRecord{
+							{Body: body("short")},
+							{Body: body("a considerably longer log message body")},
+						},
+					},
+					{
+						LogRecords: []*logspb.LogRecord{
+							{Body: body("another scope")},
+						},
+					},
+				},
+			},
+			{
+				ScopeLogs: []*logspb.ScopeLogs{
+					{
+						LogRecords: []*logspb.LogRecord{
+							{Body: body("no resource")},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	observations := svc.extractObservations(req.ResourceLogs)
+	if len(observations) != 4 {
+		t.Fatalf("Expected 4 observations, got %d", len(observations))
+	}
+
+	total := int64(0)
+	for _, obs := range observations {
+		if obs.Bytes <= 0 {
+			t.Errorf("Expected positive byte size, got %d for %v", obs.Bytes, obs)
+		}
+		total += obs.Bytes
+	}
+
+	if want := int64(proto.Size(req)); total != want {
+		t.Errorf("Expected record sizes to add up to the request size %d, got %d", want, total)
+	}
+
+	if observations[1].Bytes <= observations[0].Bytes {
+		t.Errorf("Expected the longer record to be attributed more bytes, got %d <= %d", observations[1].Bytes, observations[0].Bytes)
+	}
+}
@@ -0,0 +1,113 @@
+package severity
+
+import (
+	"strings"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// Bucket is a coarse severity level derived from an OTLP log record
+type Bucket uint8
+
+const (
+	Unspecified Bucket = iota
+	Trace
+	Debug
+	Info
+	Warn
+	Error
+	Fatal
+
+	// NumBuckets is the number of severity buckets, including Unspecified
+	NumBuckets = int(Fatal) + 1
+)
+
+var bucketNames = [NumBuckets]string{
+	Unspecified: "UNSPECIFIED",
+	Trace:       "TRACE",
+	Debug:       "DEBUG",
+	Info:        "INFO",
+	Warn:        "WARN",
+	Error:       "ERROR",
+	Fatal:       "FATAL",
+}
+
+// textAliases maps common severity text prefixes that do not match a bucket name
+var textAliases = []struct {
+	prefix string
+	bucket Bucket
+}{
+	{"WARNING", Warn},
+	{"NOTICE", Info},
+	{"ERR", Error},
+	{"CRIT", Fatal},
+	{"ALERT", Fatal},
+	{"EMERG", Fatal},
+	{"PANIC", Fatal},
+}
+
+func (b Bucket) String() string {
+	if int(b) < NumBuckets {
+		return bucketNames[b]
+	}
+	return bucketNames[Unspecified]
+}
+
+// FromRecord derives the bucket of a log record from its SeverityNumber,
+// falling back to SeverityText when the number is unspecified
+func FromRecord(record *logspb.LogRecord) Bucket {
+	if bucket := FromNumber(record.SeverityNumber); bucket != Unspecified {
+		return bucket
+	}
+	return FromText(record.SeverityText)
+}
+
+// FromNumber maps an OTLP SeverityNumber (1-24) onto its bucket
+func FromNumber(number logspb.SeverityNumber) Bucket {
+	if number < logspb.SeverityNumber_SEVERITY_NUMBER_TRACE || number > logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4 {
+		return Unspecified
+	}
+	// Each bucket spans four consecutive severity numbers
+	return Bucket((number-1)/4) + Trace
+}
+
+// FromText maps free-form severity text such as "warning" or "ERROR2" onto a bucket
+func FromText(text string) Bucket {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	if text == "" {
+		return Unspecified
+	}
+
+	for _, alias := range textAliases {
+		if strings.HasPrefix(text, alias.prefix) {
+			return alias.bucket
+		}
+	}
+	for bucket := Trace; int(bucket) < NumBuckets; bucket++ {
+		if strings.HasPrefix(text, bucketNames[bucket]) {
+			return bucket
+		}
+	}
+	return Unspecified
+}
+
+// Counts is a per-bucket breakdown of log record counts
+type Counts [NumBuckets]int64
+
+// Add folds other into c
+func (c *Counts) Add(other Counts) {
+	for i, n := range other {
+		c[i] += n
+	}
+}
+
+// Map returns the non-zero buckets keyed by name
+func (c Counts) Map() map[string]int64 {
+	m := make(map[string]int64)
+	for i, n := range c {
+		if n != 0 {
+			m[Bucket(i).String()] = n
+		}
+	}
+	return m
+}
@@ -0,0 +1,100 @@
+package severity
+
+import (
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestFromNumber(t *testing.T) {
+	tests := []struct {
+		number logspb.SeverityNumber
+		want   Bucket
+	}{
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, want: Unspecified},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, want: Trace},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE4, want: Trace},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, want: Debug},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_INFO3, want: Info},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_WARN2, want: Warn},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, want: Error},
+		{number: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, want: Fatal},
+		{number: logspb.SeverityNumber(99), want: Unspecified},
+	}
+
+	for _, tt := range tests {
+		if got := FromNumber(tt.number); got != tt.want {
+			t.Errorf("FromNumber(%d) = %s, want %s", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestFromText(t *testing.T) {
+	tests := []struct {
+		text string
+		want Bucket
+	}{
+		{text: "", want: Unspecified},
+		{text: "trace", want: Trace},
+		{text: "DEBUG2", want: Debug},
+		{text: " Info ", want: Info},
+		{text: "notice", want: Info},
+		{text: "warning", want: Warn},
+		{text: "WARN", want: Warn},
+		{text: "err", want: Error},
+		{text: "Error", want: Error},
+		{text: "critical", want: Fatal},
+		{text: "panic", want: Fatal},
+		{text: "fatal", want: Fatal},
+		{text: "verbose", want: Unspecified},
+	}
+
+	for _, tt := range tests {
+		if got := FromText(tt.text); got != tt.want {
+			t.Errorf("FromText(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFromRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record *logspb.LogRecord
+		want   Bucket
+	}{
+		{
+			name:   "number takes precedence over text",
+			record: &logspb.LogRecord{SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, SeverityText: "INFO"},
+			want:   Error,
+		},
+		{
+			name:   "text fallback",
+			record: &logspb.LogRecord{SeverityText: "warning"},
+			want:   Warn,
+		},
+		{
+			name:   "neither set",
+			record: &logspb.LogRecord{},
+			want:   Unspecified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromRecord(tt.record); got != tt.want {
+				t.Errorf("FromRecord() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounts(t *testing.T) {
+	var c Counts
+	c[Info] = 2
+	c.Add(Counts{Info: 1, Error: 3})
+
+	m := c.Map()
+	if len(m) != 2 || m["INFO"] != 3 || m["ERROR"] != 3 {
+		t.Errorf("Unexpected breakdown %v", m)
+	}
+}
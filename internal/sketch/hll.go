@@ -0,0 +1,156 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// encodingVersion prefixes the binary encoding so the format can evolve
+	encodingVersion = 1
+
+	// MinPrecision and MaxPrecision bound the number of register index bits
+	MinPrecision = 4
+	MaxPrecision = 18
+
+	// DefaultPrecision uses 4096 registers (4KiB) for a standard error of about 1.6%
+	DefaultPrecision = 12
+)
+
+// HyperLogLog estimates the number of distinct strings added to it using a
+// fixed amount of memory. Sketches with the same precision can be merged.
+// HyperLogLog is not safe for concurrent use.
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+// New creates an empty sketch with 2^precision registers
+func New(precision uint8) (*HyperLogLog, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, fmt.Errorf("hyperloglog precision must be between %d and %d, got %d", MinPrecision, MaxPrecision, precision)
+	}
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// Precision returns the number of register index bits
+func (h *HyperLogLog) Precision() uint8 {
+	return h.precision
+}
+
+// Add records a value in the sketch
+func (h *HyperLogLog) Add(value string) {
+	hash := xxhash.Sum64String(value)
+	index := hash >> (64 - h.precision)
+	// Guard bit keeps the rank bounded when the remaining bits are all zero
+	rest := hash<<h.precision | 1<<(h.precision-1)
+	rank := uint8(bits.LeadingZeros64(rest)) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values added
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(len(h.registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(len(h.registers)) * m * m / sum
+
+	// Small range correction: linear counting is more accurate for sparse sketches
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// Merge folds other into h so that h estimates the union of both sketches
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if other == nil {
+		return nil
+	}
+	if other.precision != h.precision {
+		return fmt.Errorf("cannot merge hyperloglog sketches with precision %d and %d", h.precision, other.precision)
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// Reset clears all registers
+func (h *HyperLogLog) Reset() {
+	clear(h.registers)
+}
+
+// Clone returns an independent copy of the sketch
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	registers := make([]uint8, len(h.registers))
+	copy(registers, h.registers)
+	return &HyperLogLog{
+		precision: h.precision,
+		registers: registers,
+	}
+}
+
+// MarshalBinary encodes the sketch so it can be shipped to and merged by other replicas
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 2+len(h.registers))
+	data = append(data, encodingVersion, h.precision)
+	data = append(data, h.registers...)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a sketch produced by MarshalBinary
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("hyperloglog encoding too short: %d bytes", len(data))
+	}
+	if data[0] != encodingVersion {
+		return fmt.Errorf("unsupported hyperloglog encoding version %d", data[0])
+	}
+
+	precision := data[1]
+	if precision < MinPrecision || precision > MaxPrecision {
+		return fmt.Errorf("invalid hyperloglog precision %d", precision)
+	}
+	if len(data)-2 != 1<<precision {
+		return fmt.Errorf("hyperloglog encoding has %d registers, expected %d", len(data)-2, 1<<precision)
+	}
+
+	h.precision = precision
+	h.registers = make([]uint8, 1<<precision)
+	copy(h.registers, data[2:])
+	return nil
+}
+
+// alpha returns the bias correction constant for m registers
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
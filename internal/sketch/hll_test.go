@@ -0,0 +1,158 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNew_InvalidPrecision(t *testing.T) {
+	for _, p := range []uint8{0, MinPrecision - 1, MaxPrecision + 1} {
+		if _, err := New(p); err == nil {
+			t.Errorf("Expected error for precision %d", p)
+		}
+	}
+}
+
+func TestHyperLogLog_Estimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+	}{
+		{name: "empty", distinct: 0},
+		{name: "small", distinct: 10},
+		{name: "medium", distinct: 1000},
+		{name: "large", distinct: 100000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := New(DefaultPrecision)
+			if err != nil {
+				t.Fatalf("Failed to create sketch: %v", err)
+			}
+
+			// Add every value twice; duplicates must not change the estimate
+			for i := 0; i < tt.distinct; i++ {
+				h.Add(fmt.Sprintf("value-%d", i))
+				h.Add(fmt.Sprintf("value-%d", i))
+			}
+
+			got := h.Estimate()
+			if tt.distinct == 0 {
+				if got != 0 {
+					t.Errorf("Expected estimate 0 for empty sketch, got %d", got)
+				}
+				return
+			}
+
+			relErr := math.Abs(float64(got)-float64(tt.distinct)) / float64(tt.distinct)
+			if relErr > 0.05 {
+				t.Errorf("Estimate %d too far from %d (relative error %.3f)", got, tt.distinct, relErr)
+			}
+		})
+	}
+}
+
+func TestHyperLogLog_Merge(t *testing.T) {
+	a, _ := New(DefaultPrecision)
+	b, _ := New(DefaultPrecision)
+
+	for i := 0; i < 5000; i++ {
+		a.Add(fmt.Sprintf("value-%d", i))
+	}
+	for i := 2500; i < 7500; i++ {
+		b.Add(fmt.Sprintf("value-%d", i))
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Unexpected merge error: %v", err)
+	}
+
+	got := a.Estimate()
+	relErr := math.Abs(float64(got)-7500) / 7500
+	if relErr > 0.05 {
+		t.Errorf("Merged estimate %d too far from 7500 (relative error %.3f)", got, relErr)
+	}
+}
+
+func TestHyperLogLog_MergePrecisionMismatch(t *testing.T) {
+	a, _ := New(10)
+	b, _ := New(12)
+
+	if err := a.Merge(b); err == nil {
+		t.Error("Expected error when merging sketches with different precision")
+	}
+}
+
+func TestHyperLogLog_Reset(t *testing.T) {
+	h, _ := New(DefaultPrecision)
+	h.Add("a")
+	h.Add("b")
+
+	h.Reset()
+
+	if got := h.Estimate(); got != 0 {
+		t.Errorf("Expected estimate 0 after reset, got %d", got)
+	}
+}
+
+func TestHyperLogLog_MarshalRoundTrip(t *testing.T) {
+	h, _ := New(DefaultPrecision)
+	for i := 0; i < 1000; i++ {
+		h.Add(fmt.Sprintf("value-%d", i))
+	}
+
+	data, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected marshal error: %v", err)
+	}
+
+	var decoded HyperLogLog
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected unmarshal error: %v", err)
+	}
+
+	if decoded.Precision() != h.Precision() {
+		t.Errorf("Expected precision %d, got %d", h.Precision(), decoded.Precision())
+	}
+	if decoded.Estimate() != h.Estimate() {
+		t.Errorf("Expected estimate %d after round trip, got %d", h.Estimate(), decoded.Estimate())
+	}
+}
+
+func TestHyperLogLog_UnmarshalInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "unknown version", data: []byte{9, 4}},
+		{name: "invalid precision", data: []byte{encodingVersion, 30}},
+		{name: "truncated registers", data: []byte{encodingVersion, 4, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h HyperLogLog
+			if err := h.UnmarshalBinary(tt.data); err == nil {
+				t.Error("Expected error for invalid encoding")
+			}
+		})
+	}
+}
+
+func TestHyperLogLog_Clone(t *testing.T) {
+	h, _ := New(DefaultPrecision)
+	h.Add("a")
+
+	clone := h.Clone()
+	clone.Add("b")
+
+	if h.Estimate() != 1 {
+		t.Errorf("Expected original estimate to stay 1, got %d", h.Estimate())
+	}
+	if clone.Estimate() != 2 {
+		t.Errorf("Expected clone estimate 2, got %d", clone.Estimate())
+	}
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Exporter kinds accepted in Config.Exporter
+const (
+	// ExporterStdout writes spans as JSON to stdout
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans as JSON to a file
+	ExporterFile = "file"
+	// ExporterOTLP exports spans to an OTLP gRPC receiver
+	ExporterOTLP = "otlp"
+
+	// serviceName identifies this process in the exported resource
+	serviceName = "otlp-log-parser-assignment"
+)
+
+// Config configures self-tracing
+type Config struct {
+	// Exporter is "stdout", "file:<path>" or "otlp:<host:port>"; empty disables tracing
+	Exporter string
+	// Insecure disables TLS to the OTLP endpoint
+	Insecure bool
+	// SampleRatio is the fraction of new traces sampled; traces started by a
+	// sampled caller are always sampled
+	SampleRatio float64
+}
+
+// Propagator extracts and injects W3C trace context and baggage
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// parseExporter splits a kind[:target] exporter
+func parseExporter(exporter string) (kind, target string) {
+	kind, target, _ = strings.Cut(exporter, ":")
+	return kind, target
+}
+
+// Validate checks the exporter and sample ratio of config
+func Validate(config Config) error {
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1")
+	}
+	if config.Exporter == "" {
+		return nil
+	}
+
+	kind, target := parseExporter(config.Exporter)
+	switch kind {
+	case ExporterStdout:
+		if target != "" {
+			return fmt.Errorf("trace exporter %q does not take a target", kind)
+		}
+	case ExporterFile:
+		if target == "" {
+			return fmt.Errorf("trace exporter %q requires a file path", kind)
+		}
+	case ExporterOTLP:
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("trace exporter %q requires a host:port address: %w", kind, err)
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter %q (expected %s, %s or %s)", kind, ExporterStdout, ExporterFile, ExporterOTLP)
+	}
+	return nil
+}
+
+// Provider creates the tracers of the server
+type Provider struct {
+	trace.TracerProvider
+	sdk  *sdktrace.TracerProvider
+	file io.Closer
+}
+
+// New creates a provider exporting spans as configured. With no exporter
+// configured it returns a no-op provider, so instrumented code costs almost
+// nothing.
+func New(ctx context.Context, config Config) (*Provider, error) {
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+	if config.Exporter == "" {
+		return &Provider{TracerProvider: noop.NewTracerProvider()}, nil
+	}
+
+	p := &Provider{}
+	var exporter sdktrace.SpanExporter
+	var err error
+	kind, target := parseExporter(config.Exporter)
+	switch kind {
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		f, openErr := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if openErr != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", openErr)
+		}
+		p.file = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(target)}
+		if config.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		if p.file != nil {
+			p.file.Close()
+		}
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", kind, err)
+	}
+
+	p.sdk = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	p.TracerProvider = p.sdk
+	return p, nil
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.sdk == nil {
+		return nil
+	}
+	err := p.sdk.Shutdown(ctx)
+	if p.file != nil {
+		if closeErr := p.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "disabled", config: Config{SampleRatio: 1}},
+		{name: "stdout", config: Config{Exporter: "stdout", SampleRatio: 0.5}},
+		{name: "file", config: Config{Exporter: "file:/tmp/spans.json", SampleRatio: 1}},
+		{name: "otlp", config: Config{Exporter: "otlp:collector:4317", SampleRatio: 1}},
+		{name: "stdout with target", config: Config{Exporter: "stdout:x", SampleRatio: 1}, wantErr: true},
+		{name: "file without path", config: Config{Exporter: "file", SampleRatio: 1}, wantErr: true},
+		{name: "otlp without port", config: Config{Exporter: "otlp:collector", SampleRatio: 1}, wantErr: true},
+		{name: "unknown exporter", config: Config{Exporter: "jaeger", SampleRatio: 1}, wantErr: true},
+		{name: "negative ratio", config: Config{SampleRatio: -0.1}, wantErr: true},
+		{name: "ratio above 1", config: Config{SampleRatio: 1.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	p, err := New(context.Background(), Config{SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, span := p.Tracer("test").Start(context.Background(), "noop")
+	if span.SpanContext().IsValid() {
+		t.Error("Expected a no-op span when tracing is disabled")
+	}
+	span.End()
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestNew_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.json")
+	p, err := New(context.Background(), Config{Exporter: "file:" + path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, span := p.Tracer("test").Start(context.Background(), "sampled-span")
+	span.End()
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace file: %v", err)
+	}
+	if !strings.Contains(string(data), `"Name":"sampled-span"`) || !strings.Contains(string(data), serviceName) {
+		t.Errorf("Expected the span and service name in the trace file, got %s", data)
+	}
+}
+
+func TestNew_SampleRatioZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.json")
+	p, err := New(context.Background(), Config{Exporter: "file:" + path, SampleRatio: 0})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, span := p.Tracer("test").Start(context.Background(), "dropped-span")
+	if span.SpanContext().IsSampled() {
+		t.Error("Expected new traces not to be sampled at ratio 0")
+	}
+	span.End()
+	_ = p.Shutdown(context.Background())
+}
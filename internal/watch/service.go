@@ -0,0 +1,52 @@
+package watch
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"otlp-log-parser-assignment/internal/logger"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
+)
+
+// Service implements CountsService on top of a Hub
+type Service struct {
+	countsv1.UnimplementedCountsServiceServer
+
+	hub    *Hub
+	logger *logger.Logger
+}
+
+// NewService creates a CountsService streaming the windows published to hub
+func NewService(hub *Hub, logger *logger.Logger) *Service {
+	return &Service{hub: hub, logger: logger}
+}
+
+// WatchWindows streams window events until the client cancels or the hub
+// closes, which ends the stream with Unavailable so clients reconnect
+func (s *Service) WatchWindows(req *countsv1.WatchWindowsRequest, stream countsv1.CountsService_WatchWindowsServer) error {
+	sub, err := s.hub.Subscribe(Filter{AttributeKey: req.AttributeKey, ValuePrefix: req.ValuePrefix})
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer sub.Close()
+
+	s.logger.Infow("Watch subscriber connected",
+		"attribute_key", req.AttributeKey,
+		"value_prefix", req.ValuePrefix,
+	)
+	defer s.logger.Infow("Watch subscriber disconnected")
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
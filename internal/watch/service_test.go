@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
+)
+
+func TestService_WatchWindows(t *testing.T) {
+	testLogger, _ := logger.New(false)
+	m := metrics.New(nil)
+	hub := NewHub(Config{AttributeKey: "service.name", BufferSize: 4, Metrics: m}, testLogger)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	countsv1.RegisterCountsServiceServer(server, NewService(hub, testLogger))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := countsv1.NewCountsServiceClient(conn).WatchWindows(ctx, &countsv1.WatchWindowsRequest{ValuePrefix: "cart"})
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+
+	// The subscription is registered once the server handles the call
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(m.WatchSubscribers) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Subscriber not registered in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	hub.Report(testReport(7))
+
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive: %v", err)
+	}
+	window := resp.GetWindow()
+	if window.GetWindowNumber() != 7 || len(window.GetValues()) != 1 || window.Values[0].Value != "cart" {
+		t.Errorf("Unexpected window: %v", window)
+	}
+
+	// Closing the hub ends the stream so clients reconnect elsewhere
+	hub.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable once the hub closes, got %v", err)
+	}
+}
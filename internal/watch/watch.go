@@ -0,0 +1,264 @@
+package watch
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/report"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
+)
+
+// ErrClosed is returned by Subscribe once the hub is closed
+var ErrClosed = errors.New("watch hub is closed")
+
+// Config configures a Hub
+type Config struct {
+	// AttributeKey is the attribute the published windows are counted by when
+	// a report does not name its own
+	AttributeKey string
+	// BufferSize is the number of events buffered per subscriber; values
+	// below 1 buffer a single event
+	BufferSize int
+	// Metrics records subscribers and dropped windows (defaults to an unregistered instance)
+	Metrics *metrics.Metrics
+}
+
+// Filter selects the windows and values a subscriber receives
+type Filter struct {
+	// AttributeKey only matches windows counted by this key; empty matches any key
+	AttributeKey string
+	// ValuePrefix only keeps values starting with this prefix; empty keeps all
+	ValuePrefix string
+}
+
+// Hub is a report.Reporter that fans completed windows out to subscribers.
+// Publishing never blocks: a subscriber whose buffer is full misses windows
+// and is told how many before the next window it receives.
+type Hub struct {
+	config  Config
+	metrics *metrics.Metrics
+	logger  *logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub creates a hub without subscribers
+func NewHub(config Config, logger *logger.Logger) *Hub {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1
+	}
+	m := config.Metrics
+	if m == nil {
+		m = metrics.New(nil)
+	}
+
+	return &Hub{
+		config:      config,
+		metrics:     m,
+		logger:      logger,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber receiving the windows completed from now on
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	s := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan *countsv1.WatchWindowsResponse, h.config.BufferSize),
+	}
+	h.subscribers[s] = struct{}{}
+	h.metrics.WatchSubscribers.Inc()
+	return s, nil
+}
+
+// Report publishes a window to every matching subscriber
+func (h *Hub) Report(r report.Report) error {
+	window := toWindowReport(r, h.config.AttributeKey)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		if !s.filter.matches(window) {
+			continue
+		}
+		if s.offer(s.filter.apply(window)) {
+			continue
+		}
+		h.metrics.WatchDroppedWindowsTotal.Inc()
+		if s.dropped == 1 {
+			h.logger.Warnw("Watch subscriber fell behind, dropping windows",
+				"window_number", window.WindowNumber,
+				"buffer_size", h.config.BufferSize,
+			)
+		}
+	}
+	return nil
+}
+
+// Close ends every subscription; later Subscribe calls fail with ErrClosed
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	for s := range h.subscribers {
+		h.remove(s)
+	}
+	return nil
+}
+
+// remove unregisters s and closes its events; h.mu must be held
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	delete(h.subscribers, s)
+	close(s.events)
+	h.metrics.WatchSubscribers.Dec()
+}
+
+// Subscription is a subscriber's buffered feed of window events
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan *countsv1.WatchWindowsResponse
+
+	// dropped counts the windows missed since the last delivered event;
+	// guarded by hub.mu
+	dropped      int64
+	firstDropped int64
+	lastDropped  int64
+}
+
+// Events delivers window and dropped events; it is closed when the
+// subscription or the hub is closed
+func (s *Subscription) Events() <-chan *countsv1.WatchWindowsResponse {
+	return s.events
+}
+
+// Close unsubscribes; buffered events are discarded
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
+
+// offer enqueues window, preceded by a notice of the windows dropped before
+// it, and reports whether it fit in the buffer
+func (s *Subscription) offer(window *countsv1.WindowReport) bool {
+	if s.dropped > 0 {
+		notice := &countsv1.WatchWindowsResponse{Event: &countsv1.WatchWindowsResponse_Dropped{
+			Dropped: &countsv1.WindowsDropped{
+				Count:             s.dropped,
+				FirstWindowNumber: s.firstDropped,
+				LastWindowNumber:  s.lastDropped,
+			},
+		}}
+		select {
+		case s.events <- notice:
+			s.dropped = 0
+		default:
+			s.drop(window.WindowNumber)
+			return false
+		}
+	}
+
+	select {
+	case s.events <- &countsv1.WatchWindowsResponse{Event: &countsv1.WatchWindowsResponse_Window{Window: window}}:
+		return true
+	default:
+		s.drop(window.WindowNumber)
+		return false
+	}
+}
+
+func (s *Subscription) drop(windowNumber int64) {
+	if s.dropped == 0 {
+		s.firstDropped = windowNumber
+	}
+	s.dropped++
+	s.lastDropped = windowNumber
+}
+
+func (f Filter) matches(window *countsv1.WindowReport) bool {
+	return f.AttributeKey == "" || f.AttributeKey == window.AttributeKey
+}
+
+// apply returns window with only the values matching the prefix. Windows are
+// shared between subscribers, so a filtered window is a copy.
+func (f Filter) apply(window *countsv1.WindowReport) *countsv1.WindowReport {
+	if f.ValuePrefix == "" {
+		return window
+	}
+
+	filtered := &countsv1.WindowReport{
+		WindowNumber:  window.WindowNumber,
+		AttributeKey:  window.AttributeKey,
+		Start:         window.Start,
+		End:           window.End,
+		TotalLogs:     window.TotalLogs,
+		TotalBytes:    window.TotalBytes,
+		Reconfigured:  window.Reconfigured,
+		MissedWindows: window.MissedWindows,
+	}
+	for _, value := range window.Values {
+		if strings.HasPrefix(value.Value, f.ValuePrefix) {
+			filtered.Values = append(filtered.Values, value)
+		}
+	}
+	return filtered
+}
+
+// toWindowReport converts a report into the wire representation; attributeKey
+// is used when the report does not name its key
+func toWindowReport(r report.Report, attributeKey string) *countsv1.WindowReport {
+	if r.AttributeKey != "" {
+		attributeKey = r.AttributeKey
+	}
+	window := &countsv1.WindowReport{
+		WindowNumber:  r.WindowNumber,
+		AttributeKey:  attributeKey,
+		Start:         timestamppb.New(r.Start),
+		End:           timestamppb.New(r.End),
+		Reconfigured:  r.Reconfigured,
+		MissedWindows: r.MissedWindows,
+	}
+	if r.Delta == nil {
+		return window
+	}
+
+	window.TotalLogs = r.Delta.TotalLogs
+	window.TotalBytes = r.Delta.TotalBytes
+	window.Values = make([]*countsv1.ValueCount, len(r.Delta.Values))
+	for i, value := range r.Delta.Values {
+		window.Values[i] = &countsv1.ValueCount{
+			Value:      value.Value,
+			Count:      value.Count,
+			Bytes:      value.Bytes,
+			Percentage: value.Percentage,
+			Severity:   value.Severity,
+			Distinct:   value.Distinct,
+		}
+	}
+	return window
+}
@@ -0,0 +1,182 @@
+package watch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"otlp-log-parser-assignment/internal/logger"
+	"otlp-log-parser-assignment/internal/metrics"
+	"otlp-log-parser-assignment/internal/report"
+	countsv1 "otlp-log-parser-assignment/proto/counts/v1"
+)
+
+func newTestHub(t *testing.T, bufferSize int) (*Hub, *metrics.Metrics) {
+	t.Helper()
+
+	testLogger, _ := logger.New(false)
+	m := metrics.New(nil)
+	return NewHub(Config{AttributeKey: "service.name", BufferSize: bufferSize, Metrics: m}, testLogger), m
+}
+
+func testReport(number int64) report.Report {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(number) * time.Minute)
+	return report.Report{
+		WindowNumber: number,
+		Start:        start,
+		End:          start.Add(time.Minute),
+		Delta: &report.Delta{
+			TotalLogs:  3,
+			TotalBytes: 30,
+			Values: []report.ValueCount{
+				{Value: "cart", Count: 1, Bytes: 10, Percentage: 100.0 / 3},
+				{Value: "checkout", Count: 2, Bytes: 20, Percentage: 200.0 / 3, Severity: map[string]int64{"error": 2}},
+			},
+		},
+	}
+}
+
+func receive(t *testing.T, sub *Subscription) *countsv1.WatchWindowsResponse {
+	t.Helper()
+
+	select {
+	case event, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("Expected an event, subscription closed")
+		}
+		return event
+	default:
+		t.Fatal("Expected a buffered event")
+		return nil
+	}
+}
+
+func TestHub_Report(t *testing.T) {
+	hub, _ := newTestHub(t, 4)
+	sub, err := hub.Subscribe(Filter{})
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	hub.Report(testReport(1))
+
+	window := receive(t, sub).GetWindow()
+	if window == nil {
+		t.Fatal("Expected a window event")
+	}
+	if window.WindowNumber != 1 || window.AttributeKey != "service.name" || window.TotalLogs != 3 || window.TotalBytes != 30 {
+		t.Errorf("Unexpected window: %v", window)
+	}
+	if !window.Start.AsTime().Equal(testReport(1).Start) || len(window.Values) != 2 {
+		t.Errorf("Unexpected window: %v", window)
+	}
+	if got := window.Values[1].Severity["error"]; got != 2 {
+		t.Errorf("Expected checkout's severity breakdown, got %v", window.Values[1])
+	}
+}
+
+func TestHub_Filters(t *testing.T) {
+	hub, _ := newTestHub(t, 4)
+	prefix, _ := hub.Subscribe(Filter{ValuePrefix: "check"})
+	otherKey, _ := hub.Subscribe(Filter{AttributeKey: "host.name"})
+	all, _ := hub.Subscribe(Filter{AttributeKey: "service.name"})
+
+	hub.Report(testReport(1))
+
+	window := receive(t, prefix).GetWindow()
+	if len(window.Values) != 1 || window.Values[0].Value != "checkout" {
+		t.Errorf("Expected only checkout, got %v", window.Values)
+	}
+	if window.TotalLogs != 3 {
+		t.Errorf("Expected totals to cover every value, got %d", window.TotalLogs)
+	}
+	if len(otherKey.Events()) != 0 {
+		t.Error("Expected no windows for another attribute key")
+	}
+	if got := receive(t, all).GetWindow(); len(got.Values) != 2 {
+		t.Errorf("Expected the prefix filter not to affect other subscribers, got %v", got.Values)
+	}
+}
+
+func TestHub_DropAndNotify(t *testing.T) {
+	hub, m := newTestHub(t, 2)
+	sub, _ := hub.Subscribe(Filter{})
+
+	// Windows 3 to 5 find the buffer full; publishing must not block
+	for number := int64(1); number <= 5; number++ {
+		hub.Report(testReport(number))
+	}
+	if got := testutil.ToFloat64(m.WatchDroppedWindowsTotal); got != 3 {
+		t.Errorf("Expected 3 dropped windows, got %v", got)
+	}
+
+	if got := receive(t, sub).GetWindow().GetWindowNumber(); got != 1 {
+		t.Errorf("Expected window 1, got %d", got)
+	}
+	if got := receive(t, sub).GetWindow().GetWindowNumber(); got != 2 {
+		t.Errorf("Expected window 2, got %d", got)
+	}
+
+	// Once there is room the subscriber learns what it missed first
+	hub.Report(testReport(6))
+	dropped := receive(t, sub).GetDropped()
+	if dropped == nil || dropped.Count != 3 || dropped.FirstWindowNumber != 3 || dropped.LastWindowNumber != 5 {
+		t.Errorf("Expected windows 3 to 5 reported dropped, got %v", dropped)
+	}
+	if got := receive(t, sub).GetWindow().GetWindowNumber(); got != 6 {
+		t.Errorf("Expected window 6 after the notice, got %d", got)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	hub, m := newTestHub(t, 1)
+	sub, _ := hub.Subscribe(Filter{})
+	closed, _ := hub.Subscribe(Filter{})
+	closed.Close()
+	closed.Close()
+
+	if got := testutil.ToFloat64(m.WatchSubscribers); got != 1 {
+		t.Errorf("Expected 1 subscriber, got %v", got)
+	}
+
+	hub.Close()
+	if _, ok := <-sub.Events(); ok {
+		t.Error("Expected events to be closed with the hub")
+	}
+	sub.Close()
+	if got := testutil.ToFloat64(m.WatchSubscribers); got != 0 {
+		t.Errorf("Expected no subscribers, got %v", got)
+	}
+	if _, err := hub.Subscribe(Filter{}); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if err := hub.Report(testReport(1)); err != nil {
+		t.Errorf("Expected reports after close to be ignored, got %v", err)
+	}
+}
+
+func TestToWindowReport_CumulativeOnly(t *testing.T) {
+	r := testReport(1)
+	r.Delta = nil
+
+	window := toWindowReport(r, "service.name")
+	if window.TotalLogs != 0 || len(window.Values) != 0 || window.WindowNumber != 1 {
+		t.Errorf("Expected an empty window without a delta, got %v", window)
+	}
+}
+
+func TestHub_ReconfiguredWindow(t *testing.T) {
+	hub, _ := newTestHub(t, 4)
+	namespaces, _ := hub.Subscribe(Filter{AttributeKey: "k8s.namespace", ValuePrefix: "check"})
+
+	r := testReport(1)
+	r.AttributeKey = "k8s.namespace"
+	r.Reconfigured = true
+	hub.Report(r)
+
+	window := receive(t, namespaces).GetWindow()
+	if window.AttributeKey != "k8s.namespace" || !window.Reconfigured {
+		t.Errorf("Expected a reconfigured k8s.namespace window, got %v", window)
+	}
+}
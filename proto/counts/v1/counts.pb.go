@@ -0,0 +1,533 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: proto/counts/v1/counts.proto
+
+package countsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WatchWindowsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream windows counted by this attribute key; empty matches any key.
+	AttributeKey string `protobuf:"bytes,1,opt,name=attribute_key,json=attributeKey,proto3" json:"attribute_key,omitempty"`
+	// Only include values starting with this prefix; empty includes every value.
+	// Window totals always cover every value.
+	ValuePrefix   string `protobuf:"bytes,2,opt,name=value_prefix,json=valuePrefix,proto3" json:"value_prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchWindowsRequest) Reset() {
+	*x = WatchWindowsRequest{}
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchWindowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchWindowsRequest) ProtoMessage() {}
+
+func (x *WatchWindowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchWindowsRequest.ProtoReflect.Descriptor instead.
+func (*WatchWindowsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_counts_v1_counts_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WatchWindowsRequest) GetAttributeKey() string {
+	if x != nil {
+		return x.AttributeKey
+	}
+	return ""
+}
+
+func (x *WatchWindowsRequest) GetValuePrefix() string {
+	if x != nil {
+		return x.ValuePrefix
+	}
+	return ""
+}
+
+type WatchWindowsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*WatchWindowsResponse_Window
+	//	*WatchWindowsResponse_Dropped
+	Event         isWatchWindowsResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchWindowsResponse) Reset() {
+	*x = WatchWindowsResponse{}
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchWindowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchWindowsResponse) ProtoMessage() {}
+
+func (x *WatchWindowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchWindowsResponse.ProtoReflect.Descriptor instead.
+func (*WatchWindowsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_counts_v1_counts_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WatchWindowsResponse) GetEvent() isWatchWindowsResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *WatchWindowsResponse) GetWindow() *WindowReport {
+	if x != nil {
+		if x, ok := x.Event.(*WatchWindowsResponse_Window); ok {
+			return x.Window
+		}
+	}
+	return nil
+}
+
+func (x *WatchWindowsResponse) GetDropped() *WindowsDropped {
+	if x != nil {
+		if x, ok := x.Event.(*WatchWindowsResponse_Dropped); ok {
+			return x.Dropped
+		}
+	}
+	return nil
+}
+
+type isWatchWindowsResponse_Event interface {
+	isWatchWindowsResponse_Event()
+}
+
+type WatchWindowsResponse_Window struct {
+	Window *WindowReport `protobuf:"bytes,1,opt,name=window,proto3,oneof"`
+}
+
+type WatchWindowsResponse_Dropped struct {
+	Dropped *WindowsDropped `protobuf:"bytes,2,opt,name=dropped,proto3,oneof"`
+}
+
+func (*WatchWindowsResponse_Window) isWatchWindowsResponse_Event() {}
+
+func (*WatchWindowsResponse_Dropped) isWatchWindowsResponse_Event() {}
+
+// WindowReport summarizes a completed window.
+type WindowReport struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	WindowNumber int64                  `protobuf:"varint,1,opt,name=window_number,json=windowNumber,proto3" json:"window_number,omitempty"`
+	AttributeKey string                 `protobuf:"bytes,2,opt,name=attribute_key,json=attributeKey,proto3" json:"attribute_key,omitempty"`
+	Start        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	End          *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	// Totals and values are zero when the report mode omits the window's own
+	// counts (report-mode=cumulative).
+	TotalLogs  int64 `protobuf:"varint,5,opt,name=total_logs,json=totalLogs,proto3" json:"total_logs,omitempty"`
+	TotalBytes int64 `protobuf:"varint,6,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	// Values are sorted by value.
+	Values []*ValueCount `protobuf:"bytes,7,rep,name=values,proto3" json:"values,omitempty"`
+	// Set when the window was closed early because the attribute key or the
+	// window duration was reconfigured.
+	Reconfigured bool `protobuf:"varint,8,opt,name=reconfigured,proto3" json:"reconfigured,omitempty"`
+	// Set on a gap report, which holds no counts: the number of windows
+	// between start and end that passed while the server was down.
+	MissedWindows int64 `protobuf:"varint,9,opt,name=missed_windows,json=missedWindows,proto3" json:"missed_windows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WindowReport) Reset() {
+	*x = WindowReport{}
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WindowReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WindowReport) ProtoMessage() {}
+
+func (x *WindowReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WindowReport.ProtoReflect.Descriptor instead.
+func (*WindowReport) Descriptor() ([]byte, []int) {
+	return file_proto_counts_v1_counts_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WindowReport) GetWindowNumber() int64 {
+	if x != nil {
+		return x.WindowNumber
+	}
+	return 0
+}
+
+func (x *WindowReport) GetAttributeKey() string {
+	if x != nil {
+		return x.AttributeKey
+	}
+	return ""
+}
+
+func (x *WindowReport) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *WindowReport) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *WindowReport) GetTotalLogs() int64 {
+	if x != nil {
+		return x.TotalLogs
+	}
+	return 0
+}
+
+func (x *WindowReport) GetTotalBytes() int64 {
+	if x != nil {
+		return x.TotalBytes
+	}
+	return 0
+}
+
+func (x *WindowReport) GetValues() []*ValueCount {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *WindowReport) GetReconfigured() bool {
+	if x != nil {
+		return x.Reconfigured
+	}
+	return false
+}
+
+func (x *WindowReport) GetMissedWindows() int64 {
+	if x != nil {
+		return x.MissedWindows
+	}
+	return 0
+}
+
+// ValueCount is a single attribute value's share of a window.
+type ValueCount struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Value      string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count      int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Bytes      int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Percentage float64                `protobuf:"fixed64,4,opt,name=percentage,proto3" json:"percentage,omitempty"`
+	Severity   map[string]int64       `protobuf:"bytes,5,rep,name=severity,proto3" json:"severity,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// Estimated distinct values of the distinct-of attribute, 0 when disabled.
+	Distinct      uint64 `protobuf:"varint,6,opt,name=distinct,proto3" json:"distinct,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValueCount) Reset() {
+	*x = ValueCount{}
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValueCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValueCount) ProtoMessage() {}
+
+func (x *ValueCount) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValueCount.ProtoReflect.Descriptor instead.
+func (*ValueCount) Descriptor() ([]byte, []int) {
+	return file_proto_counts_v1_counts_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ValueCount) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ValueCount) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ValueCount) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *ValueCount) GetPercentage() float64 {
+	if x != nil {
+		return x.Percentage
+	}
+	return 0
+}
+
+func (x *ValueCount) GetSeverity() map[string]int64 {
+	if x != nil {
+		return x.Severity
+	}
+	return nil
+}
+
+func (x *ValueCount) GetDistinct() uint64 {
+	if x != nil {
+		return x.Distinct
+	}
+	return 0
+}
+
+// WindowsDropped reports windows discarded because the subscriber fell behind.
+type WindowsDropped struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Count             int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	FirstWindowNumber int64                  `protobuf:"varint,2,opt,name=first_window_number,json=firstWindowNumber,proto3" json:"first_window_number,omitempty"`
+	LastWindowNumber  int64                  `protobuf:"varint,3,opt,name=last_window_number,json=lastWindowNumber,proto3" json:"last_window_number,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *WindowsDropped) Reset() {
+	*x = WindowsDropped{}
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WindowsDropped) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WindowsDropped) ProtoMessage() {}
+
+func (x *WindowsDropped) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_counts_v1_counts_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WindowsDropped.ProtoReflect.Descriptor instead.
+func (*WindowsDropped) Descriptor() ([]byte, []int) {
+	return file_proto_counts_v1_counts_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WindowsDropped) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *WindowsDropped) GetFirstWindowNumber() int64 {
+	if x != nil {
+		return x.FirstWindowNumber
+	}
+	return 0
+}
+
+func (x *WindowsDropped) GetLastWindowNumber() int64 {
+	if x != nil {
+		return x.LastWindowNumber
+	}
+	return 0
+}
+
+var File_proto_counts_v1_counts_proto protoreflect.FileDescriptor
+
+const file_proto_counts_v1_counts_proto_rawDesc = "" +
+	"\n" +
+	"\x1cproto/counts/v1/counts.proto\x12\x19otlp_log_parser.counts.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"]\n" +
+	"\x13WatchWindowsRequest\x12#\n" +
+	"\rattribute_key\x18\x01 \x01(\tR\fattributeKey\x12!\n" +
+	"\fvalue_prefix\x18\x02 \x01(\tR\vvaluePrefix\"\xa9\x01\n" +
+	"\x14WatchWindowsResponse\x12A\n" +
+	"\x06window\x18\x01 \x01(\v2'.otlp_log_parser.counts.v1.WindowReportH\x00R\x06window\x12E\n" +
+	"\adropped\x18\x02 \x01(\v2).otlp_log_parser.counts.v1.WindowsDroppedH\x00R\adroppedB\a\n" +
+	"\x05event\"\x82\x03\n" +
+	"\fWindowReport\x12#\n" +
+	"\rwindow_number\x18\x01 \x01(\x03R\fwindowNumber\x12#\n" +
+	"\rattribute_key\x18\x02 \x01(\tR\fattributeKey\x120\n" +
+	"\x05start\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x1d\n" +
+	"\n" +
+	"total_logs\x18\x05 \x01(\x03R\ttotalLogs\x12\x1f\n" +
+	"\vtotal_bytes\x18\x06 \x01(\x03R\n" +
+	"totalBytes\x12=\n" +
+	"\x06values\x18\a \x03(\v2%.otlp_log_parser.counts.v1.ValueCountR\x06values\x12\"\n" +
+	"\freconfigured\x18\b \x01(\bR\freconfigured\x12%\n" +
+	"\x0emissed_windows\x18\t \x01(\x03R\rmissedWindows\"\x98\x02\n" +
+	"\n" +
+	"ValueCount\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12\x1e\n" +
+	"\n" +
+	"percentage\x18\x04 \x01(\x01R\n" +
+	"percentage\x12O\n" +
+	"\bseverity\x18\x05 \x03(\v23.otlp_log_parser.counts.v1.ValueCount.SeverityEntryR\bseverity\x12\x1a\n" +
+	"\bdistinct\x18\x06 \x01(\x04R\bdistinct\x1a;\n" +
+	"\rSeverityEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x84\x01\n" +
+	"\x0eWindowsDropped\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\x12.\n" +
+	"\x13first_window_number\x18\x02 \x01(\x03R\x11firstWindowNumber\x12,\n" +
+	"\x12last_window_number\x18\x03 \x01(\x03R\x10lastWindowNumber2\x82\x01\n" +
+	"\rCountsService\x12q\n" +
+	"\fWatchWindows\x12..otlp_log_parser.counts.v1.WatchWindowsRequest\x1a/.otlp_log_parser.counts.v1.WatchWindowsResponse0\x01B5Z3otlp-log-parser-assignment/proto/counts/v1;countsv1b\x06proto3"
+
+var (
+	file_proto_counts_v1_counts_proto_rawDescOnce sync.Once
+	file_proto_counts_v1_counts_proto_rawDescData []byte
+)
+
+func file_proto_counts_v1_counts_proto_rawDescGZIP() []byte {
+	file_proto_counts_v1_counts_proto_rawDescOnce.Do(func() {
+		file_proto_counts_v1_counts_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_counts_v1_counts_proto_rawDesc), len(file_proto_counts_v1_counts_proto_rawDesc)))
+	})
+	return file_proto_counts_v1_counts_proto_rawDescData
+}
+
+var file_proto_counts_v1_counts_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_counts_v1_counts_proto_goTypes = []any{
+	(*WatchWindowsRequest)(nil),   // 0: otlp_log_parser.counts.v1.WatchWindowsRequest
+	(*WatchWindowsResponse)(nil),  // 1: otlp_log_parser.counts.v1.WatchWindowsResponse
+	(*WindowReport)(nil),          // 2: otlp_log_parser.counts.v1.WindowReport
+	(*ValueCount)(nil),            // 3: otlp_log_parser.counts.v1.ValueCount
+	(*WindowsDropped)(nil),        // 4: otlp_log_parser.counts.v1.WindowsDropped
+	nil,                           // 5: otlp_log_parser.counts.v1.ValueCount.SeverityEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_proto_counts_v1_counts_proto_depIdxs = []int32{
+	2, // 0: otlp_log_parser.counts.v1.WatchWindowsResponse.window:type_name -> otlp_log_parser.counts.v1.WindowReport
+	4, // 1: otlp_log_parser.counts.v1.WatchWindowsResponse.dropped:type_name -> otlp_log_parser.counts.v1.WindowsDropped
+	6, // 2: otlp_log_parser.counts.v1.WindowReport.start:type_name -> google.protobuf.Timestamp
+	6, // 3: otlp_log_parser.counts.v1.WindowReport.end:type_name -> google.protobuf.Timestamp
+	3, // 4: otlp_log_parser.counts.v1.WindowReport.values:type_name -> otlp_log_parser.counts.v1.ValueCount
+	5, // 5: otlp_log_parser.counts.v1.ValueCount.severity:type_name -> otlp_log_parser.counts.v1.ValueCount.SeverityEntry
+	0, // 6: otlp_log_parser.counts.v1.CountsService.WatchWindows:input_type -> otlp_log_parser.counts.v1.WatchWindowsRequest
+	1, // 7: otlp_log_parser.counts.v1.CountsService.WatchWindows:output_type -> otlp_log_parser.counts.v1.WatchWindowsResponse
+	7, // [7:8] is the sub-list for method output_type
+	6, // [6:7] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_proto_counts_v1_counts_proto_init() }
+func file_proto_counts_v1_counts_proto_init() {
+	if File_proto_counts_v1_counts_proto != nil {
+		return
+	}
+	file_proto_counts_v1_counts_proto_msgTypes[1].OneofWrappers = []any{
+		(*WatchWindowsResponse_Window)(nil),
+		(*WatchWindowsResponse_Dropped)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_counts_v1_counts_proto_rawDesc), len(file_proto_counts_v1_counts_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_counts_v1_counts_proto_goTypes,
+		DependencyIndexes: file_proto_counts_v1_counts_proto_depIdxs,
+		MessageInfos:      file_proto_counts_v1_counts_proto_msgTypes,
+	}.Build()
+	File_proto_counts_v1_counts_proto = out.File
+	file_proto_counts_v1_counts_proto_goTypes = nil
+	file_proto_counts_v1_counts_proto_depIdxs = nil
+}
@@ -0,0 +1,149 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: proto/counts/v1/counts.proto
+
+package countsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	CountsService_WatchWindows_FullMethodName = "/otlp_log_parser.counts.v1.CountsService/WatchWindows"
+)
+
+// CountsServiceClient is the client API for CountsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CountsService publishes attribute value counts as windows complete.
+type CountsServiceClient interface {
+	// WatchWindows streams every window report completed after the call, until
+	// the client cancels or the server shuts down. Each subscriber has a bounded
+	// buffer: when it is full, further windows are dropped and the subscriber
+	// receives a WindowsDropped event before the next window it is sent.
+	WatchWindows(ctx context.Context, in *WatchWindowsRequest, opts ...grpc.CallOption) (CountsService_WatchWindowsClient, error)
+}
+
+type countsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCountsServiceClient(cc grpc.ClientConnInterface) CountsServiceClient {
+	return &countsServiceClient{cc}
+}
+
+func (c *countsServiceClient) WatchWindows(ctx context.Context, in *WatchWindowsRequest, opts ...grpc.CallOption) (CountsService_WatchWindowsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CountsService_ServiceDesc.Streams[0], CountsService_WatchWindows_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &countsServiceWatchWindowsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CountsService_WatchWindowsClient interface {
+	Recv() (*WatchWindowsResponse, error)
+	grpc.ClientStream
+}
+
+type countsServiceWatchWindowsClient struct {
+	grpc.ClientStream
+}
+
+func (x *countsServiceWatchWindowsClient) Recv() (*WatchWindowsResponse, error) {
+	m := new(WatchWindowsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CountsServiceServer is the server API for CountsService service.
+// All implementations must embed UnimplementedCountsServiceServer
+// for forward compatibility
+//
+// CountsService publishes attribute value counts as windows complete.
+type CountsServiceServer interface {
+	// WatchWindows streams every window report completed after the call, until
+	// the client cancels or the server shuts down. Each subscriber has a bounded
+	// buffer: when it is full, further windows are dropped and the subscriber
+	// receives a WindowsDropped event before the next window it is sent.
+	WatchWindows(*WatchWindowsRequest, CountsService_WatchWindowsServer) error
+	mustEmbedUnimplementedCountsServiceServer()
+}
+
+// UnimplementedCountsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedCountsServiceServer struct {
+}
+
+func (UnimplementedCountsServiceServer) WatchWindows(*WatchWindowsRequest, CountsService_WatchWindowsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchWindows not implemented")
+}
+func (UnimplementedCountsServiceServer) mustEmbedUnimplementedCountsServiceServer() {}
+
+// UnsafeCountsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CountsServiceServer will
+// result in compilation errors.
+type UnsafeCountsServiceServer interface {
+	mustEmbedUnimplementedCountsServiceServer()
+}
+
+func RegisterCountsServiceServer(s grpc.ServiceRegistrar, srv CountsServiceServer) {
+	s.RegisterService(&CountsService_ServiceDesc, srv)
+}
+
+func _CountsService_WatchWindows_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchWindowsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CountsServiceServer).WatchWindows(m, &countsServiceWatchWindowsServer{ServerStream: stream})
+}
+
+type CountsService_WatchWindowsServer interface {
+	Send(*WatchWindowsResponse) error
+	grpc.ServerStream
+}
+
+type countsServiceWatchWindowsServer struct {
+	grpc.ServerStream
+}
+
+func (x *countsServiceWatchWindowsServer) Send(m *WatchWindowsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CountsService_ServiceDesc is the grpc.ServiceDesc for CountsService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CountsService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "otlp_log_parser.counts.v1.CountsService",
+	HandlerType: (*CountsServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchWindows",
+			Handler:       _CountsService_WatchWindows_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/counts/v1/counts.proto",
+}